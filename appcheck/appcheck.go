@@ -18,6 +18,8 @@ package appcheck
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -45,8 +47,105 @@ var (
 	ErrTokenIssuer = errors.New("token has incorrect issuer")
 	// ErrTokenSubject is returned when the token subject is empty or missing.
 	ErrTokenSubject = errors.New("token has empty or missing subject")
+	// ErrTokenAppID is returned when the token subject does not match the expected app ID.
+	ErrTokenAppID = errors.New("token was issued for a different app")
 )
 
+const (
+	tokenExpired      = "token-expired"
+	tokenUsedTooEarly = "token-used-too-early"
+	tokenInvalid      = "token-invalid"
+)
+
+// VerificationError is the error returned when an App Check token fails verification.
+//
+// It wraps the cause of the failure, such as one of the Err* values of this package, so that
+// errors.Is() can be used to check for a specific cause. IsTokenExpired(), IsTokenUsedTooEarly()
+// and IsInvalidToken() classify the failure.
+type VerificationError struct {
+	// KeyID is the key ID (kid) header of the token, if the token could be decoded.
+	KeyID string
+	// Issuer is the issuer (iss) claim of the token, if the token could be decoded.
+	Issuer string
+	// Err is the cause of the failure.
+	Err error
+
+	code string
+}
+
+func (e *VerificationError) Error() string {
+	var details []string
+	if e.KeyID != "" {
+		details = append(details, "kid: "+e.KeyID)
+	}
+	if e.Issuer != "" {
+		details = append(details, "iss: "+e.Issuer)
+	}
+	if len(details) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (%s)", e.Err, strings.Join(details, ", "))
+}
+
+// Unwrap returns the cause of the failure.
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// IsTokenExpired checks if the given error was due to an expired App Check token.
+func IsTokenExpired(err error) bool {
+	return hasVerificationErrorCode(err, tokenExpired)
+}
+
+// IsTokenUsedTooEarly checks if the given error was due to an App Check token that is not valid
+// yet, because its issued-at (iat) or not-before (nbf) time is in the future.
+//
+// This typically indicates that the clock of the server is behind.
+func IsTokenUsedTooEarly(err error) bool {
+	return hasVerificationErrorCode(err, tokenUsedTooEarly)
+}
+
+// IsInvalidToken checks if the given error was due to an App Check token that is malformed, has
+// an invalid signature or has invalid claims.
+func IsInvalidToken(err error) bool {
+	return hasVerificationErrorCode(err, tokenInvalid)
+}
+
+func hasVerificationErrorCode(err error, code string) bool {
+	var ve *VerificationError
+	return errors.As(err, &ve) && ve.code == code
+}
+
+// newVerificationError wraps the given cause of a verification failure, along with the key ID and
+// the issuer of the token if it could be decoded.
+func newVerificationError(token *jwt.Token, err error) *VerificationError {
+	ve := &VerificationError{
+		Err:  err,
+		code: tokenInvalid,
+	}
+	if token != nil {
+		ve.KeyID, _ = token.Header["kid"].(string)
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			ve.Issuer, _ = claims["iss"].(string)
+		}
+	}
+
+	// Timing failures are only reported as such if the token is otherwise valid, including its
+	// signature.
+	var jwtErr *jwt.ValidationError
+	if errors.As(err, &jwtErr) {
+		const timing = jwt.ValidationErrorExpired | jwt.ValidationErrorNotValidYet | jwt.ValidationErrorIssuedAt
+		if jwtErr.Errors&^timing == 0 {
+			if jwtErr.Errors&jwt.ValidationErrorExpired != 0 {
+				ve.code = tokenExpired
+			} else if jwtErr.Errors != 0 {
+				ve.code = tokenUsedTooEarly
+			}
+		}
+	}
+	return ve
+}
+
 // DecodedAppCheckToken represents a verified App Check token.
 //
 // DecodedAppCheckToken provides typed accessors to the common JWT fields such as Audience (aud)
@@ -66,6 +165,7 @@ type DecodedAppCheckToken struct {
 type Client struct {
 	projectID string
 	jwks      *keyfunc.JWKS
+	cache     *tokenCache
 }
 
 // NewClient creates a new instance of the Firebase App Check Client.
@@ -73,19 +173,36 @@ type Client struct {
 // This function can only be invoked from within the SDK. Client applications should access the
 // the App Check service through firebase.App.
 func NewClient(ctx context.Context, conf *internal.AppCheckConfig) (*Client, error) {
+	if conf.TokenCacheSize < 0 {
+		return nil, errors.New("token cache size must not be negative")
+	}
+
 	// TODO: Add support for overriding the HTTP client using the App one.
 	jwks, err := keyfunc.Get(JWKSUrl, keyfunc.Options{
 		Ctx:             ctx,
 		RefreshInterval: 6 * time.Hour,
+		RequestFactory: func(ctx context.Context, url string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			internal.WithClientVersion(conf.Version)(req)
+			return req, nil
+		},
 	})
 	if err != nil {
 		return nil, err
 	}
+	conf.Tracker.TrackFunc(jwks.EndBackground)
 
-	return &Client{
+	client := &Client{
 		projectID: conf.ProjectID,
 		jwks:      jwks,
-	}, nil
+	}
+	if conf.TokenCacheSize > 0 {
+		client.cache = newTokenCache(conf.TokenCacheSize)
+	}
+	return client, nil
 }
 
 // VerifyToken verifies the given App Check token.
@@ -98,9 +215,20 @@ func NewClient(ctx context.Context, conf *internal.AppCheckConfig) (*Client, err
 //   - The JWT is not expired, and it has been issued some time in the past.
 //   - The JWT is signed by a Firebase App Check backend server as determined by the keySource.
 //
-// If any of the above conditions are not met, an error is returned. Otherwise a pointer to a
-// decoded App Check token is returned.
+// If any of the above conditions are not met, a *VerificationError is returned. Otherwise a
+// pointer to a decoded App Check token is returned.
+//
+// If the token cache is enabled via firebase.Config, the result of verifying a token is cached
+// until the token expires, and verifying the same token again returns the cached result without
+// repeating the checks. This is a local cache of verification results, and does not make tokens
+// single-use: a cached token is accepted any number of times before it expires.
 func (c *Client) VerifyToken(token string) (*DecodedAppCheckToken, error) {
+	if c.cache != nil {
+		if decoded, ok := c.cache.get(token); ok {
+			return decoded, nil
+		}
+	}
+
 	// References for checks:
 	// https://firebase.googleblog.com/2021/10/protecting-backends-with-app-check.html
 	// https://github.com/firebase/firebase-admin-node/blob/master/src/app-check/token-verifier.ts#L106
@@ -117,22 +245,24 @@ func (c *Client) VerifyToken(token string) (*DecodedAppCheckToken, error) {
 		return c.jwks.Keyfunc(t)
 	})
 	if err != nil {
-		return nil, err
+		return nil, newVerificationError(decodedToken, err)
 	}
 
 	claims, ok := decodedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return nil, ErrTokenClaims
+		return nil, newVerificationError(decodedToken, ErrTokenClaims)
 	}
 
-	rawAud := claims["aud"].([]interface{})
+	rawAud, _ := claims["aud"].([]interface{})
 	aud := []string{}
 	for _, v := range rawAud {
-		aud = append(aud, v.(string))
+		if s, ok := v.(string); ok {
+			aud = append(aud, s)
+		}
 	}
 
 	if !contains(aud, "projects/"+c.projectID) {
-		return nil, ErrTokenAudience
+		return nil, newVerificationError(decodedToken, ErrTokenAudience)
 	}
 
 	// We check the prefix to make sure this token was issued
@@ -140,12 +270,12 @@ func (c *Client) VerifyToken(token string) (*DecodedAppCheckToken, error) {
 	// Project Number suffix because the Golang SDK only has project ID.
 	//
 	// This is consistent with the Firebase Admin Node SDK.
-	if !strings.HasPrefix(claims["iss"].(string), appCheckIssuer) {
-		return nil, ErrTokenIssuer
+	if iss, _ := claims["iss"].(string); !strings.HasPrefix(iss, appCheckIssuer) {
+		return nil, newVerificationError(decodedToken, ErrTokenIssuer)
 	}
 
 	if val, ok := claims["sub"].(string); !ok || val == "" {
-		return nil, ErrTokenSubject
+		return nil, newVerificationError(decodedToken, ErrTokenSubject)
 	}
 
 	appCheckToken := DecodedAppCheckToken{
@@ -163,9 +293,38 @@ func (c *Client) VerifyToken(token string) (*DecodedAppCheckToken, error) {
 	}
 	appCheckToken.Claims = claims
 
+	if c.cache != nil {
+		c.cache.put(token, &appCheckToken)
+	}
 	return &appCheckToken, nil
 }
 
+// VerifyTokenForApp verifies the given App Check token like VerifyToken, and additionally checks
+// that the token was issued for the app with the given ID.
+//
+// The app ID is compared with the subject (sub) claim of the token, which is the Firebase app ID
+// of the app that obtained it (e.g. "1:12345678:android:abcdef"). This prevents a valid token
+// obtained by one app of the project from being accepted by endpoints meant for another app.
+// A VerificationError wrapping ErrTokenAppID is returned if the app ID does not match.
+func (c *Client) VerifyTokenForApp(token, appID string) (*DecodedAppCheckToken, error) {
+	if appID == "" {
+		return nil, errors.New("app ID must not be empty")
+	}
+
+	decoded, err := c.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if decoded.AppID != appID {
+		return nil, &VerificationError{
+			Issuer: decoded.Issuer,
+			Err:    ErrTokenAppID,
+			code:   tokenInvalid,
+		}
+	}
+	return decoded, nil
+}
+
 func contains(s []string, str string) bool {
 	for _, v := range s {
 		if v == str {
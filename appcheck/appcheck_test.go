@@ -2,13 +2,16 @@ package appcheck
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -264,6 +267,310 @@ func TestVerifyTokenNotExpired(t *testing.T) {
 	}
 }
 
+func TestVerifyTokenForApp(t *testing.T) {
+	ts, err := setupFakeJWKS()
+	if err != nil {
+		t.Fatalf("Error setting up fake JWKS server: %v", err)
+	}
+	defer ts.Close()
+
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		t.Fatalf("Error loading private key: %v", err)
+	}
+
+	JWKSUrl = ts.URL
+	conf := &internal.AppCheckConfig{
+		ProjectID: "project_id",
+	}
+
+	client, err := NewClient(context.Background(), conf)
+	if err != nil {
+		t.Errorf("Error creating NewClient: %v", err)
+	}
+
+	mockTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	jwt.TimeFunc = func() time.Time {
+		return mockTime
+	}
+
+	claims := struct {
+		Aud []string `json:"aud"`
+		jwt.RegisteredClaims
+	}{
+		[]string{"projects/12345678", "projects/project_id"},
+		jwt.RegisteredClaims{
+			Issuer:    "https://firebaseappcheck.googleapis.com/12345678",
+			Subject:   "12345678:app:ID",
+			ExpiresAt: jwt.NewNumericDate(mockTime.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(mockTime),
+		},
+	}
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	jwtToken.Header["kid"] = "FGQdnRlzAmKyKr6-Hg_kMQrBkj_H6i6ADnBQz4OI6BU"
+	token, err := jwtToken.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("error generating JWT: %v", err)
+	}
+
+	gotToken, err := client.VerifyTokenForApp(token, "12345678:app:ID")
+	if err != nil {
+		t.Fatalf("VerifyTokenForApp() = %v; want = nil", err)
+	}
+	if gotToken.AppID != "12345678:app:ID" {
+		t.Errorf("VerifyTokenForApp().AppID = %q; want = %q", gotToken.AppID, "12345678:app:ID")
+	}
+
+	gotToken, err = client.VerifyTokenForApp(token, "12345678:app:OTHER")
+	if gotToken != nil || !errors.Is(err, ErrTokenAppID) {
+		t.Errorf("VerifyTokenForApp(other app) = (%v, %v); want = (nil, %v)", gotToken, err, ErrTokenAppID)
+	}
+
+	gotToken, err = client.VerifyTokenForApp("", "12345678:app:ID")
+	if gotToken != nil || err == nil || errors.Is(err, ErrTokenAppID) {
+		t.Errorf("VerifyTokenForApp(invalid token) = (%v, %v); want = (nil, verification error)", gotToken, err)
+	}
+
+	want := "app ID must not be empty"
+	if gotToken, err := client.VerifyTokenForApp(token, ""); gotToken != nil || err == nil || err.Error() != want {
+		t.Errorf("VerifyTokenForApp(empty app ID) = (%v, %v); want = (nil, %q)", gotToken, err, want)
+	}
+}
+
+func TestVerifyTokenErrors(t *testing.T) {
+	ts, err := setupFakeJWKS()
+	if err != nil {
+		t.Fatalf("Error setting up fake JWKS server: %v", err)
+	}
+	defer ts.Close()
+
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		t.Fatalf("Error loading private key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	JWKSUrl = ts.URL
+	conf := &internal.AppCheckConfig{
+		ProjectID: "project_id",
+	}
+
+	client, err := NewClient(context.Background(), conf)
+	if err != nil {
+		t.Errorf("Error creating NewClient: %v", err)
+	}
+
+	mockTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	jwt.TimeFunc = func() time.Time {
+		return mockTime
+	}
+
+	const kid = "FGQdnRlzAmKyKr6-Hg_kMQrBkj_H6i6ADnBQz4OI6BU"
+	const issuer = "https://firebaseappcheck.googleapis.com/12345678"
+	type appCheckClaims struct {
+		Aud []string `json:"aud"`
+		jwt.RegisteredClaims
+	}
+	validClaims := func() *appCheckClaims {
+		return &appCheckClaims{
+			[]string{"projects/12345678", "projects/project_id"},
+			jwt.RegisteredClaims{
+				Issuer:    issuer,
+				Subject:   "12345678:app:ID",
+				ExpiresAt: jwt.NewNumericDate(mockTime.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(mockTime),
+			},
+		}
+	}
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(mockTime.Add(-time.Hour))
+	issuedInFuture := validClaims()
+	issuedInFuture.IssuedAt = jwt.NewNumericDate(mockTime.Add(time.Hour))
+	notValidYet := validClaims()
+	notValidYet.NotBefore = jwt.NewNumericDate(mockTime.Add(time.Minute))
+	wrongAudience := validClaims()
+	wrongAudience.Aud = []string{"projects/another_project_id"}
+
+	cases := []struct {
+		name   string
+		claims *appCheckClaims
+		key    *rsa.PrivateKey
+		check  func(error) bool
+		reason error
+	}{
+		{"Expired", expired, privateKey, IsTokenExpired, jwt.ErrTokenExpired},
+		{"IssuedInFuture", issuedInFuture, privateKey, IsTokenUsedTooEarly, jwt.ErrTokenUsedBeforeIssued},
+		{"NotValidYet", notValidYet, privateKey, IsTokenUsedTooEarly, jwt.ErrTokenNotValidYet},
+		{"WrongAudience", wrongAudience, privateKey, IsInvalidToken, ErrTokenAudience},
+		{"ExpiredWithInvalidSignature", expired, otherKey, IsInvalidToken, nil},
+	}
+	for _, tc := range cases {
+		jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, tc.claims)
+		jwtToken.Header["kid"] = kid
+		token, err := jwtToken.SignedString(tc.key)
+		if err != nil {
+			t.Fatalf("error generating JWT: %v", err)
+		}
+
+		_, err = client.VerifyToken(token)
+		if !tc.check(err) {
+			t.Errorf("VerifyToken(%s) = %v; want = %s", tc.name, err, tc.name)
+		}
+		if tc.reason != nil && !errors.Is(err, tc.reason) {
+			t.Errorf("VerifyToken(%s) = %v; want = %v", tc.name, err, tc.reason)
+		}
+
+		var ve *VerificationError
+		if !errors.As(err, &ve) || ve.KeyID != kid || ve.Issuer != issuer {
+			t.Errorf("VerifyToken(%s) = %#v; want = {KeyID: %q, Issuer: %q}", tc.name, err, kid, issuer)
+			continue
+		}
+		wantSuffix := fmt.Sprintf("(kid: %s, iss: %s)", kid, issuer)
+		if !strings.HasSuffix(err.Error(), wantSuffix) {
+			t.Errorf("VerifyToken(%s) = %q; want suffix = %q", tc.name, err.Error(), wantSuffix)
+		}
+	}
+
+	_, err = client.VerifyToken("malformed")
+	if !IsInvalidToken(err) || IsTokenExpired(err) || IsTokenUsedTooEarly(err) {
+		t.Errorf("VerifyToken(malformed) = %v; want = InvalidToken", err)
+	}
+	if IsInvalidToken(errors.New("other")) || IsTokenExpired(nil) {
+		t.Errorf("predicates matched errors not returned by VerifyToken")
+	}
+}
+
+func TestVerifyTokenCache(t *testing.T) {
+	ts, err := setupFakeJWKS()
+	if err != nil {
+		t.Fatalf("Error setting up fake JWKS server: %v", err)
+	}
+	defer ts.Close()
+
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		t.Fatalf("Error loading private key: %v", err)
+	}
+
+	JWKSUrl = ts.URL
+	conf := &internal.AppCheckConfig{
+		ProjectID:      "project_id",
+		TokenCacheSize: 10,
+	}
+
+	client, err := NewClient(context.Background(), conf)
+	if err != nil {
+		t.Errorf("Error creating NewClient: %v", err)
+	}
+
+	mockTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	jwt.TimeFunc = func() time.Time {
+		return mockTime
+	}
+
+	claims := struct {
+		Aud []string `json:"aud"`
+		jwt.RegisteredClaims
+	}{
+		[]string{"projects/12345678", "projects/project_id"},
+		jwt.RegisteredClaims{
+			Issuer:    "https://firebaseappcheck.googleapis.com/12345678",
+			Subject:   "12345678:app:ID",
+			ExpiresAt: jwt.NewNumericDate(mockTime.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(mockTime),
+		},
+	}
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	jwtToken.Header["kid"] = "FGQdnRlzAmKyKr6-Hg_kMQrBkj_H6i6ADnBQz4OI6BU"
+	token, err := jwtToken.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("error generating JWT: %v", err)
+	}
+
+	first, err := client.VerifyToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Claims["mutated"] = true
+
+	// Cached results are returned without repeating the checks, such as the audience check.
+	client.projectID = "other_project_id"
+	second, err := client.VerifyToken(token)
+	if err != nil {
+		t.Fatalf("VerifyToken(cached) = %v; want = nil", err)
+	}
+	if _, ok := second.Claims["mutated"]; ok || second.AppID != "12345678:app:ID" {
+		t.Errorf("VerifyToken(cached) = %#v; want = unmodified token", second)
+	}
+
+	// Expired tokens are evicted, and verified again.
+	jwt.TimeFunc = func() time.Time {
+		return mockTime.Add(2 * time.Hour)
+	}
+	if _, err := client.VerifyToken(token); !IsTokenExpired(err) {
+		t.Errorf("VerifyToken(expired) = %v; want = TokenExpired", err)
+	}
+}
+
+func TestTokenCacheEviction(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	cache := newTokenCache(2)
+	for _, token := range []string{"a", "b"} {
+		cache.put(token, &DecodedAppCheckToken{AppID: token, ExpiresAt: expires})
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Fatalf("get(a) = false; want = true")
+	}
+	cache.put("c", &DecodedAppCheckToken{AppID: "c", ExpiresAt: expires})
+
+	for token, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		decoded, ok := cache.get(token)
+		if ok != want || (ok && decoded.AppID != token) {
+			t.Errorf("get(%s) = (%v, %v); want = %v", token, decoded, ok, want)
+		}
+	}
+}
+
+func TestNewClientInvalidTokenCacheSize(t *testing.T) {
+	conf := &internal.AppCheckConfig{
+		ProjectID:      "project_id",
+		TokenCacheSize: -1,
+	}
+	want := "token cache size must not be negative"
+	if client, err := NewClient(context.Background(), conf); client != nil || err == nil || err.Error() != want {
+		t.Errorf("NewClient() = (%v, %v); want = (nil, %q)", client, err, want)
+	}
+}
+
+func TestNewClientSendsClientVersion(t *testing.T) {
+	jwks, err := os.ReadFile("../testdata/mock.jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var clientVersion string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientVersion = r.Header.Get("X-Client-Version")
+		w.Write(jwks)
+	}))
+	defer ts.Close()
+
+	JWKSUrl = ts.URL
+	conf := &internal.AppCheckConfig{
+		ProjectID: "project_id",
+		Version:   "test.version",
+	}
+	if _, err := NewClient(context.Background(), conf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Go/Admin/test.version"; clientVersion != want {
+		t.Errorf("X-Client-Version = %q; want = %q", clientVersion, want)
+	}
+}
+
 func setupFakeJWKS() (*httptest.Server, error) {
 	jwks, err := os.ReadFile("../testdata/mock.jwks.json")
 	if err != nil {
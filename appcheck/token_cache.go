@@ -0,0 +1,100 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appcheck
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// tokenCache is an LRU cache of verified App Check tokens, keyed by the token string. Entries are
+// evicted when the cache is full, and are never returned after the token has expired.
+type tokenCache struct {
+	size    int
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type tokenCacheEntry struct {
+	token   string
+	decoded *DecodedAppCheckToken
+}
+
+func newTokenCache(size int) *tokenCache {
+	return &tokenCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns a copy of the cached verification result of the given token, if the token is in the
+// cache and has not expired.
+func (c *tokenCache) get(token string) (*DecodedAppCheckToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[token]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*tokenCacheEntry)
+	if !jwt.TimeFunc().Before(entry.decoded.ExpiresAt) {
+		c.lru.Remove(elem)
+		delete(c.entries, token)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.decoded.copy(), true
+}
+
+// put adds the verification result of the given token to the cache, evicting the least recently
+// used token if the cache is full.
+func (c *tokenCache) put(token string, decoded *DecodedAppCheckToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[token]; ok {
+		elem.Value.(*tokenCacheEntry).decoded = decoded.copy()
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	if c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenCacheEntry).token)
+	}
+	c.entries[token] = c.lru.PushFront(&tokenCacheEntry{
+		token:   token,
+		decoded: decoded.copy(),
+	})
+}
+
+// copy returns a copy of the token with its own audience and claims, so that cached results are not
+// affected by changes made by the callers of VerifyToken.
+func (t *DecodedAppCheckToken) copy() *DecodedAppCheckToken {
+	result := *t
+	result.Audience = append([]string(nil), t.Audience...)
+	if t.Claims != nil {
+		result.Claims = make(map[string]interface{}, len(t.Claims))
+		for k, v := range t.Claims {
+			result.Claims[k] = v
+		}
+	}
+	return &result
+}
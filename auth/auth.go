@@ -18,14 +18,18 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"firebase.google.com/go/v4/internal"
 	"golang.org/x/oauth2"
+	"golang.org/x/time/rate"
 	"google.golang.org/api/option"
 	"google.golang.org/api/transport"
 )
@@ -37,11 +41,21 @@ const (
 	firebaseAudience   = "https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit"
 	oneHourInSeconds   = 3600
 
+	// Maximum duration by which the issued-at time of custom tokens may be backdated.
+	maxCustomTokenBackdate = 5 * time.Minute
+
+	// Default maximum number of requests issued concurrently by APIs that fan out over multiple
+	// resources.
+	maxConcurrentRequests = 10
+
 	// SDK-generated error codes
 	idTokenRevoked       = "ID_TOKEN_REVOKED"
 	userDisabled         = "USER_DISABLED"
 	sessionCookieRevoked = "SESSION_COOKIE_REVOKED"
 	tenantIDMismatch     = "TENANT_ID_MISMATCH"
+	tenantNotEmpty       = "TENANT_NOT_EMPTY"
+	secondFactorRequired = "SECOND_FACTOR_REQUIRED"
+	authTooOld           = "AUTH_TOO_OLD"
 )
 
 var reservedClaims = []string{
@@ -49,6 +63,10 @@ var reservedClaims = []string{
 	"exp", "firebase", "iat", "iss", "jti", "nbf", "nonce", "sub",
 }
 
+// reservedCustomTokenClaims extends reservedClaims with the claims that are set at the top level
+// of custom tokens by the SDK itself.
+var reservedCustomTokenClaims = append(append([]string{}, reservedClaims...), "uid", "tenant_id")
+
 var emulatorToken = &oauth2.Token{
 	AccessToken: "owner",
 }
@@ -110,15 +128,34 @@ func NewClient(ctx context.Context, conf *internal.AuthConfig) (*Client, error)
 		}
 	}
 
+	if conf.CustomTokenBackdate < 0 || conf.CustomTokenBackdate > maxCustomTokenBackdate {
+		return nil, errors.New("custom token backdate must not be negative or longer than 5 minutes")
+	}
+
 	idTokenVerifier, err := newIDTokenVerifier(ctx, conf.ProjectID)
 	if err != nil {
 		return nil, err
 	}
+	if conf.JWKSFile != "" {
+		idTokenVerifier.keySource = newJWKSFileKeySource(conf.JWKSFile)
+	}
 
 	cookieVerifier, err := newSessionCookieVerifier(ctx, conf.ProjectID)
 	if err != nil {
 		return nil, err
 	}
+	idTokenVerifier.strictEmulatorProjectID = conf.EmulatorStrictProjectID
+	cookieVerifier.strictEmulatorProjectID = conf.EmulatorStrictProjectID
+	idTokenVerifier.trackResources(conf.Tracker)
+	cookieVerifier.trackResources(conf.Tracker)
+	idTokenVerifier.limitResponseSize(conf.MaxResponseSize)
+	cookieVerifier.limitResponseSize(conf.MaxResponseSize)
+
+	noAuthHTTPClient, _, err := transport.NewHTTPClient(ctx, option.WithoutAuthentication())
+	if err != nil {
+		return nil, err
+	}
+	conf.Tracker.TrackHTTPClient(noAuthHTTPClient)
 
 	var opts []option.ClientOption
 	if isEmulator {
@@ -132,14 +169,20 @@ func NewClient(ctx context.Context, conf *internal.AuthConfig) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
+	conf.Tracker.TrackHTTPClient(transport)
 
 	hc := internal.WithDefaultRetryConfig(transport)
 	hc.CreateErrFn = handleHTTPError
 	hc.Opts = []internal.HTTPOption{
-		internal.WithHeader("X-Client-Version", fmt.Sprintf("Go/Admin/%s", conf.Version)),
+		internal.WithClientVersion(conf.Version),
 	}
+	hc.AccessTokenFromContext = true
+	hc.MaxResponseSize = conf.MaxResponseSize
 
 	baseURL := defaultAuthURL
+	if conf.IdentityToolkitBaseURL != "" {
+		baseURL = strings.TrimSuffix(conf.IdentityToolkitBaseURL, "/")
+	}
 	if isEmulator {
 		baseURL = fmt.Sprintf("http://%s/identitytoolkit.googleapis.com", authEmulatorHost)
 	}
@@ -162,6 +205,8 @@ func NewClient(ctx context.Context, conf *internal.AuthConfig) (*Client, error)
 		signer:                 signer,
 		clock:                  internal.SystemClock,
 		isEmulator:             isEmulator,
+		oidcKeys:               newOIDCKeyCache(noAuthHTTPClient, conf.MaxResponseSize),
+		customTokenBackdate:    conf.CustomTokenBackdate,
 	}
 	return &Client{
 		baseClient:    base,
@@ -195,6 +240,39 @@ func (c *baseClient) CustomToken(ctx context.Context, uid string) (string, error
 // CustomTokenWithClaims is similar to CustomToken, but in addition to the user ID, it also encodes
 // all the key-value pairs in the provided map as claims in the resulting JWT.
 func (c *baseClient) CustomTokenWithClaims(ctx context.Context, uid string, devClaims map[string]interface{}) (string, error) {
+	return c.customToken(ctx, uid, devClaims, oneHourInSeconds*time.Second)
+}
+
+// CustomTokenWithClaimsAndExpiry is similar to CustomTokenWithClaims, but the resulting JWT
+// expires after the given duration instead of one hour. The duration must be at least one second,
+// and not longer than one hour.
+//
+// When called on a tenant-scoped client, the token is issued for the tenant of the client. A
+// tenant_id claim may be specified for clarity, in which case it must match the tenant of the
+// client.
+func (c *baseClient) CustomTokenWithClaimsAndExpiry(
+	ctx context.Context, uid string, devClaims map[string]interface{}, expiresIn time.Duration) (string, error) {
+	if expiresIn < time.Second || expiresIn > oneHourInSeconds*time.Second {
+		return "", errors.New("custom token expiry must be between 1 second and 1 hour")
+	}
+
+	if tenantID, ok := devClaims["tenant_id"]; ok {
+		if tenantID != c.tenantID || c.tenantID == "" {
+			return "", fmt.Errorf("tenant_id claim does not match the tenant of the client: %q", c.tenantID)
+		}
+		claims := make(map[string]interface{}, len(devClaims))
+		for k, v := range devClaims {
+			if k != "tenant_id" {
+				claims[k] = v
+			}
+		}
+		devClaims = claims
+	}
+	return c.customToken(ctx, uid, devClaims, expiresIn)
+}
+
+func (c *baseClient) customToken(
+	ctx context.Context, uid string, devClaims map[string]interface{}, expiresIn time.Duration) (string, error) {
 	iss, err := c.signer.Email(ctx)
 	if err != nil {
 		return "", err
@@ -205,7 +283,7 @@ func (c *baseClient) CustomTokenWithClaims(ctx context.Context, uid string, devC
 	}
 
 	var disallowed []string
-	for _, k := range reservedClaims {
+	for _, k := range reservedCustomTokenClaims {
 		if _, contains := devClaims[k]; contains {
 			disallowed = append(disallowed, k)
 		}
@@ -216,7 +294,7 @@ func (c *baseClient) CustomTokenWithClaims(ctx context.Context, uid string, devC
 		return "", fmt.Errorf("developer claims %q are reserved and cannot be specified", strings.Join(disallowed, ", "))
 	}
 
-	now := c.clock.Now().Unix()
+	now := c.clock.Now().Add(-c.customTokenBackdate).Unix()
 	info := &jwtInfo{
 		header: jwtHeader{Algorithm: c.signer.Algorithm(), Type: "JWT"},
 		payload: &customToken{
@@ -225,7 +303,7 @@ func (c *baseClient) CustomTokenWithClaims(ctx context.Context, uid string, devC
 			Aud:      firebaseAudience,
 			UID:      uid,
 			Iat:      now,
-			Exp:      now + oneHourInSeconds,
+			Exp:      now + int64(expiresIn/time.Second),
 			TenantID: c.tenantID,
 			Claims:   devClaims,
 		},
@@ -235,7 +313,8 @@ func (c *baseClient) CustomTokenWithClaims(ctx context.Context, uid string, devC
 
 // SessionCookie creates a new Firebase session cookie from the given ID token and expiry
 // duration. The returned JWT can be set as a server-side session cookie with a custom cookie
-// policy. Expiry duration must be at least 5 minutes but may not exceed 14 days.
+// policy. Expiry duration must be at least 5 minutes but may not exceed 14 days, which is checked
+// before making any RPC calls.
 func (c *Client) SessionCookie(
 	ctx context.Context,
 	idToken string,
@@ -269,6 +348,74 @@ type FirebaseInfo struct {
 	SignInProvider string                 `json:"sign_in_provider"`
 	Tenant         string                 `json:"tenant"`
 	Identities     map[string]interface{} `json:"identities"`
+
+	// SignInSecondFactor is the type of the second factor (e.g. "phone") used to sign in, if the
+	// user signed in with multi-factor authentication.
+	SignInSecondFactor string `json:"sign_in_second_factor,omitempty"`
+
+	// SecondFactorIdentifier is the UID of the enrolled second factor used to sign in, if the user
+	// signed in with multi-factor authentication.
+	SecondFactorIdentifier string `json:"second_factor_identifier,omitempty"`
+}
+
+// Identities returns the identifiers of the user for each identity provider linked to the account,
+// as listed in the firebase.identities claim. For example, the "google.com" key maps to the
+// Google user IDs, and the "email" key maps to the email addresses of the user.
+//
+// Values in the claim that are not lists of strings are omitted.
+func (t *Token) Identities() map[string][]string {
+	identities := make(map[string][]string, len(t.Firebase.Identities))
+	for provider, raw := range t.Firebase.Identities {
+		list, ok := raw.([]interface{})
+		if !ok {
+			continue
+		}
+		var ids []string
+		for _, item := range list {
+			if id, ok := item.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		identities[provider] = ids
+	}
+	return identities
+}
+
+// EmailVerifiedByProvider checks if the email address in the token is verified, and the user has
+// an identity with the given federated provider (e.g. "google.com").
+//
+// Firebase Auth marks the email of an account as verified when a trusted federated provider
+// asserts it. Use this to accept such emails while rejecting emails that are merely marked as
+// verified on a password account. Always returns false for the "password" provider.
+func (t *Token) EmailVerifiedByProvider(providerID string) bool {
+	if providerID == "" || providerID == "password" {
+		return false
+	}
+	if verified, _ := t.Claims["email_verified"].(bool); !verified {
+		return false
+	}
+	return len(t.Identities()[providerID]) > 0
+}
+
+// ExpiresIn returns the remaining validity of the token at the given time, as indicated by its
+// exp claim. The result is negative if the token has expired by then.
+func (t *Token) ExpiresIn(now time.Time) time.Duration {
+	return time.Unix(t.Expires, 0).Sub(now)
+}
+
+// ClaimsAs stores the claims of the token in the value pointed to by v, which is typically a
+// pointer to a struct with fields for the custom claims of interest.
+//
+// The Claims map is serialized to JSON, and deserialized into v using
+// https://golang.org/pkg/encoding/json/#Unmarshal. Therefore v has the same requirements as the
+// json package, and struct fields are matched to claims by their json tags. The standard claims
+// exposed as fields of Token, such as iss and exp, are not part of the Claims map.
+func (t *Token) ClaimsAs(v interface{}) error {
+	b, err := json.Marshal(t.Claims)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
 }
 
 // baseClient exposes the APIs common to both auth.Client and auth.TenantClient.
@@ -285,6 +432,8 @@ type baseClient struct {
 	signer                 cryptoSigner
 	clock                  internal.Clock
 	isEmulator             bool
+	oidcKeys               *oidcKeyCache
+	customTokenBackdate    time.Duration
 }
 
 func (c *baseClient) withTenantID(tenantID string) *baseClient {
@@ -308,6 +457,10 @@ func (c *baseClient) withTenantID(tenantID string) *baseClient {
 //
 // This does not check whether or not the token has been revoked or disabled. Use `VerifyIDTokenAndCheckRevoked()`
 // when a revocation check is needed.
+//
+// When connected to the Auth Emulator, the signature of the token is not verified, and by default
+// neither are its issuer and audience checked against the project ID. Set
+// Config.AuthEmulatorStrictProjectID to enforce the project ID checks in emulator mode.
 func (c *baseClient) VerifyIDToken(ctx context.Context, idToken string) (*Token, error) {
 	return c.verifyIDToken(ctx, idToken, false)
 }
@@ -322,21 +475,155 @@ func (c *baseClient) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken s
 	return c.verifyIDToken(ctx, idToken, true)
 }
 
-func (c *baseClient) verifyIDToken(ctx context.Context, idToken string, checkRevokedOrDisabled bool) (*Token, error) {
-	decoded, err := c.idTokenVerifier.VerifyToken(ctx, idToken, c.isEmulator)
+// VerifyIDTokenAndCheckDisabled verifies the provided ID token, and additionally checks that the
+// user identified by the token has not been disabled. Use IsUserDisabled() to check for this error.
+//
+// Unlike VerifyIDTokenAndCheckRevoked(), this does not check whether the token has been revoked.
+// Like it, this function must make an RPC call to look up the user, which developers are advised
+// to take into consideration when including it in an authorization flow that gets executed often.
+func (c *baseClient) VerifyIDTokenAndCheckDisabled(ctx context.Context, idToken string) (*Token, error) {
+	decoded, err := c.verifyIDToken(ctx, idToken, false)
 	if err != nil {
 		return nil, err
 	}
 
-	if c.tenantID != "" && c.tenantID != decoded.Firebase.Tenant {
+	user, err := c.GetUser(ctx, decoded.UID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, userDisabledError()
+	}
+
+	return decoded, nil
+}
+
+// VerifyOptions specifies additional checks performed by VerifyIDTokenWithOptions.
+type VerifyOptions struct {
+	// CheckRevoked additionally checks that the token has not been revoked and that the user has
+	// not been disabled. This requires an RPC call, as in VerifyIDTokenAndCheckRevoked().
+	CheckRevoked bool
+
+	// RequireSecondFactor rejects tokens that were not obtained by signing in with a second factor.
+	// Use IsSecondFactorRequired() to check for this error.
+	RequireSecondFactor bool
+
+	// ClockSkew is the tolerated difference between the local clock and the clock of the token
+	// issuer, applied when checking the iat and exp claims. Values greater than 10 minutes are
+	// capped at 10 minutes, and negative values are rejected. Defaults to 5 minutes if zero.
+	ClockSkew time.Duration
+
+	// MaxAuthAge rejects tokens of users who signed in longer ago than the given duration, as
+	// indicated by the auth_time claim of the token. Unlike the expiry of the token, the auth_time
+	// is not updated when the token is refreshed, so this allows requiring a recent sign-in for
+	// sensitive operations. Use IsAuthTooOld() to check for this error, and prompt the user to
+	// re-authenticate. Negative values are rejected. No limit is applied if zero.
+	MaxAuthAge time.Duration
+}
+
+// VerifyIDTokenWithOptions verifies the provided ID token in the same way as VerifyIDToken(), and
+// additionally applies the checks specified in opts. If opts is nil, this behaves the same as
+// VerifyIDToken().
+func (c *baseClient) VerifyIDTokenWithOptions(ctx context.Context, idToken string, opts *VerifyOptions) (*Token, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+	if opts.ClockSkew < 0 {
+		return nil, errors.New("clock skew must not be negative")
+	}
+	if opts.MaxAuthAge < 0 {
+		return nil, errors.New("max auth age must not be negative")
+	}
+
+	verifier := c.idTokenVerifier
+	if opts.ClockSkew > 0 {
+		verifier = verifier.withClockSkew(opts.ClockSkew)
+	}
+	decoded, err := c.verifyIDTokenWith(ctx, verifier, idToken, opts.CheckRevoked)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.RequireSecondFactor && decoded.Firebase.SecondFactorIdentifier == "" {
 		return nil, &internal.FirebaseError{
 			ErrorCode: internal.InvalidArgument,
-			String:    fmt.Sprintf("invalid tenant id: %q", decoded.Firebase.Tenant),
+			String:    "ID token was not obtained by signing in with a second factor",
 			Ext: map[string]interface{}{
-				authErrorCode: tenantIDMismatch,
+				authErrorCode: secondFactorRequired,
 			},
 		}
 	}
+	if opts.MaxAuthAge > 0 && verifier.clock.Now().Sub(time.Unix(decoded.AuthTime, 0)) > opts.MaxAuthAge {
+		return nil, &internal.FirebaseError{
+			ErrorCode: internal.InvalidArgument,
+			String:    fmt.Sprintf("user signed in more than %v ago", opts.MaxAuthAge),
+			Ext: map[string]interface{}{
+				authErrorCode: authTooOld,
+			},
+		}
+	}
+
+	return decoded, nil
+}
+
+// VerifyIDTokenAndGetUser verifies the provided ID token in the same way as VerifyIDToken(), and
+// then looks up the UserRecord of the user identified by the token.
+//
+// Verification failures are returned without contacting the backend. Otherwise, this function
+// always makes an RPC call to fetch the user, in addition to any calls made to verify the token.
+func (c *baseClient) VerifyIDTokenAndGetUser(ctx context.Context, idToken string) (*Token, *UserRecord, error) {
+	decoded, err := c.verifyIDToken(ctx, idToken, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := c.GetUser(ctx, decoded.UID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decoded, user, nil
+}
+
+// VerifyIDTokenAndGetTenantClient verifies the provided ID token in the same way as
+// VerifyIDToken(), and returns a TenantClient scoped to the tenant that issued the token.
+//
+// This is a shorthand for verifying the token, and then calling
+// TenantManager.AuthForTenant() with the tenant ID of the token. A tenant ID mismatch error is
+// returned if the token does not belong to a tenant.
+func (c *Client) VerifyIDTokenAndGetTenantClient(ctx context.Context, idToken string) (*Token, *TenantClient, error) {
+	decoded, err := c.verifyIDToken(ctx, idToken, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tenantID := decoded.Firebase.Tenant
+	if tenantID == "" {
+		return nil, nil, tenantMismatchError(tenantID)
+	}
+
+	return decoded, &TenantClient{
+		baseClient: c.withTenantID(tenantID),
+	}, nil
+}
+
+func (c *baseClient) verifyIDToken(ctx context.Context, idToken string, checkRevokedOrDisabled bool) (*Token, error) {
+	return c.verifyIDTokenWith(ctx, c.idTokenVerifier, idToken, checkRevokedOrDisabled)
+}
+
+func (c *baseClient) verifyIDTokenWith(
+	ctx context.Context, verifier *tokenVerifier, idToken string, checkRevokedOrDisabled bool) (*Token, error) {
+	decoded, err := verifier.VerifyToken(ctx, idToken, c.isEmulator)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.tenantID != "" && c.tenantID != decoded.Firebase.Tenant {
+		return nil, tenantMismatchError(decoded.Firebase.Tenant)
+	}
+	if tenantID, ok := ExpectedTenant(ctx); ok && tenantID != decoded.Firebase.Tenant {
+		return nil, tenantMismatchError(decoded.Firebase.Tenant)
+	}
 
 	if c.isEmulator || checkRevokedOrDisabled {
 		err = c.checkRevokedOrDisabled(ctx, decoded, idTokenRevoked, "ID token has been revoked")
@@ -348,11 +635,76 @@ func (c *baseClient) verifyIDToken(ctx context.Context, idToken string, checkRev
 	return decoded, nil
 }
 
+func tenantMismatchError(tenantID string) error {
+	return &internal.FirebaseError{
+		ErrorCode: internal.InvalidArgument,
+		String:    fmt.Sprintf("invalid tenant id: %q", tenantID),
+		Ext: map[string]interface{}{
+			authErrorCode: tenantIDMismatch,
+		},
+	}
+}
+
+type expectedTenantKey struct{}
+
+// WithExpectedTenant returns a copy of ctx that carries the given tenant ID as the expected tenant
+// of the ID tokens verified with it.
+//
+// ID token verification functions called with the returned context fail with a tenant ID mismatch
+// error if the token was not issued by the expected tenant. Use an empty tenant ID to expect tokens
+// that do not belong to any tenant. This applies in addition to the tenant of a tenant-scoped
+// Client, if any.
+func WithExpectedTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, expectedTenantKey{}, tenantID)
+}
+
+// ExpectedTenant returns the expected tenant ID carried by ctx, and whether one was set by
+// WithExpectedTenant().
+func ExpectedTenant(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(expectedTenantKey{}).(string)
+	return tenantID, ok
+}
+
+// WithAccessToken returns a copy of ctx that carries the given OAuth2 access token.
+//
+// Calls made to the Auth APIs, such as user, tenant and project configuration management, with
+// the returned context are authorized with this token, instead of the credentials of the App.
+// This allows acting on behalf of an end user or another principal for a single call, such as
+// with a delegated OAuth2 token obtained in a request handler. The token is used as is, and is
+// not refreshed. Calls that do not contact the Auth APIs, such as the verification of ID tokens
+// against cached public keys or the signing of custom tokens, are not affected. Neither are the
+// other services of the App, such as Cloud Messaging or the Realtime Database.
+//
+// The token replaces the credentials that the App attaches to requests. When the App is
+// initialized with option.WithHTTPClient or a custom transport, its credentials cannot be
+// replaced safely, and calls made with the returned context fail with an error instead.
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return internal.WithAccessToken(ctx, token)
+}
+
 // IsTenantIDMismatch checks if the given error was due to a mismatched tenant ID in a JWT.
 func IsTenantIDMismatch(err error) bool {
 	return hasAuthErrorCode(err, tenantIDMismatch)
 }
 
+// IsTenantNotEmpty checks if the given error was due to an attempt to delete a tenant that still
+// has users.
+func IsTenantNotEmpty(err error) bool {
+	return hasAuthErrorCode(err, tenantNotEmpty)
+}
+
+// IsSecondFactorRequired checks if the given error was due to an ID token that was not obtained by
+// signing in with a second factor.
+func IsSecondFactorRequired(err error) bool {
+	return hasAuthErrorCode(err, secondFactorRequired)
+}
+
+// IsAuthTooOld checks if the given error was due to an ID token of a user who did not sign in
+// recently enough, as required by VerifyOptions.MaxAuthAge.
+func IsAuthTooOld(err error) bool {
+	return hasAuthErrorCode(err, authTooOld)
+}
+
 // IsIDTokenRevoked checks if the given error was due to a revoked ID token.
 //
 // When IsIDTokenRevoked returns true, IsIDTokenInvalid is guaranteed to return true.
@@ -381,6 +733,14 @@ func IsUserDisabled(err error) bool {
 //
 // This does not check whether or not the cookie has been revoked. Use `VerifySessionCookieAndCheckRevoked()`
 // when a revocation check is needed.
+//
+// The cookie is verified with the session cookie public keys, which are distinct from the keys of
+// ID tokens. Cookies signed with a key that has since been rotated out fail with an error for
+// which IsSessionCookieSigningKeyExpired() returns true, so that the user can be prompted to sign
+// in again.
+//
+// As with VerifyIDToken(), the issuer and audience of the cookie are not checked against the
+// project ID in emulator mode, unless Config.AuthEmulatorStrictProjectID is set.
 func (c *Client) VerifySessionCookie(ctx context.Context, sessionCookie string) (*Token, error) {
 	return c.verifySessionCookie(ctx, sessionCookie, false)
 }
@@ -395,8 +755,43 @@ func (c *Client) VerifySessionCookieAndCheckRevoked(ctx context.Context, session
 	return c.verifySessionCookie(ctx, sessionCookie, true)
 }
 
+// SessionCookieVerifyOptions specifies additional checks performed by
+// VerifySessionCookieWithOptions.
+type SessionCookieVerifyOptions struct {
+	// CheckRevoked additionally checks that the cookie has not been revoked and that the user has
+	// not been disabled. This requires an RPC call, as in VerifySessionCookieAndCheckRevoked().
+	CheckRevoked bool
+
+	// Audiences is the set of acceptable audience (aud) claims. The session cookie is rejected only
+	// if its audience matches none of them. If empty, the audience must match the project ID.
+	Audiences []string
+}
+
+// VerifySessionCookieWithOptions verifies the provided session cookie in the same way as
+// VerifySessionCookie(), and additionally applies the checks specified in opts. If opts is nil,
+// this behaves the same as VerifySessionCookie().
+//
+// Setting Audiences allows a single backend to accept session cookies issued for several front
+// ends. The issuer (iss) claim must still match the project ID.
+func (c *Client) VerifySessionCookieWithOptions(ctx context.Context, sessionCookie string, opts *SessionCookieVerifyOptions) (*Token, error) {
+	if opts == nil {
+		opts = &SessionCookieVerifyOptions{}
+	}
+
+	verifier := c.cookieVerifier
+	if len(opts.Audiences) > 0 {
+		verifier = verifier.withAudiences(opts.Audiences)
+	}
+	return c.verifySessionCookieWith(ctx, verifier, sessionCookie, opts.CheckRevoked)
+}
+
 func (c *Client) verifySessionCookie(ctx context.Context, sessionCookie string, checkRevokedOrDisabled bool) (*Token, error) {
-	decoded, err := c.cookieVerifier.VerifyToken(ctx, sessionCookie, c.isEmulator)
+	return c.verifySessionCookieWith(ctx, c.cookieVerifier, sessionCookie, checkRevokedOrDisabled)
+}
+
+func (c *Client) verifySessionCookieWith(
+	ctx context.Context, verifier *tokenVerifier, sessionCookie string, checkRevokedOrDisabled bool) (*Token, error) {
+	decoded, err := verifier.VerifyToken(ctx, sessionCookie, c.isEmulator)
 	if err != nil {
 		return nil, err
 	}
@@ -425,14 +820,7 @@ func (c *baseClient) checkRevokedOrDisabled(ctx context.Context, token *Token, e
 		return err
 	}
 	if user.Disabled {
-		return &internal.FirebaseError{
-			ErrorCode: internal.InvalidArgument,
-			String:    "user has been disabled",
-			Ext: map[string]interface{}{
-				authErrorCode: userDisabled,
-			},
-		}
-
+		return userDisabledError()
 	}
 	if token.IssuedAt*1000 < user.TokensValidAfterMillis {
 		return &internal.FirebaseError{
@@ -446,6 +834,129 @@ func (c *baseClient) checkRevokedOrDisabled(ctx context.Context, token *Token, e
 	return nil
 }
 
+func userDisabledError() error {
+	return &internal.FirebaseError{
+		ErrorCode: internal.InvalidArgument,
+		String:    "user has been disabled",
+		Ext: map[string]interface{}{
+			authErrorCode: userDisabled,
+		},
+	}
+}
+
+// BatchOptions controls how APIs that fan out over multiple resources (e.g. CreateUsers) issue
+// their requests, so that callers can stay within the Identity Toolkit quota.
+type BatchOptions struct {
+	// Concurrency is the maximum number of requests in flight at any time. Defaults to 10 if not
+	// positive.
+	Concurrency int
+
+	// RateLimit is the maximum number of requests started per second. No rate limit is applied
+	// if zero.
+	RateLimit rate.Limit
+}
+
+// runConcurrently invokes fn for each index in [0, n), as permitted by the given options. It
+// returns once all invocations have completed.
+func runConcurrently(ctx context.Context, n int, opts *BatchOptions, fn func(idx int)) {
+	concurrency := maxConcurrentRequests
+	var limiter *rate.Limiter
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		if opts.RateLimit > 0 {
+			limiter = rate.NewLimiter(opts.RateLimit, 1)
+		}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for idx := 0; idx < n; idx++ {
+		if limiter != nil {
+			// If the context is done, fn is still invoked so it can report the error.
+			limiter.Wait(ctx)
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(idx)
+		}(idx)
+	}
+	wg.Wait()
+}
+
+// authErrorStatus maps the auth error codes to the HTTP status that a server should respond with
+// when a request fails with the corresponding error.
+var authErrorStatus = map[string]int{
+	idTokenExpired:          http.StatusUnauthorized,
+	idTokenInvalid:          http.StatusUnauthorized,
+	idTokenRevoked:          http.StatusUnauthorized,
+	sessionCookieExpired:    http.StatusUnauthorized,
+	sessionCookieInvalid:    http.StatusUnauthorized,
+	sessionCookieRevoked:    http.StatusUnauthorized,
+	sessionCookieKeyExpired: http.StatusUnauthorized,
+	tenantIDMismatch:        http.StatusUnauthorized,
+	userDisabled:            http.StatusForbidden,
+	secondFactorRequired:    http.StatusForbidden,
+	authTooOld:              http.StatusUnauthorized,
+	certificateFetchFailed:  http.StatusServiceUnavailable,
+	oidcTokenInvalid:        http.StatusUnauthorized,
+	blockingTokenInvalid:    http.StatusUnauthorized,
+}
+
+// platformErrorStatus maps the platform error codes to HTTP statuses, for the errors that do not
+// have an auth error code listed in authErrorStatus.
+var platformErrorStatus = map[internal.ErrorCode]int{
+	internal.InvalidArgument:    http.StatusBadRequest,
+	internal.FailedPrecondition: http.StatusBadRequest,
+	internal.OutOfRange:         http.StatusBadRequest,
+	internal.Unauthenticated:    http.StatusUnauthorized,
+	internal.PermissionDenied:   http.StatusForbidden,
+	internal.NotFound:           http.StatusNotFound,
+	internal.Conflict:           http.StatusConflict,
+	internal.Aborted:            http.StatusConflict,
+	internal.AlreadyExists:      http.StatusConflict,
+	internal.ResourceExhausted:  http.StatusTooManyRequests,
+	internal.Unavailable:        http.StatusServiceUnavailable,
+	internal.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// HTTPStatus returns the HTTP status that a server should respond with when handling a request
+// fails with the given error. This allows HTTP middleware to translate the errors returned by the
+// auth package into responses, without checking each error with the Is... functions.
+//
+// Invalid, expired and revoked ID tokens and session cookies, as well as tokens issued for a
+// different tenant, map to 401 Unauthorized, as do tokens of users who did not sign in recently
+// enough. Disabled users and tokens lacking a required second factor map to 403 Forbidden. Other
+// errors returned by this package map to the status corresponding to their platform error code,
+// such as 404 Not Found for a missing user. Errors that cannot be classified, including errors not
+// returned by this package, map to 500 Internal Server Error. HTTPStatus returns 200 OK if err is
+// nil.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	fe, ok := err.(*internal.FirebaseError)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+
+	if code, ok := fe.Ext[authErrorCode].(string); ok {
+		if status, ok := authErrorStatus[code]; ok {
+			return status
+		}
+	}
+	if status, ok := platformErrorStatus[fe.ErrorCode]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
 func hasAuthErrorCode(err error, code string) bool {
 	fe, ok := err.(*internal.FirebaseError)
 	if !ok {
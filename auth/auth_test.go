@@ -23,7 +23,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -31,6 +33,7 @@ import (
 	"firebase.google.com/go/v4/errorutils"
 	"firebase.google.com/go/v4/internal"
 	"golang.org/x/oauth2/google"
+	"golang.org/x/time/rate"
 	"google.golang.org/api/option"
 	"google.golang.org/api/transport"
 )
@@ -316,6 +319,76 @@ func TestNewClientEmulatorHostEnvVar(t *testing.T) {
 	}
 }
 
+func TestNewClientIdentityToolkitBaseURL(t *testing.T) {
+	idToolkitV1Endpoint := "https://us-identitytoolkit.p.example.com/v1"
+	idToolkitV2Endpoint := "https://us-identitytoolkit.p.example.com/v2"
+
+	client, err := NewClient(context.Background(), &internal.AuthConfig{
+		Opts:                   optsWithTokenSource,
+		IdentityToolkitBaseURL: "https://us-identitytoolkit.p.example.com/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseClient := client.baseClient
+	if baseClient.userManagementEndpoint != idToolkitV1Endpoint {
+		t.Errorf("baseClient.userManagementEndpoint = %q; want = %q", baseClient.userManagementEndpoint, idToolkitV1Endpoint)
+	}
+	if baseClient.providerConfigEndpoint != idToolkitV2Endpoint {
+		t.Errorf("baseClient.providerConfigEndpoint = %q; want = %q", baseClient.providerConfigEndpoint, idToolkitV2Endpoint)
+	}
+	if baseClient.tenantMgtEndpoint != idToolkitV2Endpoint {
+		t.Errorf("baseClient.tenantMgtEndpoint = %q; want = %q", baseClient.tenantMgtEndpoint, idToolkitV2Endpoint)
+	}
+	if baseClient.projectMgtEndpoint != idToolkitV2Endpoint {
+		t.Errorf("baseClient.projectMgtEndpoint = %q; want = %q", baseClient.projectMgtEndpoint, idToolkitV2Endpoint)
+	}
+	if client.TenantManager.endpoint != idToolkitV2Endpoint {
+		t.Errorf("TenantManager.endpoint = %q; want = %q", client.TenantManager.endpoint, idToolkitV2Endpoint)
+	}
+}
+
+func TestNewClientMaxResponseSize(t *testing.T) {
+	client, err := NewClient(context.Background(), &internal.AuthConfig{
+		Opts:            optsWithTokenSource,
+		MaxResponseSize: 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseClient := client.baseClient
+	if got := baseClient.httpClient.MaxResponseSize; got != 1024 {
+		t.Errorf("httpClient.MaxResponseSize = %d; want = 1024", got)
+	}
+	for _, tv := range []*tokenVerifier{baseClient.idTokenVerifier, baseClient.cookieVerifier} {
+		if got := tv.keySource.(*httpKeySource).MaxResponseSize; got != 1024 {
+			t.Errorf("%s keySource.MaxResponseSize = %d; want = 1024", tv.shortName, got)
+		}
+	}
+	if got := baseClient.oidcKeys.maxResponseSize; got != 1024 {
+		t.Errorf("oidcKeys.maxResponseSize = %d; want = 1024", got)
+	}
+}
+
+func TestNewClientIdentityToolkitBaseURLWithEmulator(t *testing.T) {
+	os.Setenv(emulatorHostEnvVar, "localhost:9099")
+	defer os.Unsetenv(emulatorHostEnvVar)
+
+	client, err := NewClient(context.Background(), &internal.AuthConfig{
+		IdentityToolkitBaseURL: "https://us-identitytoolkit.p.example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "http://localhost:9099/identitytoolkit.googleapis.com/v1"
+	if client.baseClient.userManagementEndpoint != want {
+		t.Errorf("baseClient.userManagementEndpoint = %q; want = %q", client.baseClient.userManagementEndpoint, want)
+	}
+}
+
 func TestCustomToken(t *testing.T) {
 	client := &Client{
 		baseClient: &baseClient{
@@ -416,6 +489,150 @@ func TestCustomTokenError(t *testing.T) {
 	}
 }
 
+func TestCustomTokenReservedClaims(t *testing.T) {
+	client := &baseClient{
+		signer: testSigner,
+		clock:  testClock,
+	}
+	reserved := []string{
+		"acr", "amr", "at_hash", "aud", "auth_time", "azp", "cnf", "c_hash", "exp", "firebase",
+		"iat", "iss", "jti", "nbf", "nonce", "sub", "uid", "tenant_id",
+	}
+	for _, claim := range reserved {
+		claims := map[string]interface{}{claim: "value", "premium": true}
+		token, err := client.CustomTokenWithClaims(context.Background(), "user1", claims)
+		want := fmt.Sprintf("developer claim %q is reserved and cannot be specified", claim)
+		if token != "" || err == nil || err.Error() != want {
+			t.Errorf("CustomTokenWithClaims(%q) = (%q, %v); want = (\"\", %q)", claim, token, err, want)
+		}
+	}
+}
+
+func TestCustomTokenWithClaimsAndExpiry(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			tenantID: "tenantID",
+			signer:   testSigner,
+			clock:    testClock,
+		},
+	}
+	cases := []map[string]interface{}{
+		nil,
+		{"device": "sensor-1"},
+		{"device": "sensor-1", "tenant_id": "tenantID"},
+	}
+	for _, claims := range cases {
+		token, err := client.CustomTokenWithClaimsAndExpiry(context.Background(), "user1", claims, 5*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var payload customToken
+		if err := decode(strings.Split(token, ".")[1], &payload); err != nil {
+			t.Fatal(err)
+		}
+		now := testClock.Now().Unix()
+		if payload.Iat != now || payload.Exp != now+300 {
+			t.Errorf("(Iat, Exp) = (%d, %d); want = (%d, %d)", payload.Iat, payload.Exp, now, now+300)
+		}
+		if payload.TenantID != "tenantID" {
+			t.Errorf("TenantID = %q; want = %q", payload.TenantID, "tenantID")
+		}
+		if payload.Claims["device"] != claims["device"] {
+			t.Errorf("Claims[device] = %v; want = %v", payload.Claims["device"], claims["device"])
+		}
+		if _, ok := payload.Claims["tenant_id"]; ok {
+			t.Errorf("Claims[tenant_id] = %v; want = none", payload.Claims["tenant_id"])
+		}
+	}
+}
+
+func TestCustomTokenWithClaimsAndExpiryError(t *testing.T) {
+	cases := []struct {
+		name      string
+		tenantID  string
+		claims    map[string]interface{}
+		expiresIn time.Duration
+		want      string
+	}{
+		{
+			name:      "ZeroExpiry",
+			expiresIn: 0,
+			want:      "custom token expiry must be between 1 second and 1 hour",
+		},
+		{
+			name:      "LongExpiry",
+			expiresIn: time.Hour + time.Second,
+			want:      "custom token expiry must be between 1 second and 1 hour",
+		},
+		{
+			name:      "TenantMismatch",
+			tenantID:  "tenantID",
+			claims:    map[string]interface{}{"tenant_id": "otherTenantID"},
+			expiresIn: time.Minute,
+			want:      `tenant_id claim does not match the tenant of the client: "tenantID"`,
+		},
+		{
+			name:      "NoClientTenant",
+			claims:    map[string]interface{}{"tenant_id": ""},
+			expiresIn: time.Minute,
+			want:      `tenant_id claim does not match the tenant of the client: ""`,
+		},
+		{
+			name:      "ReservedClaim",
+			claims:    map[string]interface{}{"sub": "1234"},
+			expiresIn: time.Minute,
+			want:      `developer claim "sub" is reserved and cannot be specified`,
+		},
+	}
+
+	for _, tc := range cases {
+		client := &baseClient{
+			tenantID: tc.tenantID,
+			signer:   testSigner,
+			clock:    testClock,
+		}
+		token, err := client.CustomTokenWithClaimsAndExpiry(context.Background(), "user1", tc.claims, tc.expiresIn)
+		if token != "" || err == nil || err.Error() != tc.want {
+			t.Errorf("CustomTokenWithClaimsAndExpiry(%s) = (%q, %v); want = (\"\", %q)", tc.name, token, err, tc.want)
+		}
+	}
+}
+
+func TestCustomTokenBackdate(t *testing.T) {
+	client := &baseClient{
+		signer:              testSigner,
+		clock:               testClock,
+		customTokenBackdate: 30 * time.Second,
+	}
+	token, err := client.CustomToken(context.Background(), "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload customToken
+	if err := decode(strings.Split(token, ".")[1], &payload); err != nil {
+		t.Fatal(err)
+	}
+	iat := testClock.Now().Unix() - 30
+	if payload.Iat != iat || payload.Exp != iat+3600 {
+		t.Errorf("(Iat, Exp) = (%d, %d); want = (%d, %d)", payload.Iat, payload.Exp, iat, iat+3600)
+	}
+}
+
+func TestNewClientInvalidCustomTokenBackdate(t *testing.T) {
+	for _, backdate := range []time.Duration{-time.Second, 5*time.Minute + time.Second} {
+		conf := &internal.AuthConfig{
+			Opts:                optsWithTokenSource,
+			ProjectID:           "mock-project-id",
+			CustomTokenBackdate: backdate,
+		}
+		if client, err := NewClient(context.Background(), conf); client != nil || err == nil {
+			t.Errorf("NewClient(%v) = (%v, %v); want = (nil, error)", backdate, client, err)
+		}
+	}
+}
+
 func TestCustomTokenInvalidCredential(t *testing.T) {
 	ctx := context.Background()
 	conf := &internal.AuthConfig{
@@ -504,6 +721,95 @@ func TestVerifyIDTokenFromTenant(t *testing.T) {
 	}
 }
 
+func TestVerifyIDTokenExpectedTenant(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			idTokenVerifier: testIDTokenVerifier,
+		},
+	}
+	tenantToken := getIDToken(mockIDTokenPayload{
+		"firebase": map[string]interface{}{
+			"tenant":           "tenantID",
+			"sign_in_provider": "custom",
+		},
+	})
+
+	ctx := WithExpectedTenant(context.Background(), "tenantID")
+	if got, ok := ExpectedTenant(ctx); got != "tenantID" || !ok {
+		t.Errorf("ExpectedTenant() = (%q, %v); want = (%q, true)", got, ok, "tenantID")
+	}
+	ft, err := client.VerifyIDToken(ctx, tenantToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.Firebase.Tenant != "tenantID" {
+		t.Errorf("Tenant = %q; want = %q", ft.Firebase.Tenant, "tenantID")
+	}
+
+	cases := []struct {
+		name     string
+		tenantID string
+		idToken  string
+	}{
+		{"OtherTenant", "otherTenantID", tenantToken},
+		{"NoTenantInToken", "tenantID", testIDToken},
+		{"NoTenantExpected", "", tenantToken},
+	}
+	for _, tc := range cases {
+		ctx := WithExpectedTenant(context.Background(), tc.tenantID)
+		ft, err := client.VerifyIDToken(ctx, tc.idToken)
+		if ft != nil || !IsTenantIDMismatch(err) {
+			t.Errorf("VerifyIDToken(%s) = (%v, %v); want = (nil, %q)", tc.name, ft, err, tenantIDMismatch)
+		}
+	}
+}
+
+func TestExpectedTenantNotSet(t *testing.T) {
+	if got, ok := ExpectedTenant(context.Background()); got != "" || ok {
+		t.Errorf("ExpectedTenant() = (%q, %v); want = (\"\", false)", got, ok)
+	}
+}
+
+func TestWithAccessToken(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	var auth []string
+	s.Srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(testGetUserResponse)
+	})
+
+	ctx := WithAccessToken(context.Background(), "user-token")
+	if _, err := s.Client.GetUser(ctx, "testuser"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.GetUser(context.Background(), "testuser"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Bearer user-token", "Bearer test.token"}
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("Authorization = %v; want = %v", auth, want)
+	}
+}
+
+func TestWithAccessTokenCustomHTTPClient(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	// Clients specified with option.WithHTTPClient are used as is.
+	s.Client.baseClient.httpClient.Client = &http.Client{Transport: http.DefaultTransport}
+
+	ctx := WithAccessToken(context.Background(), "user-token")
+	user, err := s.Client.GetUser(ctx, "testuser")
+	if user != nil || err == nil {
+		t.Errorf("GetUser() = (%v, %v); want = (nil, error)", user, err)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("requests = %d; want = 0", len(s.Req))
+	}
+}
+
 func TestVerifyIDTokenClockSkew(t *testing.T) {
 	now := testClock.Now().Unix()
 	cases := []struct {
@@ -763,6 +1069,50 @@ func TestEmulatorVerifyIDTokenExpiredError(t *testing.T) {
 	}
 }
 
+func TestEmulatorVerifyIDTokenProjectMismatch(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+
+	s.Client.idTokenVerifier = testIDTokenVerifier
+	s.Client.isEmulator = true
+
+	token := getEmulatedIDToken(mockIDTokenPayload{
+		"aud": "other-project",
+		"iss": "https://securetoken.google.com/other-project",
+	})
+	ft, err := s.Client.VerifyIDToken(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.Audience != "other-project" || ft.Claims["admin"] != true {
+		t.Errorf("VerifyIDToken() = %#v; want token of other-project", ft)
+	}
+
+	s.Client.idTokenVerifier = testIDTokenVerifier.withAudiences(nil)
+	s.Client.idTokenVerifier.strictEmulatorProjectID = true
+	if ft, err := s.Client.VerifyIDToken(context.Background(), token); ft != nil || !IsIDTokenInvalid(err) {
+		t.Errorf("VerifyIDToken(Strict) = (%v, %v); want = (nil, IDTokenInvalid)", ft, err)
+	}
+	if ft, err := s.Client.VerifyIDToken(context.Background(), getEmulatedIDToken(nil)); ft == nil || err != nil {
+		t.Errorf("VerifyIDToken(Strict) = (%v, %v); want = (token, nil)", ft, err)
+	}
+}
+
+func TestVerifyIDTokenProjectMismatchNotLenient(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			idTokenVerifier: testIDTokenVerifier,
+		},
+	}
+	token := getIDToken(mockIDTokenPayload{
+		"aud": "other-project",
+		"iss": "https://securetoken.google.com/other-project",
+	})
+	if ft, err := client.VerifyIDToken(context.Background(), token); ft != nil || !IsIDTokenInvalid(err) {
+		t.Errorf("VerifyIDToken() = (%v, %v); want = (nil, IDTokenInvalid)", ft, err)
+	}
+}
+
 func TestEmulatorVerifyIDTokenUnreachableEmulator(t *testing.T) {
 	conf := &internal.AuthConfig{
 		Opts:      optsWithTokenSource,
@@ -835,6 +1185,183 @@ func TestVerifyIDTokenAndCheckRevoked(t *testing.T) {
 	}
 }
 
+func TestVerifyIDTokenAndGetUser(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	ft, user, err := s.Client.VerifyIDTokenAndGetUser(context.Background(), testIDToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.UID != ft.Subject {
+		t.Errorf("UID = %q; Sub = %q; want UID = Sub", ft.UID, ft.Subject)
+	}
+	if user.UID != testUser.UID {
+		t.Errorf("VerifyIDTokenAndGetUser() UID = %q; want = %q", user.UID, testUser.UID)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("VerifyIDTokenAndGetUser() = %d requests; want = 1", len(s.Req))
+	}
+}
+
+func TestVerifyIDTokenAndGetUserInvalidToken(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	ft, user, err := s.Client.VerifyIDTokenAndGetUser(context.Background(), "")
+	if ft != nil || user != nil || err == nil {
+		t.Errorf("VerifyIDTokenAndGetUser('') = (%v, %v, %v); want = (nil, nil, error)", ft, user, err)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("VerifyIDTokenAndGetUser('') = %d requests; want = 0", len(s.Req))
+	}
+}
+
+func TestVerifyIDTokenAndGetTenantClient(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			idTokenVerifier: testIDTokenVerifier,
+		},
+	}
+	tenantToken := getIDToken(mockIDTokenPayload{
+		"firebase": map[string]interface{}{
+			"tenant":           "tenantID",
+			"sign_in_provider": "custom",
+		},
+	})
+
+	ft, tc, err := client.VerifyIDTokenAndGetTenantClient(context.Background(), tenantToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.Firebase.Tenant != "tenantID" {
+		t.Errorf("Tenant = %q; want = %q", ft.Firebase.Tenant, "tenantID")
+	}
+	if tc.TenantID() != "tenantID" {
+		t.Errorf("TenantID() = %q; want = %q", tc.TenantID(), "tenantID")
+	}
+	if _, err := tc.VerifyIDToken(context.Background(), tenantToken); err != nil {
+		t.Errorf("TenantClient.VerifyIDToken() = %v", err)
+	}
+}
+
+func TestVerifyIDTokenAndGetTenantClientError(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			idTokenVerifier: testIDTokenVerifier,
+		},
+	}
+
+	ft, tc, err := client.VerifyIDTokenAndGetTenantClient(context.Background(), testIDToken)
+	if ft != nil || tc != nil || !IsTenantIDMismatch(err) {
+		t.Errorf("VerifyIDTokenAndGetTenantClient() = (%v, %v, %v); want = (nil, nil, %q)", ft, tc, err, tenantIDMismatch)
+	}
+
+	ft, tc, err = client.VerifyIDTokenAndGetTenantClient(context.Background(), "invalid")
+	if ft != nil || tc != nil || !IsIDTokenInvalid(err) {
+		t.Errorf("VerifyIDTokenAndGetTenantClient() = (%v, %v, %v); want = (nil, nil, IDTokenInvalid)", ft, tc, err)
+	}
+}
+
+func TestTokenExpiresIn(t *testing.T) {
+	ft, err := testIDTokenVerifier.VerifyToken(context.Background(), getIDToken(nil), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := testClock.Now()
+	want := time.Unix(ft.Expires, 0).Sub(now)
+	if got := ft.ExpiresIn(now); got != want || got <= 0 {
+		t.Errorf("ExpiresIn() = %v; want = %v", got, want)
+	}
+
+	later := time.Unix(ft.Expires, 0).Add(time.Minute)
+	if got := ft.ExpiresIn(later); got != -time.Minute {
+		t.Errorf("ExpiresIn(after expiry) = %v; want = %v", got, -time.Minute)
+	}
+}
+
+func TestTokenClaimsAs(t *testing.T) {
+	ft, err := testIDTokenVerifier.VerifyToken(context.Background(), getIDToken(mockIDTokenPayload{
+		"role":   "admin",
+		"level":  3,
+		"groups": []string{"a", "b"},
+	}), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims struct {
+		Role   string   `json:"role"`
+		Level  int      `json:"level"`
+		Groups []string `json:"groups"`
+		Issuer string   `json:"iss"`
+	}
+	if err := ft.ClaimsAs(&claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != "admin" || claims.Level != 3 || !reflect.DeepEqual(claims.Groups, []string{"a", "b"}) {
+		t.Errorf("ClaimsAs() = %#v; want = {Role: admin, Level: 3, Groups: [a b]}", claims)
+	}
+	if claims.Issuer != "" {
+		t.Errorf("ClaimsAs().Issuer = %q; want = %q", claims.Issuer, "")
+	}
+
+	var mismatch struct {
+		Role int `json:"role"`
+	}
+	if err := ft.ClaimsAs(&mismatch); err == nil {
+		t.Errorf("ClaimsAs(mismatched type) = nil; want error")
+	}
+	if err := ft.ClaimsAs(nil); err == nil {
+		t.Errorf("ClaimsAs(nil) = nil; want error")
+	}
+}
+
+func TestTokenIdentities(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	idToken := getIDToken(mockIDTokenPayload{
+		"email_verified": true,
+		"firebase": map[string]interface{}{
+			"sign_in_provider": "google.com",
+			"identities": map[string]interface{}{
+				"google.com": []interface{}{"google_uid"},
+				"email":      []interface{}{"user@example.com"},
+				"invalid":    "not-a-list",
+			},
+		},
+	})
+	ft, err := s.Client.VerifyIDToken(context.Background(), idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"google.com": {"google_uid"},
+		"email":      {"user@example.com"},
+	}
+	if got := ft.Identities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Identities() = %v; want = %v", got, want)
+	}
+	if !ft.EmailVerifiedByProvider("google.com") {
+		t.Errorf("EmailVerifiedByProvider(google.com) = false; want = true")
+	}
+	for _, provider := range []string{"apple.com", "password", ""} {
+		if ft.EmailVerifiedByProvider(provider) {
+			t.Errorf("EmailVerifiedByProvider(%q) = true; want = false", provider)
+		}
+	}
+
+	ft.Claims["email_verified"] = false
+	if ft.EmailVerifiedByProvider("google.com") {
+		t.Errorf("EmailVerifiedByProvider(google.com) with unverified email = true; want = false")
+	}
+}
+
 func TestVerifyIDTokenDoesNotCheckRevoked(t *testing.T) {
 	s := echoServer(testGetUserResponse, t)
 	defer s.Close()
@@ -881,6 +1408,136 @@ func TestVerifyIDTokenAndCheckRevokedError(t *testing.T) {
 	}
 }
 
+func TestVerifyIDTokenWithOptionsSecondFactor(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	token := getIDToken(mockIDTokenPayload{
+		"firebase": map[string]interface{}{
+			"identities":               map[string]interface{}{},
+			"sign_in_provider":         "password",
+			"sign_in_second_factor":    "phone",
+			"second_factor_identifier": "enrolled-factor-uid",
+		},
+	})
+	opts := &VerifyOptions{RequireSecondFactor: true}
+	ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), token, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.Firebase.SignInSecondFactor != "phone" {
+		t.Errorf("SignInSecondFactor = %q; want = %q", ft.Firebase.SignInSecondFactor, "phone")
+	}
+	if ft.Firebase.SecondFactorIdentifier != "enrolled-factor-uid" {
+		t.Errorf("SecondFactorIdentifier = %q; want = %q", ft.Firebase.SecondFactorIdentifier, "enrolled-factor-uid")
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("VerifyIDTokenWithOptions() requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestVerifyIDTokenWithOptionsSecondFactorError(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	opts := &VerifyOptions{RequireSecondFactor: true}
+	ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), testIDToken, opts)
+	we := "ID token was not obtained by signing in with a second factor"
+	if ft != nil || !IsSecondFactorRequired(err) || err.Error() != we {
+		t.Errorf("VerifyIDTokenWithOptions() = (%v, %v); want = (nil, %q)", ft, err, we)
+	}
+
+	if ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), testIDToken, nil); ft == nil || err != nil {
+		t.Errorf("VerifyIDTokenWithOptions(nil) = (%v, %v); want = (token, nil)", ft, err)
+	}
+}
+
+func TestVerifyIDTokenWithOptionsMaxAuthAge(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	// The default auth_time of the test tokens is 100 seconds ago.
+	opts := &VerifyOptions{MaxAuthAge: 5 * time.Minute}
+	ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), testIDToken, opts)
+	if ft == nil || err != nil {
+		t.Errorf("VerifyIDTokenWithOptions() = (%v, %v); want = (token, nil)", ft, err)
+	}
+
+	token := getIDToken(mockIDTokenPayload{"auth_time": testClock.Now().Unix() - 3600})
+	ft, err = s.Client.VerifyIDTokenWithOptions(context.Background(), token, opts)
+	we := "user signed in more than 5m0s ago"
+	if ft != nil || !IsAuthTooOld(err) || err.Error() != we {
+		t.Errorf("VerifyIDTokenWithOptions() = (%v, %v); want = (nil, %q)", ft, err, we)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("VerifyIDTokenWithOptions() requests = %d; want = 0", len(s.Req))
+	}
+
+	we = "max auth age must not be negative"
+	opts = &VerifyOptions{MaxAuthAge: -time.Minute}
+	if ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), testIDToken, opts); ft != nil || err == nil || err.Error() != we {
+		t.Errorf("VerifyIDTokenWithOptions() = (%v, %v); want = (nil, %q)", ft, err, we)
+	}
+}
+
+func TestVerifyIDTokenWithOptionsCheckRevoked(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	revokedToken := getIDToken(mockIDTokenPayload{"uid": "uid", "iat": 1970})
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	p, err := s.Client.VerifyIDTokenWithOptions(context.Background(), revokedToken, &VerifyOptions{CheckRevoked: true})
+	if p != nil || !IsIDTokenRevoked(err) {
+		t.Errorf("VerifyIDTokenWithOptions() = (%v, %v); want = (nil, IDTokenRevoked)", p, err)
+	}
+}
+
+func TestVerifyIDTokenWithOptionsClockSkew(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	now := testClock.Now().Unix()
+	expired := getIDToken(mockIDTokenPayload{
+		"iat": now - 10000,
+		"exp": now - 120,
+	})
+	future := getIDToken(mockIDTokenPayload{"iat": now + 120})
+
+	// Both tokens are within the default leeway.
+	for _, token := range []string{expired, future} {
+		if _, err := s.Client.VerifyIDTokenWithOptions(context.Background(), token, nil); err != nil {
+			t.Errorf("VerifyIDTokenWithOptions(nil) = %v; want = nil", err)
+		}
+	}
+
+	opts := &VerifyOptions{ClockSkew: time.Minute}
+	if ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), expired, opts); ft != nil || !IsIDTokenExpired(err) {
+		t.Errorf("VerifyIDTokenWithOptions(expired) = (%v, %v); want = (nil, IDTokenExpired)", ft, err)
+	}
+	if ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), future, opts); ft != nil || !IsIDTokenInvalid(err) {
+		t.Errorf("VerifyIDTokenWithOptions(future) = (%v, %v); want = (nil, IDTokenInvalid)", ft, err)
+	}
+
+	// The skew is capped at 10 minutes.
+	stale := getIDToken(mockIDTokenPayload{
+		"iat": now - 10000,
+		"exp": now - 900,
+	})
+	opts = &VerifyOptions{ClockSkew: time.Hour}
+	if ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), stale, opts); ft != nil || !IsIDTokenExpired(err) {
+		t.Errorf("VerifyIDTokenWithOptions(stale) = (%v, %v); want = (nil, IDTokenExpired)", ft, err)
+	}
+
+	opts = &VerifyOptions{ClockSkew: -time.Second}
+	if ft, err := s.Client.VerifyIDTokenWithOptions(context.Background(), testIDToken, opts); ft != nil || err == nil {
+		t.Errorf("VerifyIDTokenWithOptions(negative skew) = (%v, %v); want = (nil, error)", ft, err)
+	}
+}
+
 func TestVerifyIDTokenAndCheckDisabledError(t *testing.T) {
 	s := echoServer(testGetDisabledUserResponse, t)
 	defer s.Close()
@@ -895,6 +1552,108 @@ func TestVerifyIDTokenAndCheckDisabledError(t *testing.T) {
 	}
 }
 
+func TestVerifyIDTokenAndCheckDisabled(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	// Revocation is not checked, so tokens issued before tokensValidAfterTime are accepted.
+	revokedToken := getIDToken(mockIDTokenPayload{"uid": "uid", "iat": 1970})
+	ft, err := s.Client.VerifyIDTokenAndCheckDisabled(context.Background(), revokedToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.IssuedAt != 1970 {
+		t.Errorf("IssuedAt = %d; want = %d", ft.IssuedAt, 1970)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("VerifyIDTokenAndCheckDisabled() = %d requests; want = 1", len(s.Req))
+	}
+}
+
+func TestVerifyIDTokenAndCheckDisabledUserDisabled(t *testing.T) {
+	s := echoServer(testGetDisabledUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	p, err := s.Client.VerifyIDTokenAndCheckDisabled(context.Background(), testIDToken)
+	we := "user has been disabled"
+	if p != nil || !IsUserDisabled(err) || !IsIDTokenInvalid(err) || err.Error() != we {
+		t.Errorf("VerifyIDTokenAndCheckDisabled(ctx, token) =(%v, %v); want = (%v, %v)",
+			p, err, nil, we)
+	}
+}
+
+func TestVerifyIDTokenAndCheckDisabledInvalidToken(t *testing.T) {
+	s := echoServer(testGetDisabledUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	p, err := s.Client.VerifyIDTokenAndCheckDisabled(context.Background(), "invalid-token")
+	if p != nil || !IsIDTokenInvalid(err) || IsUserDisabled(err) {
+		t.Errorf("VerifyIDTokenAndCheckDisabled() = (%v, %v); want = (nil, IDTokenInvalid)", p, err)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("VerifyIDTokenAndCheckDisabled() = %d requests; want = 0", len(s.Req))
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	authError := func(code internal.ErrorCode, authCode string) error {
+		return &internal.FirebaseError{
+			ErrorCode: code,
+			String:    "test error",
+			Ext:       map[string]interface{}{authErrorCode: authCode},
+		}
+	}
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"Nil", nil, http.StatusOK},
+		{"IDTokenExpired", authError(internal.InvalidArgument, idTokenExpired), http.StatusUnauthorized},
+		{"IDTokenInvalid", authError(internal.InvalidArgument, idTokenInvalid), http.StatusUnauthorized},
+		{"IDTokenRevoked", authError(internal.InvalidArgument, idTokenRevoked), http.StatusUnauthorized},
+		{"SessionCookieExpired", authError(internal.InvalidArgument, sessionCookieExpired), http.StatusUnauthorized},
+		{"SessionCookieInvalid", authError(internal.InvalidArgument, sessionCookieInvalid), http.StatusUnauthorized},
+		{"SessionCookieRevoked", authError(internal.InvalidArgument, sessionCookieRevoked), http.StatusUnauthorized},
+		{"TenantIDMismatch", authError(internal.InvalidArgument, tenantIDMismatch), http.StatusUnauthorized},
+		{"UserDisabled", authError(internal.InvalidArgument, userDisabled), http.StatusForbidden},
+		{"SecondFactorRequired", authError(internal.InvalidArgument, secondFactorRequired), http.StatusForbidden},
+		{"AuthTooOld", authError(internal.InvalidArgument, authTooOld), http.StatusUnauthorized},
+		{"CertificateFetchFailed", authError(internal.Unknown, certificateFetchFailed), http.StatusServiceUnavailable},
+		{"OIDCTokenInvalid", authError(internal.InvalidArgument, oidcTokenInvalid), http.StatusUnauthorized},
+		{"BlockingTokenInvalid", authError(internal.InvalidArgument, blockingTokenInvalid), http.StatusUnauthorized},
+		{"UserNotFound", authError(internal.NotFound, userNotFound), http.StatusNotFound},
+		{"EmailAlreadyExists", authError(internal.AlreadyExists, emailAlreadyExists), http.StatusConflict},
+		{"PermissionDenied", &internal.FirebaseError{ErrorCode: internal.PermissionDenied}, http.StatusForbidden},
+		{"Unavailable", &internal.FirebaseError{ErrorCode: internal.Unavailable}, http.StatusServiceUnavailable},
+		{"Internal", &internal.FirebaseError{ErrorCode: internal.Internal}, http.StatusInternalServerError},
+		{"Unknown", &internal.FirebaseError{ErrorCode: internal.Unknown}, http.StatusInternalServerError},
+		{"NotFirebaseError", errors.New("test error"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		if got := HTTPStatus(tc.err); got != tc.want {
+			t.Errorf("HTTPStatus(%s) = %d; want = %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHTTPStatusVerifyIDToken(t *testing.T) {
+	s := echoServer(testGetDisabledUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	expired := getIDToken(mockIDTokenPayload{"exp": testClock.Now().Unix() - 3600})
+	if _, err := s.Client.VerifyIDToken(context.Background(), expired); HTTPStatus(err) != http.StatusUnauthorized {
+		t.Errorf("HTTPStatus(%v) = %d; want = %d", err, HTTPStatus(err), http.StatusUnauthorized)
+	}
+	if _, err := s.Client.VerifyIDTokenAndCheckDisabled(context.Background(), testIDToken); HTTPStatus(err) != http.StatusForbidden {
+		t.Errorf("HTTPStatus(%v) = %d; want = %d", err, HTTPStatus(err), http.StatusForbidden)
+	}
+}
+
 func TestIDTokenRevocationCheckUserMgtError(t *testing.T) {
 	resp := `{
 		"kind" : "identitytoolkit#GetAccountInfoResponse",
@@ -1068,6 +1827,48 @@ func TestVerifySessionCookieError(t *testing.T) {
 	}
 }
 
+func TestVerifySessionCookieWithAudiences(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			cookieVerifier: testCookieVerifier,
+		},
+	}
+	opts := &SessionCookieVerifyOptions{
+		Audiences: []string{"web-app", "extension"},
+	}
+
+	for _, aud := range opts.Audiences {
+		cookie := getSessionCookie(mockIDTokenPayload{"aud": aud})
+		ft, err := client.VerifySessionCookieWithOptions(context.Background(), cookie, opts)
+		if err != nil {
+			t.Fatalf("VerifySessionCookieWithOptions(%q) = %v", aud, err)
+		}
+		if ft.Audience != aud {
+			t.Errorf("Audience = %q; want = %q", ft.Audience, aud)
+		}
+	}
+
+	cookie := getSessionCookie(mockIDTokenPayload{"aud": "other-app"})
+	ft, err := client.VerifySessionCookieWithOptions(context.Background(), cookie, opts)
+	want := `session cookie has invalid 'aud' (audience) claim; expected one of ["web-app" "extension"] ` +
+		`but got "other-app"`
+	if ft != nil || !IsSessionCookieInvalid(err) || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("VerifySessionCookieWithOptions() = (%v, %v); want = (nil, %q)", ft, err, want)
+	}
+
+	// The project ID is not accepted unless listed explicitly.
+	ft, err = client.VerifySessionCookieWithOptions(context.Background(), testSessionCookie, opts)
+	if ft != nil || !IsSessionCookieInvalid(err) {
+		t.Errorf("VerifySessionCookieWithOptions() = (%v, %v); want = (nil, SessionCookieInvalid)", ft, err)
+	}
+
+	// Without audiences, the project ID is required as before.
+	ft, err = client.VerifySessionCookieWithOptions(context.Background(), testSessionCookie, nil)
+	if err != nil || ft.Audience != testProjectID {
+		t.Errorf("VerifySessionCookieWithOptions(nil) = (%v, %v); want = (token, nil)", ft, err)
+	}
+}
+
 func TestVerifySessionCookieDoesNotCheckRevoked(t *testing.T) {
 	s := echoServer(testGetUserResponse, t)
 	defer s.Close()
@@ -1175,6 +1976,40 @@ func TestVerifySessionCookieUnsigned(t *testing.T) {
 	}
 }
 
+func TestVerifySessionCookieSigningKeyExpired(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{
+			idTokenVerifier: testIDTokenVerifier,
+			cookieVerifier:  testCookieVerifier,
+		},
+	}
+	cookie := getIDTokenWithSignerAndKid(testSigner, "rotated-key-id", mockIDTokenPayload{
+		"iss": "https://session.firebase.google.com/" + testProjectID,
+	})
+	ft, err := client.VerifySessionCookie(context.Background(), cookie)
+	want := `session cookie was signed with key "rotated-key-id", which is no longer published; ` +
+		"the user must sign in again"
+	if ft != nil || !IsSessionCookieSigningKeyExpired(err) || !IsSessionCookieInvalid(err) || err.Error() != want {
+		t.Errorf("VerifySessionCookie() = (%v, %v); want = (nil, %q)", ft, err, want)
+	}
+	if status := HTTPStatus(err); status != http.StatusUnauthorized {
+		t.Errorf("HTTPStatus() = %d; want = %d", status, http.StatusUnauthorized)
+	}
+
+	// ID tokens with an unknown key ID fail with the generic error.
+	token := getIDTokenWithKid("rotated-key-id", nil)
+	ft, err = client.VerifyIDToken(context.Background(), token)
+	if ft != nil || !IsIDTokenInvalid(err) || IsSessionCookieSigningKeyExpired(err) {
+		t.Errorf("VerifyIDToken() = (%v, %v); want = (nil, IDTokenInvalid)", ft, err)
+	}
+
+	// Cookies with a known key ID but an invalid signature fail with the generic error.
+	_, err = client.VerifySessionCookie(context.Background(), getEmulatedSessionCookie(nil))
+	if !IsSessionCookieInvalid(err) || IsSessionCookieSigningKeyExpired(err) {
+		t.Errorf("VerifySessionCookie(Unsigned) = %v; want = SessionCookieInvalid", err)
+	}
+}
+
 func TestEmulatorVerifySessionCookie(t *testing.T) {
 	s := echoServer(testGetUserResponse, t)
 	defer s.Close()
@@ -1511,6 +2346,58 @@ func verifyCustomToken(
 	return nil
 }
 
+func TestRunConcurrently(t *testing.T) {
+	cases := []struct {
+		name string
+		opts *BatchOptions
+		want int
+	}{
+		{"Default", nil, maxConcurrentRequests},
+		{"ZeroConcurrency", &BatchOptions{}, maxConcurrentRequests},
+		{"Concurrency", &BatchOptions{Concurrency: 2}, 2},
+	}
+	for _, tc := range cases {
+		var mu sync.Mutex
+		var inFlight, peak int
+		calls := make([]bool, 30)
+		runConcurrently(context.Background(), len(calls), tc.opts, func(idx int) {
+			mu.Lock()
+			inFlight++
+			if inFlight > peak {
+				peak = inFlight
+			}
+			calls[idx] = true
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		})
+
+		for idx, called := range calls {
+			if !called {
+				t.Errorf("%s: fn(%d) not called", tc.name, idx)
+			}
+		}
+		if peak > tc.want {
+			t.Errorf("%s: peak concurrency = %d; want <= %d", tc.name, peak, tc.want)
+		}
+	}
+}
+
+func TestRunConcurrentlyRateLimit(t *testing.T) {
+	opts := &BatchOptions{RateLimit: rate.Limit(50)}
+	start := time.Now()
+	runConcurrently(context.Background(), 6, opts, func(idx int) {})
+
+	// The first call is allowed immediately; the remaining 5 are spaced 20ms apart.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("runConcurrently() completed in %v; want >= 80ms", elapsed)
+	}
+}
+
 func logFatal(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,200 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+const (
+	blockingTokenInvalid = "BLOCKING_TOKEN_INVALID"
+
+	blockingEventTypePrefix = "providers/cloud.auth/eventTypes/user."
+)
+
+// IsBlockingTokenInvalid checks if the given error was due to an invalid or expired blocking
+// function token.
+func IsBlockingTokenInvalid(err error) bool {
+	return hasAuthErrorCode(err, blockingTokenInvalid)
+}
+
+// BlockingFunctionEvent is the authentication event that a blocking function is invoked for, as
+// decoded from the token sent by Firebase Auth.
+type BlockingFunctionEvent struct {
+	EventID string
+	// The type of the event, such as BeforeCreate or BeforeSignIn.
+	EventType    BlockingEventType
+	SignInMethod string
+	IPAddress    string
+	UserAgent    string
+	Locale       string
+	TenantID     string
+	// The user that is being created or signed in.
+	User *UserRecord
+	// The decoded token, whose Claims contain the rest of the event, such as the credentials
+	// forwarded as specified by the ForwardInboundCredentials of the project.
+	Token *Token
+}
+
+type blockingEventClaims struct {
+	EventID      string              `json:"event_id"`
+	EventType    string              `json:"event_type"`
+	SignInMethod string              `json:"sign_in_method"`
+	IPAddress    string              `json:"ip_address"`
+	UserAgent    string              `json:"user_agent"`
+	Locale       string              `json:"locale"`
+	TenantID     string              `json:"tenant_id"`
+	UserRecord   *blockingUserRecord `json:"user_record"`
+}
+
+type blockingUserRecord struct {
+	UID           string                 `json:"uid"`
+	Email         string                 `json:"email"`
+	EmailVerified bool                   `json:"email_verified"`
+	DisplayName   string                 `json:"display_name"`
+	PhotoURL      string                 `json:"photo_url"`
+	PhoneNumber   string                 `json:"phone_number"`
+	Disabled      bool                   `json:"disabled"`
+	CustomClaims  map[string]interface{} `json:"custom_claims"`
+	TenantID      string                 `json:"tenant_id"`
+	Metadata      *blockingUserMetadata  `json:"metadata"`
+	ProviderData  []*blockingUserInfo    `json:"provider_data"`
+}
+
+type blockingUserMetadata struct {
+	CreationTime   int64 `json:"creation_time"`
+	LastSignInTime int64 `json:"last_sign_in_time"`
+}
+
+type blockingUserInfo struct {
+	UID         string `json:"uid"`
+	ProviderID  string `json:"provider_id"`
+	DisplayName string `json:"display_name"`
+	PhotoURL    string `json:"photo_url"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// VerifyBlockingFunctionToken verifies the token that Firebase Auth sends to a blocking function
+// in the data.jwt field of the request body, and decodes the event from it.
+//
+// The audience must be the URL of the function that received the token. The token must be signed
+// by Firebase Auth, its issuer (iss) must match the project ID, its audience (aud) must match the
+// given audience, and it must not be expired. Otherwise an error is returned, and
+// IsBlockingTokenInvalid() returns true for it. This guards against invocations of the function
+// that were not made by Firebase Auth. For a tenant-aware client, the event must also belong to
+// the tenant.
+//
+// Like VerifyIDToken(), this function only makes an RPC call when the public keys of Firebase Auth
+// need to be refreshed.
+func (c *baseClient) VerifyBlockingFunctionToken(ctx context.Context, token, audience string) (*BlockingFunctionEvent, error) {
+	if audience == "" {
+		return nil, errors.New("audience must not be empty")
+	}
+
+	decoded, err := c.idTokenVerifier.forBlockingFunction(audience).VerifyToken(ctx, token, c.isEmulator)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims blockingEventClaims
+	if err := decoded.ClaimsAs(&claims); err != nil {
+		return nil, blockingTokenError("failed to decode blocking function token: " + err.Error())
+	}
+	if c.tenantID != "" && c.tenantID != claims.TenantID {
+		return nil, tenantMismatchError(claims.TenantID)
+	}
+
+	return &BlockingFunctionEvent{
+		EventID:      claims.EventID,
+		EventType:    BlockingEventType(strings.TrimPrefix(claims.EventType, blockingEventTypePrefix)),
+		SignInMethod: claims.SignInMethod,
+		IPAddress:    claims.IPAddress,
+		UserAgent:    claims.UserAgent,
+		Locale:       claims.Locale,
+		TenantID:     claims.TenantID,
+		User:         claims.UserRecord.toUserRecord(),
+		Token:        decoded,
+	}, nil
+}
+
+// forBlockingFunction returns a copy of the tokenVerifier that verifies blocking function tokens
+// issued for the given audience. Blocking function tokens are signed with the same keys, and have
+// the same issuer as ID tokens. The copy shares the key source of the original.
+func (tv *tokenVerifier) forBlockingFunction(audience string) *tokenVerifier {
+	cp := tv.withAudiences([]string{audience})
+	cp.shortName = "blocking function token"
+	cp.articledShortName = "a blocking function token"
+	cp.docURL = "https://firebase.google.com/docs/auth/extend-with-blocking-functions"
+	cp.invalidTokenCode = blockingTokenInvalid
+	cp.expiredTokenCode = blockingTokenInvalid
+	return cp
+}
+
+func blockingTokenError(msg string) error {
+	return &internal.FirebaseError{
+		ErrorCode: internal.InvalidArgument,
+		String:    msg,
+		Ext:       map[string]interface{}{authErrorCode: blockingTokenInvalid},
+	}
+}
+
+func (u *blockingUserRecord) toUserRecord() *UserRecord {
+	if u == nil {
+		return nil
+	}
+
+	var providers []*UserInfo
+	for _, p := range u.ProviderData {
+		if p == nil {
+			continue
+		}
+		providers = append(providers, &UserInfo{
+			DisplayName: p.DisplayName,
+			Email:       p.Email,
+			PhoneNumber: p.PhoneNumber,
+			PhotoURL:    p.PhotoURL,
+			ProviderID:  p.ProviderID,
+			UID:         p.UID,
+		})
+	}
+
+	metadata := &UserMetadata{}
+	if u.Metadata != nil {
+		metadata.CreationTimestamp = u.Metadata.CreationTime
+		metadata.LastLogInTimestamp = u.Metadata.LastSignInTime
+	}
+
+	return &UserRecord{
+		UserInfo: &UserInfo{
+			DisplayName: u.DisplayName,
+			Email:       u.Email,
+			PhoneNumber: u.PhoneNumber,
+			PhotoURL:    u.PhotoURL,
+			ProviderID:  defaultProviderID,
+			UID:         u.UID,
+		},
+		CustomClaims:     u.CustomClaims,
+		Disabled:         u.Disabled,
+		EmailVerified:    u.EmailVerified,
+		ProviderUserInfo: providers,
+		UserMetadata:     metadata,
+		TenantID:         u.TenantID,
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const testFunctionURL = "https://us-central1-mock-project-id.cloudfunctions.net/beforeCreate"
+
+func getBlockingFunctionToken(p mockIDTokenPayload) string {
+	payload := mockIDTokenPayload{
+		"aud":            testFunctionURL,
+		"event_id":       "event-id",
+		"event_type":     "providers/cloud.auth/eventTypes/user.beforeCreate",
+		"sign_in_method": "password",
+		"ip_address":     "1.2.3.4",
+		"user_agent":     "test-agent",
+		"locale":         "en",
+		"user_record": map[string]interface{}{
+			"uid":            "1234567890",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"display_name":   "Test User",
+			"custom_claims":  map[string]interface{}{"admin": true},
+			"metadata": map[string]interface{}{
+				"creation_time":     1500000000000,
+				"last_sign_in_time": 1600000000000,
+			},
+			"provider_data": []interface{}{
+				map[string]interface{}{
+					"uid":         "user@example.com",
+					"provider_id": "password",
+					"email":       "user@example.com",
+				},
+			},
+		},
+	}
+	for k, v := range p {
+		payload[k] = v
+	}
+	return getIDToken(payload)
+}
+
+func TestVerifyBlockingFunctionToken(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	event, err := s.Client.VerifyBlockingFunctionToken(
+		context.Background(), getBlockingFunctionToken(nil), testFunctionURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Token == nil || event.Token.Audience != testFunctionURL || event.Token.UID != "1234567890" {
+		t.Errorf("VerifyBlockingFunctionToken() Token = %#v", event.Token)
+	}
+
+	event.Token = nil
+	want := &BlockingFunctionEvent{
+		EventID:      "event-id",
+		EventType:    BeforeCreate,
+		SignInMethod: "password",
+		IPAddress:    "1.2.3.4",
+		UserAgent:    "test-agent",
+		Locale:       "en",
+		User: &UserRecord{
+			UserInfo: &UserInfo{
+				DisplayName: "Test User",
+				Email:       "user@example.com",
+				ProviderID:  "firebase",
+				UID:         "1234567890",
+			},
+			CustomClaims:  map[string]interface{}{"admin": true},
+			EmailVerified: true,
+			ProviderUserInfo: []*UserInfo{
+				{
+					Email:      "user@example.com",
+					ProviderID: "password",
+					UID:        "user@example.com",
+				},
+			},
+			UserMetadata: &UserMetadata{
+				CreationTimestamp:  1500000000000,
+				LastLogInTimestamp: 1600000000000,
+			},
+		},
+	}
+	if !reflect.DeepEqual(event, want) {
+		t.Errorf("VerifyBlockingFunctionToken() = %#v; want = %#v", event, want)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("VerifyBlockingFunctionToken() requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestVerifyBlockingFunctionTokenInvalid(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"EmptyToken", ""},
+		{"IDToken", testIDToken},
+		{"WrongAudience", getBlockingFunctionToken(mockIDTokenPayload{"aud": "https://example.com/other"})},
+		{"WrongIssuer", getBlockingFunctionToken(mockIDTokenPayload{"iss": "https://securetoken.google.com/other"})},
+		{"Expired", getBlockingFunctionToken(mockIDTokenPayload{"exp": testClock.Now().Unix() - 3600})},
+		{"BadSignature", getBlockingFunctionToken(nil)[:len(getBlockingFunctionToken(nil))-4] + "AAAA"},
+		{"MalformedUserRecord", getBlockingFunctionToken(mockIDTokenPayload{"user_record": "invalid"})},
+	}
+	for _, tc := range cases {
+		event, err := s.Client.VerifyBlockingFunctionToken(context.Background(), tc.token, testFunctionURL)
+		if event != nil || !IsBlockingTokenInvalid(err) {
+			t.Errorf("VerifyBlockingFunctionToken(%s) = (%v, %v); want = (nil, BlockingTokenInvalid)",
+				tc.name, event, err)
+		}
+		if IsIDTokenInvalid(err) {
+			t.Errorf("VerifyBlockingFunctionToken(%s) IsIDTokenInvalid() = true; want = false", tc.name)
+		}
+		if got := HTTPStatus(err); got != http.StatusUnauthorized {
+			t.Errorf("HTTPStatus(%s) = %d; want = %d", tc.name, got, http.StatusUnauthorized)
+		}
+	}
+
+	we := "audience must not be empty"
+	if event, err := s.Client.VerifyBlockingFunctionToken(context.Background(), getBlockingFunctionToken(nil), ""); event != nil || err == nil || err.Error() != we {
+		t.Errorf("VerifyBlockingFunctionToken() = (%v, %v); want = (nil, %q)", event, err, we)
+	}
+}
+
+func TestTenantVerifyBlockingFunctionToken(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+	s.Client.idTokenVerifier = testIDTokenVerifier
+
+	client, err := s.Client.TenantManager.AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatalf("AuthForTenant() = %v", err)
+	}
+
+	token := getBlockingFunctionToken(mockIDTokenPayload{"tenant_id": "tenantID"})
+	event, err := client.VerifyBlockingFunctionToken(context.Background(), token, testFunctionURL)
+	if err != nil || event.TenantID != "tenantID" {
+		t.Errorf("VerifyBlockingFunctionToken() = (%v, %v); want = (event, nil)", event, err)
+	}
+
+	token = getBlockingFunctionToken(mockIDTokenPayload{"tenant_id": "otherTenantID"})
+	event, err = client.VerifyBlockingFunctionToken(context.Background(), token, testFunctionURL)
+	if event != nil || !IsTenantIDMismatch(err) {
+		t.Errorf("VerifyBlockingFunctionToken() = (%v, %v); want = (nil, TenantIDMismatch)", event, err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+const blockingFunctionsConfigKey = "blockingFunctions"
+
+// BlockingEventType is the type of an authentication event that can trigger a blocking function.
+type BlockingEventType string
+
+// These constants represent the possible values for the BlockingEventType type.
+const (
+	// BeforeCreate events are triggered before a new user is saved to the Firebase Auth database.
+	BeforeCreate BlockingEventType = "beforeCreate"
+
+	// BeforeSignIn events are triggered after the credentials of a user are verified, but before
+	// Firebase Auth returns an ID token to the client.
+	BeforeSignIn BlockingEventType = "beforeSignIn"
+)
+
+// BlockingFunctionsConfig represents the blocking functions of a project, which are invoked
+// during authentication events to allow or block them, and to modify the users.
+type BlockingFunctionsConfig struct {
+	// The blocking function triggers, keyed by the type of the event that triggers them.
+	Triggers map[BlockingEventType]*BlockingFunctionTrigger `json:"triggers,omitempty"`
+	// The user credentials that are passed to the blocking functions.
+	ForwardInboundCredentials *ForwardInboundCredentials `json:"forwardInboundCredentials,omitempty"`
+}
+
+// BlockingFunctionTrigger represents a blocking function that is invoked for an event type.
+type BlockingFunctionTrigger struct {
+	// The HTTPS URI of the function.
+	FunctionURI string `json:"functionUri,omitempty"`
+}
+
+// ForwardInboundCredentials specifies which credentials of the user are passed to the blocking
+// functions.
+type ForwardInboundCredentials struct {
+	IDToken      bool `json:"idToken,omitempty"`
+	AccessToken  bool `json:"accessToken,omitempty"`
+	RefreshToken bool `json:"refreshToken,omitempty"`
+}
+
+func (bfc *BlockingFunctionsConfig) validate() error {
+	var eventTypes []string
+	for eventType := range bfc.Triggers {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	sort.Strings(eventTypes)
+
+	for _, eventType := range eventTypes {
+		if err := BlockingEventType(eventType).validate(); err != nil {
+			return err
+		}
+		trigger := bfc.Triggers[BlockingEventType(eventType)]
+		if trigger == nil {
+			return fmt.Errorf("trigger for %q must not be nil", eventType)
+		}
+		if err := validateFunctionURI(trigger.FunctionURI); err != nil {
+			return fmt.Errorf("trigger for %q: %v", eventType, err)
+		}
+	}
+	return nil
+}
+
+func (t BlockingEventType) validate() error {
+	switch t {
+	case BeforeCreate, BeforeSignIn:
+		return nil
+	default:
+		return fmt.Errorf("blocking event type must be 'beforeCreate' or 'beforeSignIn'; got %q", string(t))
+	}
+}
+
+func validateFunctionURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("function URI must be a valid HTTPS URL; got %q", uri)
+	}
+	return nil
+}
+
+// validateBlockingFunctionsConfig validates the blocking functions config in the given project
+// update request, if any.
+func validateBlockingFunctionsConfig(req map[string]interface{}) error {
+	val, ok := req[blockingFunctionsConfigKey]
+	if !ok {
+		return nil
+	}
+	blockingFunctionsConfig, ok := val.(BlockingFunctionsConfig)
+	if !ok {
+		return fmt.Errorf("invalid type for BlockingFunctionsConfig: %v", val)
+	}
+	return blockingFunctionsConfig.validate()
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBlockingFunctionsConfig(t *testing.T) {
+	cases := []BlockingFunctionsConfig{
+		{},
+		{ForwardInboundCredentials: &ForwardInboundCredentials{IDToken: true}},
+		{
+			Triggers: map[BlockingEventType]*BlockingFunctionTrigger{
+				BeforeCreate: {FunctionURI: "https://us-central1-project.cloudfunctions.net/beforeCreate"},
+				BeforeSignIn: {FunctionURI: "https://example.com:8443/auth/beforeSignIn?x=1"},
+			},
+		},
+	}
+	for i, bfc := range cases {
+		if err := bfc.validate(); err != nil {
+			t.Errorf("[%d] BlockingFunctionsConfig.validate() = %v; want = nil", i, err)
+		}
+	}
+}
+
+func TestBlockingFunctionsConfigInvalid(t *testing.T) {
+	cases := []struct {
+		triggers map[BlockingEventType]*BlockingFunctionTrigger
+		want     string
+	}{
+		{
+			map[BlockingEventType]*BlockingFunctionTrigger{
+				"beforeDelete": {FunctionURI: "https://example.com"},
+			},
+			`blocking event type must be 'beforeCreate' or 'beforeSignIn'; got "beforeDelete"`,
+		},
+		{
+			map[BlockingEventType]*BlockingFunctionTrigger{BeforeCreate: nil},
+			`trigger for "beforeCreate" must not be nil`,
+		},
+		{
+			map[BlockingEventType]*BlockingFunctionTrigger{BeforeCreate: {}},
+			`trigger for "beforeCreate": function URI must be a valid HTTPS URL; got ""`,
+		},
+		{
+			map[BlockingEventType]*BlockingFunctionTrigger{
+				BeforeSignIn: {FunctionURI: "http://example.com/beforeSignIn"},
+			},
+			`trigger for "beforeSignIn": function URI must be a valid HTTPS URL; got "http://example.com/beforeSignIn"`,
+		},
+		{
+			map[BlockingEventType]*BlockingFunctionTrigger{
+				BeforeSignIn: {FunctionURI: "https:///beforeSignIn"},
+			},
+			`trigger for "beforeSignIn": function URI must be a valid HTTPS URL; got "https:///beforeSignIn"`,
+		},
+		{
+			map[BlockingEventType]*BlockingFunctionTrigger{
+				BeforeSignIn: {FunctionURI: "https://example.com/%zz"},
+			},
+			`trigger for "beforeSignIn": function URI must be a valid HTTPS URL; got "https://example.com/%zz"`,
+		},
+	}
+	for _, tc := range cases {
+		config := BlockingFunctionsConfig{Triggers: tc.triggers}
+		if err := config.validate(); err == nil || err.Error() != tc.want {
+			t.Errorf("BlockingFunctionsConfig.validate() = %v; want = %q", err, tc.want)
+		}
+	}
+}
+
+func TestGetProjectConfigWithBlockingFunctions(t *testing.T) {
+	resp := `{
+		"blockingFunctions": {
+			"triggers": {
+				"beforeCreate": {
+					"functionUri": "https://example.com/beforeCreate",
+					"updateTime": "2023-01-01T00:00:00Z"
+				}
+			},
+			"forwardInboundCredentials": {
+				"idToken": true,
+				"refreshToken": true
+			}
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	projectConfig, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ProjectConfig{
+		BlockingFunctionsConfig: &BlockingFunctionsConfig{
+			Triggers: map[BlockingEventType]*BlockingFunctionTrigger{
+				BeforeCreate: {FunctionURI: "https://example.com/beforeCreate"},
+			},
+			ForwardInboundCredentials: &ForwardInboundCredentials{
+				IDToken:      true,
+				RefreshToken: true,
+			},
+		},
+	}
+	if !reflect.DeepEqual(projectConfig, want) {
+		t.Errorf("GetProjectConfig() = %#v, want = %#v", projectConfig, want)
+	}
+}
+
+func TestUpdateProjectConfigBlockingFunctions(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	options := (&ProjectConfigToUpdate{}).BlockingFunctionsConfig(BlockingFunctionsConfig{
+		Triggers: map[BlockingEventType]*BlockingFunctionTrigger{
+			BeforeCreate: {FunctionURI: "https://example.com/beforeCreate"},
+			BeforeSignIn: {FunctionURI: "https://example.com/beforeSignIn"},
+		},
+		ForwardInboundCredentials: &ForwardInboundCredentials{AccessToken: true},
+	})
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody := map[string]interface{}{
+		"blockingFunctions": map[string]interface{}{
+			"triggers": map[string]interface{}{
+				"beforeCreate": map[string]interface{}{
+					"functionUri": "https://example.com/beforeCreate",
+				},
+				"beforeSignIn": map[string]interface{}{
+					"functionUri": "https://example.com/beforeSignIn",
+				},
+			},
+			"forwardInboundCredentials": map[string]interface{}{
+				"accessToken": true,
+			},
+		},
+	}
+	if err := checkUpdateProjectConfigRequest(s, wantBody, []string{"blockingFunctions"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateProjectConfigBlockingFunctionsInvalid(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	options := (&ProjectConfigToUpdate{}).BlockingFunctionsConfig(BlockingFunctionsConfig{
+		Triggers: map[BlockingEventType]*BlockingFunctionTrigger{
+			BeforeCreate: {FunctionURI: "example.com/beforeCreate"},
+		},
+	})
+	want := `trigger for "beforeCreate": function URI must be a valid HTTPS URL; got "example.com/beforeCreate"`
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), options); err == nil || err.Error() != want {
+		t.Errorf("UpdateProjectConfig() = %v; want = %q", err, want)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("requests = %d; want = 0", len(s.Req))
+	}
+}
@@ -24,6 +24,9 @@ import (
 
 // ActionCodeSettings specifies the required continue/state URL with optional Android and iOS settings. Used when
 // invoking the email action link generation APIs.
+//
+// AndroidMinimumVersion and AndroidInstallApp are only honored by the backend when AndroidPackageName is also
+// specified.
 type ActionCodeSettings struct {
 	URL                   string `json:"continueUrl"`
 	HandleCodeInApp       bool   `json:"canHandleCodeInApp"`
@@ -279,6 +279,33 @@ func TestEmailSignInLinkInvalidSettings(t *testing.T) {
 	}
 }
 
+func TestEmailVerificationLinkPartialSettings(t *testing.T) {
+	s := echoServer(testActionLinkResponse, t)
+	defer s.Close()
+
+	settings := &ActionCodeSettings{
+		URL:                "https://example.dynamic.link",
+		IOSBundleID:        "com.example.ios",
+		AndroidPackageName: "com.example.android",
+	}
+	if _, err := s.Client.EmailVerificationLinkWithSettings(context.Background(), testEmail, settings); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"requestType":        "VERIFY_EMAIL",
+		"email":              testEmail,
+		"returnOobLink":      true,
+		"continueUrl":        "https://example.dynamic.link",
+		"canHandleCodeInApp": false,
+		"iOSBundleId":        "com.example.ios",
+		"androidPackageName": "com.example.android",
+	}
+	if err := checkActionLinkRequest(want, s); err != nil {
+		t.Fatalf("EmailVerificationLinkWithSettings() %v", err)
+	}
+}
+
 func TestEmailSignInLinkNoSettings(t *testing.T) {
 	client := &Client{
 		baseClient: &baseClient{},
@@ -0,0 +1,145 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+// EmailTemplateType identifies one of the emails sent by Firebase Auth on behalf of the project.
+type EmailTemplateType string
+
+const (
+	// VerifyEmailTemplate is the template of the email sent to verify an email address.
+	VerifyEmailTemplate EmailTemplateType = "VERIFY_EMAIL"
+
+	// PasswordResetTemplate is the template of the email sent to reset a password.
+	PasswordResetTemplate EmailTemplateType = "PASSWORD_RESET"
+
+	// ChangeEmailTemplate is the template of the email sent to the previous email address of a
+	// user, when the email address is changed.
+	ChangeEmailTemplate EmailTemplateType = "CHANGE_EMAIL"
+
+	// RevertSecondFactorAdditionTemplate is the template of the email sent when a second factor is
+	// enrolled, so that the enrollment can be reverted.
+	RevertSecondFactorAdditionTemplate EmailTemplateType = "REVERT_SECOND_FACTOR_ADDITION"
+)
+
+var emailTemplateKeys = map[EmailTemplateType]string{
+	VerifyEmailTemplate:                "verifyEmailTemplate",
+	PasswordResetTemplate:              "resetPasswordTemplate",
+	ChangeEmailTemplate:                "changeEmailTemplate",
+	RevertSecondFactorAdditionTemplate: "revertSecondFactorAdditionTemplate",
+}
+
+// EmailBodyFormat is the format of the body of an email template.
+type EmailBodyFormat string
+
+const (
+	// PlainTextBody indicates that the body of an email template is plain text.
+	PlainTextBody EmailBodyFormat = "PLAIN_TEXT"
+
+	// HTMLBody indicates that the body of an email template is HTML.
+	HTMLBody EmailBodyFormat = "HTML"
+)
+
+// EmailTemplate is the configuration of an email sent by Firebase Auth.
+//
+// The subject and body may contain the placeholders supported by the Firebase console, such as
+// %LINK% and %APP_NAME%. Customized is set by the backend, and is ignored in updates.
+type EmailTemplate struct {
+	SenderLocalPart   string          `json:"senderLocalPart,omitempty"`
+	SenderDisplayName string          `json:"senderDisplayName,omitempty"`
+	Subject           string          `json:"subject,omitempty"`
+	Body              string          `json:"body,omitempty"`
+	BodyFormat        EmailBodyFormat `json:"bodyFormat,omitempty"`
+	ReplyTo           string          `json:"replyTo,omitempty"`
+	Customized        bool            `json:"customized,omitempty"`
+}
+
+type emailNotificationConfig struct {
+	Notification struct {
+		SendEmail map[string]*EmailTemplate `json:"sendEmail"`
+	} `json:"notification"`
+}
+
+// GetEmailTemplate returns the template of the given type from the configuration of the project.
+func (c *Client) GetEmailTemplate(ctx context.Context, templateType EmailTemplateType) (*EmailTemplate, error) {
+	key, ok := emailTemplateKeys[templateType]
+	if !ok {
+		return nil, fmt.Errorf("invalid email template type: %q", templateType)
+	}
+
+	req := &internal.Request{
+		Method: http.MethodGet,
+		URL:    "/config",
+	}
+	var result emailNotificationConfig
+	if _, err := c.baseClient.makeRequest(ctx, req, &result); err != nil {
+		return nil, err
+	}
+
+	template := result.Notification.SendEmail[key]
+	if template == nil {
+		template = &EmailTemplate{}
+	}
+	return template, nil
+}
+
+// UpdateEmailTemplate replaces the template of the given type in the configuration of the
+// project, and returns the updated template.
+//
+// All the fields of the template are replaced, so fields that are not specified are cleared.
+func (c *Client) UpdateEmailTemplate(
+	ctx context.Context, templateType EmailTemplateType, template *EmailTemplate) (*EmailTemplate, error) {
+	key, ok := emailTemplateKeys[templateType]
+	if !ok {
+		return nil, fmt.Errorf("invalid email template type: %q", templateType)
+	}
+	if template == nil {
+		return nil, errors.New("email template must not be nil")
+	}
+	if template.BodyFormat != "" && template.BodyFormat != PlainTextBody && template.BodyFormat != HTMLBody {
+		return nil, fmt.Errorf("invalid email body format: %q", template.BodyFormat)
+	}
+
+	body := *template
+	body.Customized = false
+	var config emailNotificationConfig
+	config.Notification.SendEmail = map[string]*EmailTemplate{key: &body}
+	req := &internal.Request{
+		Method: http.MethodPatch,
+		URL:    "/config",
+		Body:   internal.NewJSONEntity(&config),
+		Opts: []internal.HTTPOption{
+			internal.WithQueryParam("updateMask", "notification.sendEmail."+key),
+		},
+	}
+	var result emailNotificationConfig
+	if _, err := c.baseClient.makeRequest(ctx, req, &result); err != nil {
+		return nil, err
+	}
+
+	updated := result.Notification.SendEmail[key]
+	if updated == nil {
+		updated = &EmailTemplate{}
+	}
+	return updated, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const emailTemplatesResponse = `{
+	"notification": {
+		"sendEmail": {
+			"verifyEmailTemplate": {
+				"senderLocalPart": "noreply",
+				"senderDisplayName": "Example",
+				"subject": "Verify your email for %APP_NAME%",
+				"body": "<p>Follow <a href='%LINK%'>this link</a> to verify your email address.</p>",
+				"bodyFormat": "HTML",
+				"replyTo": "support@example.com",
+				"customized": true
+			}
+		}
+	}
+}`
+
+var testEmailTemplate = &EmailTemplate{
+	SenderLocalPart:   "noreply",
+	SenderDisplayName: "Example",
+	Subject:           "Verify your email for %APP_NAME%",
+	Body:              "<p>Follow <a href='%LINK%'>this link</a> to verify your email address.</p>",
+	BodyFormat:        HTMLBody,
+	ReplyTo:           "support@example.com",
+	Customized:        true,
+}
+
+func TestGetEmailTemplate(t *testing.T) {
+	s := echoServer([]byte(emailTemplatesResponse), t)
+	defer s.Close()
+
+	template, err := s.Client.GetEmailTemplate(context.Background(), VerifyEmailTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(template, testEmailTemplate) {
+		t.Errorf("GetEmailTemplate() = %#v; want = %#v", template, testEmailTemplate)
+	}
+	req := s.Req[0]
+	if req.Method != http.MethodGet || req.URL.Path != "/projects/mock-project-id/config" {
+		t.Errorf("GetEmailTemplate() = %s %s; want = GET /projects/mock-project-id/config", req.Method, req.URL.Path)
+	}
+}
+
+func TestGetEmailTemplateNotConfigured(t *testing.T) {
+	s := echoServer([]byte(emailTemplatesResponse), t)
+	defer s.Close()
+
+	template, err := s.Client.GetEmailTemplate(context.Background(), PasswordResetTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(template, &EmailTemplate{}) {
+		t.Errorf("GetEmailTemplate() = %#v; want = empty template", template)
+	}
+}
+
+func TestUpdateEmailTemplate(t *testing.T) {
+	s := echoServer([]byte(emailTemplatesResponse), t)
+	defer s.Close()
+
+	template, err := s.Client.UpdateEmailTemplate(context.Background(), VerifyEmailTemplate, testEmailTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(template, testEmailTemplate) {
+		t.Errorf("UpdateEmailTemplate() = %#v; want = %#v", template, testEmailTemplate)
+	}
+	wantBody := map[string]interface{}{
+		"notification": map[string]interface{}{
+			"sendEmail": map[string]interface{}{
+				"verifyEmailTemplate": map[string]interface{}{
+					"senderLocalPart":   "noreply",
+					"senderDisplayName": "Example",
+					"subject":           "Verify your email for %APP_NAME%",
+					"body":              "<p>Follow <a href='%LINK%'>this link</a> to verify your email address.</p>",
+					"bodyFormat":        "HTML",
+					"replyTo":           "support@example.com",
+				},
+			},
+		},
+	}
+	wantMask := []string{"notification.sendEmail.verifyEmailTemplate"}
+	if err := checkUpdateProjectConfigRequest(s, wantBody, wantMask); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateEmailTemplateError(t *testing.T) {
+	cases := []struct {
+		name         string
+		templateType EmailTemplateType
+		template     *EmailTemplate
+		want         string
+	}{
+		{
+			name:         "InvalidType",
+			templateType: "UNKNOWN",
+			template:     testEmailTemplate,
+			want:         `invalid email template type: "UNKNOWN"`,
+		},
+		{
+			name:         "NilTemplate",
+			templateType: PasswordResetTemplate,
+			want:         "email template must not be nil",
+		},
+		{
+			name:         "InvalidBodyFormat",
+			templateType: PasswordResetTemplate,
+			template:     &EmailTemplate{BodyFormat: "MARKDOWN"},
+			want:         `invalid email body format: "MARKDOWN"`,
+		},
+	}
+
+	client := &Client{baseClient: &baseClient{}}
+	for _, tc := range cases {
+		template, err := client.UpdateEmailTemplate(context.Background(), tc.templateType, tc.template)
+		if template != nil || err == nil || err.Error() != tc.want {
+			t.Errorf("UpdateEmailTemplate(%s) = (%v, %v); want = (nil, %q)", tc.name, template, err, tc.want)
+		}
+	}
+}
+
+func TestGetEmailTemplateInvalidType(t *testing.T) {
+	client := &Client{baseClient: &baseClient{}}
+	want := `invalid email template type: "UNKNOWN"`
+	if template, err := client.GetEmailTemplate(context.Background(), "UNKNOWN"); template != nil || err == nil || err.Error() != want {
+		t.Errorf("GetEmailTemplate() = (%v, %v); want = (nil, %q)", template, err, want)
+	}
+}
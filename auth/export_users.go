@@ -16,10 +16,13 @@ package auth
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"firebase.google.com/go/v4/internal"
 	"google.golang.org/api/iterator"
@@ -45,6 +48,74 @@ func (c *baseClient) Users(ctx context.Context, nextPageToken string) *UserItera
 	return it
 }
 
+// UsersChan returns a channel that receives all the users in the project, and a channel that
+// receives the error that stopped the listing, if any.
+//
+// The users are fetched one page at a time in a background goroutine, which fetches the next page
+// only after the users of the previous page have been received. Both channels are closed once all
+// the users have been sent, or once an error has been sent. Canceling ctx stops the listing, in
+// which case the context error is sent on the error channel. Callers must either receive from the
+// user channel until it is closed, or cancel ctx, so that the goroutine can exit.
+func (c *baseClient) UsersChan(ctx context.Context) (<-chan *ExportedUserRecord, <-chan error) {
+	users := make(chan *ExportedUserRecord)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(users)
+		defer close(errs)
+
+		it := c.Users(ctx, "")
+		for {
+			user, err := it.Next()
+			if err == iterator.Done {
+				return
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				errs <- err
+				return
+			}
+
+			select {
+			case users <- user:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return users, errs
+}
+
+// UsersModifiedSince returns an iterator over the users that show activity after the given time.
+//
+// A user is included if it was created, last signed in, last refreshed an ID token, or had its
+// tokens revoked (e.g. by a password change) after since. Other changes to a user account, such
+// as profile updates, are not recorded by the backend and are therefore not detected.
+//
+// The backend does not support filtering users, so this iterates over all users in the project,
+// and filters them on the client side. The cost of a call is the same as that of Users().
+func (c *baseClient) UsersModifiedSince(ctx context.Context, since time.Time) *UserIterator {
+	it := c.Users(ctx, "")
+	cutoff := since.UnixNano() / int64(time.Millisecond)
+	it.filter = func(u *ExportedUserRecord) bool {
+		return u.modifiedAfter(cutoff)
+	}
+	return it
+}
+
+func (u *ExportedUserRecord) modifiedAfter(millis int64) bool {
+	if u.TokensValidAfterMillis > millis {
+		return true
+	}
+	if m := u.UserMetadata; m != nil {
+		return m.CreationTimestamp > millis || m.LastLogInTimestamp > millis ||
+			m.LastRefreshTimestamp > millis
+	}
+	return false
+}
+
 // UserIterator is an iterator over Users.
 //
 // Also see: https://github.com/GoogleCloudPlatform/google-cloud-go/wiki/Iterator-Guidelines
@@ -54,6 +125,7 @@ type UserIterator struct {
 	nextFunc func() error
 	pageInfo *iterator.PageInfo
 	users    []*ExportedUserRecord
+	filter   func(*ExportedUserRecord) bool
 }
 
 // PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
@@ -102,6 +174,9 @@ func (it *UserIterator) fetch(pageSize int, pageToken string) (string, error) {
 		if err != nil {
 			return "", err
 		}
+		if it.filter != nil && !it.filter(eu) {
+			continue
+		}
 		it.users = append(it.users, eu)
 	}
 	it.pageInfo.Token = parsed.NextPageToken
@@ -109,8 +184,45 @@ func (it *UserIterator) fetch(pageSize int, pageToken string) (string, error) {
 }
 
 // ExportedUserRecord is the returned user value used when listing all the users.
+//
+// PasswordHash and PasswordSalt hold the password hash and salt of the user as returned by the
+// backend, in base64url encoding. They are empty if the user does not have a password, or if the
+// credentials used to list the users are not allowed to read password hashes.
 type ExportedUserRecord struct {
 	*UserRecord
 	PasswordHash string
 	PasswordSalt string
 }
+
+// ToUserToImport returns a UserToImport that recreates the exported user account with
+// ImportUsers(), including its password hash and salt.
+//
+// The password hash and salt are decoded from their base64url encoding, so that they are imported
+// unchanged. The hash algorithm is not exported along with the hash, and therefore a
+// UserImportHash matching the password hash configuration of the source project must be passed
+// to ImportUsers() via WithHash() when importing users with passwords. Password and phone
+// identities are omitted from the provider data, since they are implied by the password hash and
+// the phone number of the account.
+func (u *ExportedUserRecord) ToUserToImport() (*UserToImport, error) {
+	imported := userToImportFromRecord(u.UserRecord)
+	if u.PasswordHash != "" {
+		hash, err := decodeBase64URL(u.PasswordHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid password hash: %v", err)
+		}
+		imported.PasswordHash(hash)
+	}
+	if u.PasswordSalt != "" {
+		salt, err := decodeBase64URL(u.PasswordSalt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid password salt: %v", err)
+		}
+		imported.PasswordSalt(salt)
+	}
+	return imported, nil
+}
+
+// decodeBase64URL decodes a base64url-encoded string, with or without padding.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"time"
 
 	"firebase.google.com/go/v4/internal"
 )
@@ -111,6 +112,104 @@ func (c *baseClient) ImportUsers(
 	return result, nil
 }
 
+// UserImportBatchResult represents the result of importing a single batch of users read from a
+// channel by ImportUsersFromChannel().
+type UserImportBatchResult struct {
+	// StartIndex is the position of the first user of this batch in the input channel.
+	StartIndex int
+
+	// UserCount is the number of users in this batch.
+	UserCount    int
+	SuccessCount int
+	FailureCount int
+
+	// Errors describes the users that failed to be imported. The Index field of each ErrorInfo
+	// corresponds to the position of the failed user in the input channel.
+	Errors []*ErrorInfo
+
+	// Err is set when the batch as a whole could not be imported (e.g. due to an invalid user or
+	// a failed RPC call). In that case all the users of the batch are counted as failures.
+	Err error
+}
+
+// ImportUsersFromChannel imports the users read from the given channel to Firebase Auth.
+//
+// Users are grouped into batches of up to 1000, and each batch is imported with ImportUsers()
+// once it is full or the input channel is closed. Batches are imported one at a time, in order.
+// Transient failures are retried by the underlying HTTP client. This makes it possible to
+// migrate an arbitrarily large number of users without holding all of them in memory.
+//
+// The result of each batch is sent on the returned channel, which is closed once the input
+// channel has been closed and all the users read from it have been imported, or when ctx is
+// done. Callers must drain the returned channel.
+func (c *baseClient) ImportUsersFromChannel(
+	ctx context.Context, users <-chan *UserToImport, opts ...UserImportOption) <-chan *UserImportBatchResult {
+
+	results := make(chan *UserImportBatchResult)
+	go func() {
+		defer close(results)
+
+		start := 0
+		batch := make([]*UserToImport, 0, maxImportUsers)
+		flush := func() bool {
+			if len(batch) == 0 {
+				return true
+			}
+			r := c.importBatch(ctx, batch, start, opts...)
+			start += len(batch)
+			batch = make([]*UserToImport, 0, maxImportUsers)
+			select {
+			case results <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case u, ok := <-users:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, u)
+				if len(batch) == maxImportUsers && !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
+
+func (c *baseClient) importBatch(
+	ctx context.Context, batch []*UserToImport, start int, opts ...UserImportOption) *UserImportBatchResult {
+
+	result := &UserImportBatchResult{
+		StartIndex: start,
+		UserCount:  len(batch),
+	}
+	r, err := c.ImportUsers(ctx, batch, opts...)
+	if err != nil {
+		result.FailureCount = len(batch)
+		result.Err = err
+		return result
+	}
+
+	result.SuccessCount = r.SuccessCount
+	result.FailureCount = r.FailureCount
+	for _, e := range r.Errors {
+		result.Errors = append(result.Errors, &ErrorInfo{
+			Index:  start + e.Index,
+			Reason: e.Reason,
+		})
+	}
+	return result
+}
+
 // UserToImport represents a user account that can be bulk imported into Firebase Auth.
 type UserToImport struct {
 	params map[string]interface{}
@@ -205,6 +304,15 @@ func (u *UserToImport) ProviderData(providers []*UserProvider) *UserToImport {
 	return u.set("providerUserInfo", providers)
 }
 
+// MFASettings setter.
+//
+// Phone and TOTP second factors are imported along with their UIDs and enrollment timestamps.
+// As in UserRecord, enrollment timestamps are in milliseconds since epoch, so the second factors
+// of an exported user can be imported unchanged.
+func (u *UserToImport) MFASettings(mfaSettings MultiFactorSettings) *UserToImport {
+	return u.set("mfaSettings", mfaSettings)
+}
+
 func (u *UserToImport) validatedUserInfo() (map[string]interface{}, error) {
 	if len(u.params) == 0 {
 		return nil, fmt.Errorf("no parameters are set on the user to import")
@@ -248,9 +356,54 @@ func (u *UserToImport) validatedUserInfo() (map[string]interface{}, error) {
 			}
 		}
 	}
+
+	if mfa, ok := info["mfaSettings"]; ok {
+		mfaInfo, err := formatImportedMultiFactors(mfa.(MultiFactorSettings))
+		if err != nil {
+			return nil, err
+		}
+		info["mfaInfo"] = mfaInfo
+		delete(info, "mfaSettings")
+	}
 	return info, nil
 }
 
+func formatImportedMultiFactors(mfa MultiFactorSettings) ([]*multiFactorInfoResponse, error) {
+	var mfaInfo []*multiFactorInfoResponse
+	for _, factor := range mfa.EnrolledFactors {
+		if factor == nil {
+			return nil, fmt.Errorf("enrolled second factor must not be nil")
+		}
+
+		info := &multiFactorInfoResponse{
+			MFAEnrollmentID: factor.UID,
+			DisplayName:     factor.DisplayName,
+		}
+		if factor.EnrollmentTimestamp != 0 {
+			enrolledAt := time.Unix(0, factor.EnrollmentTimestamp*int64(time.Millisecond))
+			info.EnrolledAt = enrolledAt.UTC().Format(time.RFC3339)
+		}
+
+		switch factor.FactorID {
+		case phoneMultiFactorID:
+			phone := factor.PhoneNumber
+			if factor.Phone != nil {
+				phone = factor.Phone.PhoneNumber
+			}
+			if err := validatePhone(phone); err != nil {
+				return nil, fmt.Errorf("the second factor \"phoneNumber\" for %q must be a non-empty E.164 standard compliant identifier string", phone)
+			}
+			info.PhoneInfo = phone
+		case totpMultiFactorID:
+			info.TOTPInfo = &TOTPInfo{}
+		default:
+			return nil, fmt.Errorf("unsupported second factor id: %q", factor.FactorID)
+		}
+		mfaInfo = append(mfaInfo, info)
+	}
+	return mfaInfo, nil
+}
+
 // WithHash returns a UserImportOption that specifies a hash configuration.
 func WithHash(hash UserImportHash) UserImportOption {
 	return withHash{hash}
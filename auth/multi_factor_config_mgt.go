@@ -45,15 +45,34 @@ const (
 // MultiFactorConfig represents a multi-factor configuration for a tenant or project.
 // This can be used to define whether multi-factor authentication is enabled or disabled and the list of second factor challenges that are supported.
 type MultiFactorConfig struct {
+	// The state of multi-factor authentication as a whole, whether it's enabled or disabled.
+	State MultiFactorConfigState `json:"state,omitempty"`
+	// The second factors other than TOTP that users can enroll. Currently, only PhoneSMSProvider is supported.
+	EnabledProviders []string `json:"enabledProviders,omitempty"`
 	// A slice of pointers to ProviderConfig structs, each outlining the specific second factor authorization method.
 	ProviderConfigs []*ProviderConfig `json:"providerConfigs,omitempty"`
 }
 
+// PhoneSMSProvider is the MultiFactorConfig.EnabledProviders value that enables SMS as a second factor.
+const PhoneSMSProvider = "PHONE_SMS"
+
+func (mfa *MultiFactorConfig) isEmpty() bool {
+	return mfa == nil || (mfa.State == "" && len(mfa.EnabledProviders) == 0 && len(mfa.ProviderConfigs) == 0)
+}
+
 func (mfa *MultiFactorConfig) validate() error {
 	if mfa == nil {
 		return nil
 	}
-	if len(mfa.ProviderConfigs) == 0 {
+	if mfa.State != "" && mfa.State != Enabled && mfa.State != Disabled {
+		return fmt.Errorf("\"MultiFactorConfig.State\" must be 'Enabled' or 'Disabled'")
+	}
+	for _, provider := range mfa.EnabledProviders {
+		if provider != PhoneSMSProvider {
+			return fmt.Errorf("\"EnabledProviders\" contains unsupported provider: %q", provider)
+		}
+	}
+	if mfa.isEmpty() {
 		return fmt.Errorf("\"ProviderConfigs\" must be a non-empty array of type \"ProviderConfig\"s")
 	}
 	for _, providerConfig := range mfa.ProviderConfigs {
@@ -31,6 +31,48 @@ func TestMultiFactorConfig(t *testing.T) {
 		t.Errorf("MultiFactorConfig not valid")
 	}
 }
+
+func TestMultiFactorConfigStateAndEnabledProviders(t *testing.T) {
+	cases := []MultiFactorConfig{
+		{State: Enabled},
+		{EnabledProviders: []string{PhoneSMSProvider}},
+		{
+			State:            Enabled,
+			EnabledProviders: []string{PhoneSMSProvider},
+			ProviderConfigs: []*ProviderConfig{{
+				State:              Enabled,
+				TOTPProviderConfig: &TOTPProviderConfig{AdjacentIntervals: 5},
+			}},
+		},
+	}
+	for i, mfa := range cases {
+		if err := mfa.validate(); err != nil {
+			t.Errorf("[%d] MultiFactorConfig.validate() = %v; want = nil", i, err)
+		}
+	}
+}
+
+func TestMultiFactorConfigInvalidState(t *testing.T) {
+	mfa := MultiFactorConfig{
+		State: "invalid",
+	}
+	want := "\"MultiFactorConfig.State\" must be 'Enabled' or 'Disabled'"
+	if err := mfa.validate(); err == nil || err.Error() != want {
+		t.Errorf("MultiFactorConfig.validate() = %v, want = %q", err, want)
+	}
+}
+
+func TestMultiFactorConfigUnsupportedEnabledProvider(t *testing.T) {
+	mfa := MultiFactorConfig{
+		State:            Enabled,
+		EnabledProviders: []string{"EMAIL"},
+	}
+	want := "\"EnabledProviders\" contains unsupported provider: \"EMAIL\""
+	if err := mfa.validate(); err == nil || err.Error() != want {
+		t.Errorf("MultiFactorConfig.validate() = %v, want = %q", err, want)
+	}
+}
+
 func TestMultiFactorConfigNoProviderConfigs(t *testing.T) {
 	mfa := MultiFactorConfig{}
 	want := "\"ProviderConfigs\" must be a non-empty array of type \"ProviderConfig\"s"
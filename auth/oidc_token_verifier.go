@@ -0,0 +1,279 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+const (
+	oidcTokenInvalid = "OIDC_TOKEN_INVALID"
+
+	// Cache duration of the OIDC provider keys, used when the JWKS response does not specify one.
+	defaultJWKSMaxAge = time.Hour
+)
+
+// IsOIDCTokenInvalid checks if the given error was due to an invalid or expired OIDC provider
+// token.
+func IsOIDCTokenInvalid(err error) bool {
+	return hasAuthErrorCode(err, oidcTokenInvalid)
+}
+
+// VerifyOIDCProviderToken verifies a token issued by the OIDC provider with the given provider ID
+// (e.g. "oidc.provider"), such as an ID token obtained by a client from Apple or Google.
+//
+// The token is verified against the OIDC provider config registered in Firebase. The token must be
+// an RS256 JWT that is signed by one of the keys published by the issuer of the config, as
+// discovered from the OpenID configuration of the issuer. Its issuer (iss) must match the issuer
+// of the config, its audience (aud) must include the client ID of the config, and it must not be
+// expired. The provider config must be enabled.
+//
+// This function makes an RPC call to fetch the provider config. The OpenID configuration of each
+// issuer is cached, and its public keys are cached as specified by the cache-control header of the
+// JWKS response, or for an hour if the header does not specify a max-age.
+//
+// The returned Token contains the claims of the provider token. Its Firebase field is not set, and
+// its UID is the subject (sub) of the token, which identifies the user at the provider rather than
+// in Firebase.
+func (c *baseClient) VerifyOIDCProviderToken(ctx context.Context, providerID, token string) (*Token, error) {
+	if c.oidcKeys == nil {
+		return nil, errors.New("OIDC provider token verification is not available")
+	}
+
+	config, err := c.OIDCProviderConfig(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, fmt.Errorf("OIDC provider config %q is disabled", providerID)
+	}
+
+	payload, err := verifyOIDCTokenContent(token, config, c.clock.Now())
+	if err != nil {
+		return nil, oidcTokenError(err.Error())
+	}
+
+	keys, err := c.oidcKeys.keySource(config.Issuer).Keys(ctx)
+	if err != nil {
+		return nil, &internal.FirebaseError{
+			ErrorCode: internal.Unknown,
+			String:    err.Error(),
+			Ext:       map[string]interface{}{authErrorCode: certificateFetchFailed},
+		}
+	}
+
+	if !verifySignatureWithKeys(token, keys) {
+		return nil, oidcTokenError("failed to verify token signature")
+	}
+
+	return payload, nil
+}
+
+func oidcTokenError(msg string) error {
+	return &internal.FirebaseError{
+		ErrorCode: internal.InvalidArgument,
+		String:    msg,
+		Ext:       map[string]interface{}{authErrorCode: oidcTokenInvalid},
+	}
+}
+
+// verifyOIDCTokenContent checks the header and the claims of an OIDC provider token, and decodes
+// it into a Token. Unlike Firebase tokens, the audience of provider tokens may be an array.
+func verifyOIDCTokenContent(token string, config *OIDCProviderConfig, now time.Time) (*Token, error) {
+	if token == "" {
+		return nil, errors.New("OIDC provider token must be a non-empty string")
+	}
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, errors.New("incorrect number of segments")
+	}
+
+	var header jwtHeader
+	if err := decode(segments[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("OIDC provider token has invalid algorithm; expected 'RS256' but got %q",
+			header.Algorithm)
+	}
+
+	var claims map[string]interface{}
+	if err := decode(segments[1], &claims); err != nil {
+		return nil, err
+	}
+	var payload struct {
+		Issuer   string      `json:"iss"`
+		Audience interface{} `json:"aud"`
+		Subject  string      `json:"sub"`
+		IssuedAt int64       `json:"iat"`
+		Expires  int64       `json:"exp"`
+		AuthTime int64       `json:"auth_time"`
+	}
+	if err := decode(segments[1], &payload); err != nil {
+		return nil, err
+	}
+
+	if payload.Issuer != config.Issuer {
+		return nil, fmt.Errorf("OIDC provider token has invalid 'iss' (issuer) claim; expected %q but got %q",
+			config.Issuer, payload.Issuer)
+	}
+	if !hasAudience(payload.Audience, config.ClientID) {
+		return nil, fmt.Errorf("OIDC provider token has invalid 'aud' (audience) claim; expected %q but got %v",
+			config.ClientID, payload.Audience)
+	}
+	if payload.Subject == "" {
+		return nil, errors.New("OIDC provider token has empty 'sub' (subject) claim")
+	}
+	if (payload.IssuedAt - clockSkewSeconds) > now.Unix() {
+		return nil, fmt.Errorf("OIDC provider token issued at future timestamp: %d", payload.IssuedAt)
+	}
+	if (payload.Expires + clockSkewSeconds) < now.Unix() {
+		return nil, fmt.Errorf("OIDC provider token has expired at: %d", payload.Expires)
+	}
+
+	for _, standardClaim := range []string{"iss", "aud", "exp", "iat", "sub", "auth_time"} {
+		delete(claims, standardClaim)
+	}
+	return &Token{
+		AuthTime: payload.AuthTime,
+		Issuer:   payload.Issuer,
+		Audience: config.ClientID,
+		Expires:  payload.Expires,
+		IssuedAt: payload.IssuedAt,
+		Subject:  payload.Subject,
+		UID:      payload.Subject,
+		Claims:   claims,
+	}, nil
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// oidcKeyCache holds the key sources of the OIDC provider issuers, so that the keys of each
+// issuer are cached across calls.
+type oidcKeyCache struct {
+	httpClient      *http.Client
+	maxResponseSize int64
+	mu              sync.Mutex
+	sources         map[string]*oidcKeySource
+}
+
+func newOIDCKeyCache(hc *http.Client, maxResponseSize int64) *oidcKeyCache {
+	return &oidcKeyCache{
+		httpClient:      hc,
+		maxResponseSize: maxResponseSize,
+		sources:         make(map[string]*oidcKeySource),
+	}
+}
+
+func (c *oidcKeyCache) keySource(issuer string) *oidcKeySource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ks, ok := c.sources[issuer]
+	if !ok {
+		ks = &oidcKeySource{
+			Issuer:          issuer,
+			HTTPClient:      c.httpClient,
+			MaxResponseSize: c.maxResponseSize,
+			Mutex:           &sync.Mutex{},
+		}
+		c.sources[issuer] = ks
+	}
+	return ks
+}
+
+// oidcKeySource fetches the RSA public keys of an OIDC provider. The JWKS URI of the provider is
+// discovered from its OpenID configuration once, and the keys are then fetched and cached by an
+// httpKeySource.
+type oidcKeySource struct {
+	Issuer          string
+	HTTPClient      *http.Client
+	MaxResponseSize int64
+	JWKS            *httpKeySource
+	Mutex           *sync.Mutex
+}
+
+// Keys returns the RSA public keys of the provider, discovering the JWKS URI if necessary.
+func (k *oidcKeySource) Keys(ctx context.Context) ([]*publicKey, error) {
+	k.Mutex.Lock()
+	if k.JWKS == nil {
+		uri, err := k.discoverJWKSURI(ctx)
+		if err != nil {
+			k.Mutex.Unlock()
+			return nil, err
+		}
+		k.JWKS = newHTTPKeySource(uri, k.HTTPClient)
+		k.JWKS.ParseKeys = parseJWKS
+		k.JWKS.DefaultMaxAge = defaultJWKSMaxAge
+		k.JWKS.MaxResponseSize = k.MaxResponseSize
+	}
+	jwks := k.JWKS
+	k.Mutex.Unlock()
+	return jwks.Keys(ctx)
+}
+
+func (k *oidcKeySource) discoverJWKSURI(ctx context.Context) (string, error) {
+	uri := strings.TrimSuffix(k.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := k.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	contents, err := internal.ReadResponseBody(resp.Body, k.MaxResponseSize)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("invalid response (%d) while retrieving OpenID configuration: %s",
+			resp.StatusCode, string(contents))
+	}
+
+	var config struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return "", err
+	}
+	if config.JWKSURI == "" {
+		return "", fmt.Errorf("OpenID configuration of %q does not specify a jwks_uri", k.Issuer)
+	}
+	return config.JWKSURI, nil
+}
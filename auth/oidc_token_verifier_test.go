@@ -0,0 +1,207 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+type mockOIDCProvider struct {
+	Srv      *httptest.Server
+	Requests []string
+}
+
+// newMockOIDCProvider starts a server that serves an OpenID configuration and a JWKS containing
+// the public key of the mock signing key.
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	p := &mockOIDCProvider{}
+	jwks := mockJWKS(t, "mock-key-id-1")
+	p.Srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.Requests = append(p.Requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": %q, "jwks_uri": %q}`, p.Srv.URL, p.Srv.URL+"/keys")
+		case "/keys":
+			w.Write(jwks)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return p
+}
+
+func (p *mockOIDCProvider) configResponse(enabled bool) []byte {
+	return []byte(fmt.Sprintf(`{
+		"name": "projects/mock-project-id/oauthIdpConfigs/oidc.provider",
+		"clientId": "CLIENT_ID",
+		"issuer": %q,
+		"enabled": %v
+	}`, p.Srv.URL, enabled))
+}
+
+func (p *mockOIDCProvider) token(claims mockIDTokenPayload) string {
+	payload := mockIDTokenPayload{
+		"iss":      p.Srv.URL,
+		"aud":      "CLIENT_ID",
+		"sub":      "provider-uid",
+		"email":    "user@example.com",
+		"firebase": nil,
+		"admin":    nil,
+	}
+	for k, v := range claims {
+		payload[k] = v
+	}
+	return getIDToken(payload)
+}
+
+func TestVerifyOIDCProviderToken(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	defer idp.Srv.Close()
+	s := echoServer(idp.configResponse(true), t)
+	defer s.Close()
+	s.Client.clock = testClock
+
+	for _, aud := range []interface{}{"CLIENT_ID", []string{"OTHER", "CLIENT_ID"}} {
+		token, err := s.Client.VerifyOIDCProviderToken(
+			context.Background(), "oidc.provider", idp.token(mockIDTokenPayload{"aud": aud}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token.Issuer != idp.Srv.URL || token.Audience != "CLIENT_ID" {
+			t.Errorf("(Issuer, Audience) = (%q, %q); want = (%q, %q)", token.Issuer, token.Audience, idp.Srv.URL, "CLIENT_ID")
+		}
+		if token.UID != "provider-uid" || token.Subject != "provider-uid" {
+			t.Errorf("(UID, Subject) = (%q, %q); want = %q", token.UID, token.Subject, "provider-uid")
+		}
+		if token.Claims["email"] != "user@example.com" {
+			t.Errorf("Claims[email] = %v; want = %q", token.Claims["email"], "user@example.com")
+		}
+	}
+
+	wantPath := "/projects/mock-project-id/oauthIdpConfigs/oidc.provider"
+	if s.Req[0].URL.Path != wantPath {
+		t.Errorf("VerifyOIDCProviderToken() URL = %q; want = %q", s.Req[0].URL.Path, wantPath)
+	}
+	// The OpenID configuration and the keys are cached across calls.
+	if len(idp.Requests) != 2 {
+		t.Errorf("VerifyOIDCProviderToken() = %v requests to the provider; want = 2", idp.Requests)
+	}
+}
+
+func TestVerifyOIDCProviderTokenInvalid(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	defer idp.Srv.Close()
+	s := echoServer(idp.configResponse(true), t)
+	defer s.Close()
+	s.Client.clock = testClock
+
+	now := testClock.Now().Unix()
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"Empty", ""},
+		{"Malformed", "not.a.token"},
+		{"WrongIssuer", idp.token(mockIDTokenPayload{"iss": "https://other.issuer"})},
+		{"WrongAudience", idp.token(mockIDTokenPayload{"aud": "OTHER"})},
+		{"WrongAudiences", idp.token(mockIDTokenPayload{"aud": []string{"OTHER"}})},
+		{"NoSubject", idp.token(mockIDTokenPayload{"sub": ""})},
+		{"Expired", idp.token(mockIDTokenPayload{"exp": now - 3600})},
+		{"FutureIssuedAt", idp.token(mockIDTokenPayload{"iat": now + 3600})},
+		{"UnknownKey", getIDTokenWithKid("other-key", mockIDTokenPayload{
+			"iss": idp.Srv.URL,
+			"aud": "CLIENT_ID",
+		})},
+	}
+	for _, tc := range cases {
+		token, err := s.Client.VerifyOIDCProviderToken(context.Background(), "oidc.provider", tc.token)
+		if token != nil || !IsOIDCTokenInvalid(err) {
+			t.Errorf("VerifyOIDCProviderToken(%s) = (%v, %v); want = (nil, OIDCTokenInvalid)", tc.name, token, err)
+		}
+	}
+}
+
+func TestVerifyOIDCProviderTokenDisabled(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	defer idp.Srv.Close()
+	s := echoServer(idp.configResponse(false), t)
+	defer s.Close()
+	s.Client.clock = testClock
+
+	token, err := s.Client.VerifyOIDCProviderToken(context.Background(), "oidc.provider", idp.token(nil))
+	want := `OIDC provider config "oidc.provider" is disabled`
+	if token != nil || err == nil || err.Error() != want {
+		t.Errorf("VerifyOIDCProviderToken() = (%v, %v); want = (nil, %q)", token, err, want)
+	}
+	if len(idp.Requests) != 0 {
+		t.Errorf("VerifyOIDCProviderToken() = %v requests to the provider; want = none", idp.Requests)
+	}
+}
+
+func TestVerifyOIDCProviderTokenDiscoveryError(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	defer idp.Srv.Close()
+	s := echoServer([]byte(fmt.Sprintf(`{
+		"name": "projects/mock-project-id/oauthIdpConfigs/oidc.provider",
+		"clientId": "CLIENT_ID",
+		"issuer": %q,
+		"enabled": true
+	}`, idp.Srv.URL+"/missing")), t)
+	defer s.Close()
+	s.Client.clock = testClock
+
+	token, err := s.Client.VerifyOIDCProviderToken(
+		context.Background(), "oidc.provider", idp.token(mockIDTokenPayload{"iss": idp.Srv.URL + "/missing"}))
+	if token != nil || !IsCertificateFetchFailed(err) {
+		t.Errorf("VerifyOIDCProviderToken() = (%v, %v); want = (nil, CertificateFetchFailed)", token, err)
+	}
+}
+
+func TestVerifyOIDCProviderTokenConfigNotFound(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "CONFIGURATION_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	token, err := s.Client.VerifyOIDCProviderToken(context.Background(), "oidc.provider", "token")
+	if token != nil || !IsConfigurationNotFound(err) {
+		t.Errorf("VerifyOIDCProviderToken() = (%v, %v); want = (nil, ConfigurationNotFound)", token, err)
+	}
+}
+
+func TestOIDCKeySourceResponseTooLarge(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	defer idp.Srv.Close()
+
+	// The limit applies to both the OpenID configuration and the JWKS of the provider.
+	for _, limit := range []int64{10, 200} {
+		ks := newOIDCKeyCache(http.DefaultClient, limit).keySource(idp.Srv.URL)
+		keys, err := ks.Keys(context.Background())
+		if keys != nil || !internal.IsResponseTooLarge(err) {
+			t.Errorf("Keys(limit = %d) = (%v, %v); want = (nil, ResponseTooLarge)", limit, keys, err)
+		}
+	}
+	want := []string{"/.well-known/openid-configuration", "/.well-known/openid-configuration", "/keys"}
+	if !reflect.DeepEqual(idp.Requests, want) {
+		t.Errorf("Requests = %v; want = %v", idp.Requests, want)
+	}
+}
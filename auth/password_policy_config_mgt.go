@@ -0,0 +1,321 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+const (
+	passwordPolicyConfigKey = "passwordPolicyConfig"
+
+	minPasswordLengthLowerBound = 6
+	minPasswordLengthUpperBound = 30
+	maxPasswordLengthUpperBound = 4096
+)
+
+// PasswordPolicyEnforcementState is the enforcement state of a password policy.
+type PasswordPolicyEnforcementState string
+
+// These constants represent the possible values for the PasswordPolicyEnforcementState type.
+const (
+	// PasswordPolicyEnforce rejects passwords that do not meet the constraints of the policy, when
+	// users sign up or change their password.
+	PasswordPolicyEnforce PasswordPolicyEnforcementState = "ENFORCE"
+
+	// PasswordPolicyOff disables the password policy.
+	PasswordPolicyOff PasswordPolicyEnforcementState = "OFF"
+)
+
+// PasswordPolicyConfig represents the password policy of a tenant or project.
+type PasswordPolicyConfig struct {
+	// The enforcement state of the policy. Must be specified in updates.
+	EnforcementState PasswordPolicyEnforcementState
+	// ForceUpgradeOnSignIn requires users whose password does not meet the policy to change their
+	// password when they sign in.
+	ForceUpgradeOnSignIn bool
+	// The constraints that passwords must meet. Must be specified when the policy is enforced.
+	Constraints *PasswordConstraints
+}
+
+// PasswordConstraints represents the constraints of a password policy.
+type PasswordConstraints struct {
+	RequireUppercase       bool
+	RequireLowercase       bool
+	RequireNumeric         bool
+	RequireNonAlphanumeric bool
+	// The minimum length of passwords, between 6 and 30. Defaults to 6 if zero.
+	MinLength int
+	// The maximum length of passwords, between MinLength and 4096. Defaults to 4096 if zero.
+	MaxLength int
+}
+
+// passwordPolicyConfigJSON is the representation of a password policy in the Identity Toolkit API,
+// where the constraints are nested in a list of policy versions.
+type passwordPolicyConfigJSON struct {
+	EnforcementState     PasswordPolicyEnforcementState `json:"passwordPolicyEnforcementState,omitempty"`
+	ForceUpgradeOnSignin bool                           `json:"forceUpgradeOnSignin,omitempty"`
+	Versions             []*passwordPolicyVersionJSON   `json:"passwordPolicyVersions,omitempty"`
+}
+
+type passwordPolicyVersionJSON struct {
+	CustomStrengthOptions *customStrengthOptionsJSON `json:"customStrengthOptions,omitempty"`
+}
+
+type customStrengthOptionsJSON struct {
+	MinPasswordLength                int  `json:"minPasswordLength,omitempty"`
+	MaxPasswordLength                int  `json:"maxPasswordLength,omitempty"`
+	ContainsLowercaseCharacter       bool `json:"containsLowercaseCharacter,omitempty"`
+	ContainsUppercaseCharacter       bool `json:"containsUppercaseCharacter,omitempty"`
+	ContainsNumericCharacter         bool `json:"containsNumericCharacter,omitempty"`
+	ContainsNonAlphanumericCharacter bool `json:"containsNonAlphanumericCharacter,omitempty"`
+}
+
+// MarshalJSON marshals a PasswordPolicyConfig into the representation of the Identity Toolkit API.
+func (ppc PasswordPolicyConfig) MarshalJSON() ([]byte, error) {
+	result := passwordPolicyConfigJSON{
+		EnforcementState:     ppc.EnforcementState,
+		ForceUpgradeOnSignin: ppc.ForceUpgradeOnSignIn,
+	}
+	if c := ppc.Constraints; c != nil {
+		result.Versions = []*passwordPolicyVersionJSON{{
+			CustomStrengthOptions: &customStrengthOptionsJSON{
+				MinPasswordLength:                c.MinLength,
+				MaxPasswordLength:                c.MaxLength,
+				ContainsLowercaseCharacter:       c.RequireLowercase,
+				ContainsUppercaseCharacter:       c.RequireUppercase,
+				ContainsNumericCharacter:         c.RequireNumeric,
+				ContainsNonAlphanumericCharacter: c.RequireNonAlphanumeric,
+			},
+		}}
+	}
+	return json.Marshal(result)
+}
+
+// UnmarshalJSON unmarshals a PasswordPolicyConfig from the representation of the Identity Toolkit
+// API. The constraints are taken from the first policy version.
+func (ppc *PasswordPolicyConfig) UnmarshalJSON(b []byte) error {
+	var parsed passwordPolicyConfigJSON
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		return err
+	}
+
+	*ppc = PasswordPolicyConfig{
+		EnforcementState:     parsed.EnforcementState,
+		ForceUpgradeOnSignIn: parsed.ForceUpgradeOnSignin,
+	}
+	if len(parsed.Versions) > 0 && parsed.Versions[0] != nil && parsed.Versions[0].CustomStrengthOptions != nil {
+		opts := parsed.Versions[0].CustomStrengthOptions
+		ppc.Constraints = &PasswordConstraints{
+			RequireUppercase:       opts.ContainsUppercaseCharacter,
+			RequireLowercase:       opts.ContainsLowercaseCharacter,
+			RequireNumeric:         opts.ContainsNumericCharacter,
+			RequireNonAlphanumeric: opts.ContainsNonAlphanumericCharacter,
+			MinLength:              opts.MinPasswordLength,
+			MaxLength:              opts.MaxPasswordLength,
+		}
+	}
+	return nil
+}
+
+func (ppc *PasswordPolicyConfig) validate() error {
+	if ppc.EnforcementState != PasswordPolicyEnforce && ppc.EnforcementState != PasswordPolicyOff {
+		return fmt.Errorf("\"PasswordPolicyConfig.EnforcementState\" must be 'ENFORCE' or 'OFF'; got %q",
+			string(ppc.EnforcementState))
+	}
+	if ppc.Constraints == nil {
+		if ppc.EnforcementState == PasswordPolicyEnforce {
+			return errors.New("\"PasswordPolicyConfig.Constraints\" must be specified when the policy is enforced")
+		}
+		return nil
+	}
+	return ppc.Constraints.validate()
+}
+
+func (c *PasswordConstraints) validate() error {
+	minLength := c.MinLength
+	if minLength != 0 && (minLength < minPasswordLengthLowerBound || minLength > minPasswordLengthUpperBound) {
+		return fmt.Errorf("\"MinLength\" must be between %d and %d; got %d",
+			minPasswordLengthLowerBound, minPasswordLengthUpperBound, minLength)
+	}
+	if minLength == 0 {
+		minLength = minPasswordLengthLowerBound
+	}
+	if c.MaxLength != 0 && (c.MaxLength < minLength || c.MaxLength > maxPasswordLengthUpperBound) {
+		return fmt.Errorf("\"MaxLength\" must be between %d and %d; got %d",
+			minLength, maxPasswordLengthUpperBound, c.MaxLength)
+	}
+	return nil
+}
+
+// validatePasswordPolicyConfig validates the password policy config in the given project or tenant
+// update request, if any.
+func validatePasswordPolicyConfig(req map[string]interface{}) error {
+	val, ok := req[passwordPolicyConfigKey]
+	if !ok {
+		return nil
+	}
+	passwordPolicyConfig, ok := val.(PasswordPolicyConfig)
+	if !ok {
+		return fmt.Errorf("invalid type for PasswordPolicyConfig: %v", val)
+	}
+	return passwordPolicyConfig.validate()
+}
+
+// PasswordPolicy is the effective password policy of a tenant or project, with the defaults of the
+// unset constraints applied.
+//
+// It can be used to check passwords locally, e.g. to give immediate feedback to users before
+// creating or updating their accounts.
+type PasswordPolicy struct {
+	EnforcementState     PasswordPolicyEnforcementState
+	ForceUpgradeOnSignIn bool
+	Constraints          PasswordConstraints
+}
+
+// PasswordRequirement identifies a requirement of a password policy.
+type PasswordRequirement string
+
+// These constants represent the possible values for the PasswordRequirement type.
+const (
+	PasswordMinLength                PasswordRequirement = "MIN_LENGTH"
+	PasswordMaxLength                PasswordRequirement = "MAX_LENGTH"
+	PasswordLowercaseCharacter       PasswordRequirement = "LOWERCASE_CHARACTER"
+	PasswordUppercaseCharacter       PasswordRequirement = "UPPERCASE_CHARACTER"
+	PasswordNumericCharacter         PasswordRequirement = "NUMERIC_CHARACTER"
+	PasswordNonAlphanumericCharacter PasswordRequirement = "NON_ALPHANUMERIC_CHARACTER"
+)
+
+// PasswordPolicyError is returned by PasswordPolicy.Validate when a password does not meet a
+// requirement of the policy.
+type PasswordPolicyError struct {
+	// The requirement that the password does not meet.
+	Requirement PasswordRequirement
+	msg         string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.msg
+}
+
+// GetPasswordPolicy returns the password policy of the tenant or project.
+//
+// For a tenant-aware client the policy of the tenant is returned, and otherwise the policy of the
+// project. If no password policy is configured, the returned policy is not enforced.
+func (c *baseClient) GetPasswordPolicy(ctx context.Context) (*PasswordPolicy, error) {
+	var config *PasswordPolicyConfig
+	if c.tenantID != "" {
+		req := &internal.Request{
+			Method: http.MethodGet,
+		}
+		var tenant Tenant
+		if _, err := c.makeRequest(ctx, req, &tenant); err != nil {
+			return nil, err
+		}
+		config = tenant.PasswordPolicyConfig
+	} else {
+		projectConfig, err := c.GetProjectConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		config = projectConfig.PasswordPolicyConfig
+	}
+	return newPasswordPolicy(config), nil
+}
+
+func newPasswordPolicy(config *PasswordPolicyConfig) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		EnforcementState: PasswordPolicyOff,
+	}
+	if config != nil {
+		if config.EnforcementState != "" {
+			policy.EnforcementState = config.EnforcementState
+		}
+		policy.ForceUpgradeOnSignIn = config.ForceUpgradeOnSignIn
+		if config.Constraints != nil {
+			policy.Constraints = *config.Constraints
+		}
+	}
+	if policy.Constraints.MinLength == 0 {
+		policy.Constraints.MinLength = minPasswordLengthLowerBound
+	}
+	if policy.Constraints.MaxLength == 0 {
+		policy.Constraints.MaxLength = maxPasswordLengthUpperBound
+	}
+	return policy
+}
+
+// Validate checks the given password against the constraints of the policy.
+//
+// A *PasswordPolicyError identifying the first requirement that the password does not meet is
+// returned if the password is rejected. Lengths are counted in characters. Uppercase, lowercase
+// and numeric characters are the ASCII letters and digits, and any other character is considered
+// non-alphanumeric. Any password is accepted if the policy is not enforced.
+func (p *PasswordPolicy) Validate(password string) error {
+	if p.EnforcementState != PasswordPolicyEnforce {
+		return nil
+	}
+
+	c := p.Constraints
+	if length := utf8.RuneCountInString(password); c.MinLength > 0 && length < c.MinLength {
+		return passwordPolicyError(PasswordMinLength,
+			"password must contain at least %d characters; got %d", c.MinLength, length)
+	} else if c.MaxLength > 0 && length > c.MaxLength {
+		return passwordPolicyError(PasswordMaxLength,
+			"password must contain at most %d characters; got %d", c.MaxLength, length)
+	}
+
+	var lower, upper, numeric, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			numeric = true
+		default:
+			other = true
+		}
+	}
+	if c.RequireLowercase && !lower {
+		return passwordPolicyError(PasswordLowercaseCharacter, "password must contain a lowercase character")
+	}
+	if c.RequireUppercase && !upper {
+		return passwordPolicyError(PasswordUppercaseCharacter, "password must contain an uppercase character")
+	}
+	if c.RequireNumeric && !numeric {
+		return passwordPolicyError(PasswordNumericCharacter, "password must contain a numeric character")
+	}
+	if c.RequireNonAlphanumeric && !other {
+		return passwordPolicyError(PasswordNonAlphanumericCharacter,
+			"password must contain a non-alphanumeric character")
+	}
+	return nil
+}
+
+func passwordPolicyError(req PasswordRequirement, format string, args ...interface{}) error {
+	return &PasswordPolicyError{
+		Requirement: req,
+		msg:         fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPasswordPolicyConfig(t *testing.T) {
+	cases := []PasswordPolicyConfig{
+		{EnforcementState: PasswordPolicyOff},
+		{EnforcementState: PasswordPolicyEnforce, Constraints: &PasswordConstraints{}},
+		{
+			EnforcementState: PasswordPolicyEnforce,
+			Constraints:      &PasswordConstraints{MinLength: 6, MaxLength: 6},
+		},
+		{
+			EnforcementState:     PasswordPolicyEnforce,
+			ForceUpgradeOnSignIn: true,
+			Constraints: &PasswordConstraints{
+				RequireUppercase: true,
+				RequireNumeric:   true,
+				MinLength:        30,
+				MaxLength:        4096,
+			},
+		},
+	}
+	for i, ppc := range cases {
+		if err := ppc.validate(); err != nil {
+			t.Errorf("[%d] PasswordPolicyConfig.validate() = %v; want = nil", i, err)
+		}
+	}
+}
+
+func TestPasswordPolicyConfigInvalid(t *testing.T) {
+	cases := []struct {
+		config PasswordPolicyConfig
+		want   string
+	}{
+		{
+			PasswordPolicyConfig{},
+			`"PasswordPolicyConfig.EnforcementState" must be 'ENFORCE' or 'OFF'; got ""`,
+		},
+		{
+			PasswordPolicyConfig{EnforcementState: "AUDIT"},
+			`"PasswordPolicyConfig.EnforcementState" must be 'ENFORCE' or 'OFF'; got "AUDIT"`,
+		},
+		{
+			PasswordPolicyConfig{EnforcementState: PasswordPolicyEnforce},
+			`"PasswordPolicyConfig.Constraints" must be specified when the policy is enforced`,
+		},
+		{
+			PasswordPolicyConfig{
+				EnforcementState: PasswordPolicyEnforce,
+				Constraints:      &PasswordConstraints{MinLength: 5},
+			},
+			`"MinLength" must be between 6 and 30; got 5`,
+		},
+		{
+			PasswordPolicyConfig{
+				EnforcementState: PasswordPolicyEnforce,
+				Constraints:      &PasswordConstraints{MinLength: 31},
+			},
+			`"MinLength" must be between 6 and 30; got 31`,
+		},
+		{
+			PasswordPolicyConfig{
+				EnforcementState: PasswordPolicyEnforce,
+				Constraints:      &PasswordConstraints{MinLength: 10, MaxLength: 8},
+			},
+			`"MaxLength" must be between 10 and 4096; got 8`,
+		},
+		{
+			PasswordPolicyConfig{
+				EnforcementState: PasswordPolicyOff,
+				Constraints:      &PasswordConstraints{MaxLength: 4097},
+			},
+			`"MaxLength" must be between 6 and 4096; got 4097`,
+		},
+	}
+	for _, tc := range cases {
+		if err := tc.config.validate(); err == nil || err.Error() != tc.want {
+			t.Errorf("PasswordPolicyConfig.validate() = %v; want = %q", err, tc.want)
+		}
+	}
+}
+
+func TestGetProjectConfigWithPasswordPolicy(t *testing.T) {
+	resp := `{
+		"passwordPolicyConfig": {
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"forceUpgradeOnSignin": true,
+			"passwordPolicyVersions": [
+				{
+					"customStrengthOptions": {
+						"minPasswordLength": 8,
+						"maxPasswordLength": 64,
+						"containsLowercaseCharacter": true,
+						"containsNonAlphanumericCharacter": true
+					}
+				}
+			]
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	projectConfig, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ProjectConfig{
+		PasswordPolicyConfig: &PasswordPolicyConfig{
+			EnforcementState:     PasswordPolicyEnforce,
+			ForceUpgradeOnSignIn: true,
+			Constraints: &PasswordConstraints{
+				RequireLowercase:       true,
+				RequireNonAlphanumeric: true,
+				MinLength:              8,
+				MaxLength:              64,
+			},
+		},
+	}
+	if !reflect.DeepEqual(projectConfig, want) {
+		t.Errorf("GetProjectConfig() = %#v, want = %#v", projectConfig, want)
+	}
+}
+
+func TestUpdateProjectConfigPasswordPolicy(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	options := (&ProjectConfigToUpdate{}).PasswordPolicyConfig(PasswordPolicyConfig{
+		EnforcementState: PasswordPolicyEnforce,
+		Constraints: &PasswordConstraints{
+			RequireUppercase: true,
+			RequireNumeric:   true,
+			MinLength:        10,
+		},
+	})
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody := map[string]interface{}{
+		"passwordPolicyConfig": map[string]interface{}{
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"passwordPolicyVersions": []interface{}{
+				map[string]interface{}{
+					"customStrengthOptions": map[string]interface{}{
+						"containsUppercaseCharacter": true,
+						"containsNumericCharacter":   true,
+						"minPasswordLength":          float64(10),
+					},
+				},
+			},
+		},
+	}
+	if err := checkUpdateProjectConfigRequest(s, wantBody, []string{"passwordPolicyConfig"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateTenantPasswordPolicy(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	options := (&TenantToUpdate{}).PasswordPolicyConfig(PasswordPolicyConfig{
+		EnforcementState:     PasswordPolicyOff,
+		ForceUpgradeOnSignIn: true,
+	})
+	if _, err := s.Client.TenantManager.UpdateTenant(context.Background(), "tenantID", options); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody := map[string]interface{}{
+		"passwordPolicyConfig": map[string]interface{}{
+			"passwordPolicyEnforcementState": "OFF",
+			"forceUpgradeOnSignin":           true,
+		},
+	}
+	if err := checkUpdateTenantRequest(s, wantBody, []string{"passwordPolicyConfig"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdatePasswordPolicyConfigInvalid(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	invalid := PasswordPolicyConfig{
+		EnforcementState: PasswordPolicyEnforce,
+		Constraints:      &PasswordConstraints{MinLength: 4},
+	}
+	want := `"MinLength" must be between 6 and 30; got 4`
+	project := (&ProjectConfigToUpdate{}).PasswordPolicyConfig(invalid)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), project); err == nil || err.Error() != want {
+		t.Errorf("UpdateProjectConfig() = %v; want = %q", err, want)
+	}
+	update := (&TenantToUpdate{}).PasswordPolicyConfig(invalid)
+	if _, err := s.Client.TenantManager.UpdateTenant(context.Background(), "tenantID", update); err == nil || err.Error() != want {
+		t.Errorf("UpdateTenant() = %v; want = %q", err, want)
+	}
+	create := (&TenantToCreate{}).PasswordPolicyConfig(invalid)
+	if _, err := s.Client.TenantManager.CreateTenant(context.Background(), create); err == nil || err.Error() != want {
+		t.Errorf("CreateTenant() = %v; want = %q", err, want)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestGetPasswordPolicy(t *testing.T) {
+	resp := `{
+		"passwordPolicyConfig": {
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"passwordPolicyVersions": [
+				{
+					"customStrengthOptions": {
+						"minPasswordLength": 8,
+						"containsNumericCharacter": true
+					}
+				}
+			]
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	policy, err := s.Client.GetPasswordPolicy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PasswordPolicy{
+		EnforcementState: PasswordPolicyEnforce,
+		Constraints: PasswordConstraints{
+			RequireNumeric: true,
+			MinLength:      8,
+			MaxLength:      4096,
+		},
+	}
+	if !reflect.DeepEqual(policy, want) {
+		t.Errorf("GetPasswordPolicy() = %#v, want = %#v", policy, want)
+	}
+	if got := s.Req[0].URL.Path; got != "/projects/mock-project-id/config" {
+		t.Errorf("GetPasswordPolicy() URL = %q; want = %q", got, "/projects/mock-project-id/config")
+	}
+}
+
+func TestGetPasswordPolicyNotConfigured(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	policy, err := s.Client.GetPasswordPolicy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PasswordPolicy{
+		EnforcementState: PasswordPolicyOff,
+		Constraints: PasswordConstraints{
+			MinLength: 6,
+			MaxLength: 4096,
+		},
+	}
+	if !reflect.DeepEqual(policy, want) {
+		t.Errorf("GetPasswordPolicy() = %#v, want = %#v", policy, want)
+	}
+	if err := policy.Validate("a"); err != nil {
+		t.Errorf("Validate() = %v; want = nil", err)
+	}
+}
+
+func TestTenantGetPasswordPolicy(t *testing.T) {
+	resp := `{
+		"name": "projects/mock-project-id/tenants/tenantID",
+		"passwordPolicyConfig": {
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"forceUpgradeOnSignin": true
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	client, err := s.Client.TenantManager.AuthForTenant("tenantID")
+	if err != nil {
+		t.Fatalf("AuthForTenant() = %v", err)
+	}
+	policy, err := client.GetPasswordPolicy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PasswordPolicy{
+		EnforcementState:     PasswordPolicyEnforce,
+		ForceUpgradeOnSignIn: true,
+		Constraints: PasswordConstraints{
+			MinLength: 6,
+			MaxLength: 4096,
+		},
+	}
+	if !reflect.DeepEqual(policy, want) {
+		t.Errorf("GetPasswordPolicy() = %#v, want = %#v", policy, want)
+	}
+	if got := s.Req[0].URL.Path; got != "/projects/mock-project-id/tenants/tenantID" {
+		t.Errorf("GetPasswordPolicy() URL = %q; want = %q", got, "/projects/mock-project-id/tenants/tenantID")
+	}
+}
+
+func TestGetPasswordPolicyError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "CONFIGURATION_NOT_FOUND"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	policy, err := s.Client.GetPasswordPolicy(context.Background())
+	if policy != nil || !IsConfigurationNotFound(err) {
+		t.Errorf("GetPasswordPolicy() = (%v, %v); want = (nil, ConfigurationNotFound)", policy, err)
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := &PasswordPolicy{
+		EnforcementState: PasswordPolicyEnforce,
+		Constraints: PasswordConstraints{
+			RequireLowercase:       true,
+			RequireUppercase:       true,
+			RequireNumeric:         true,
+			RequireNonAlphanumeric: true,
+			MinLength:              8,
+			MaxLength:              12,
+		},
+	}
+	if err := policy.Validate("Passw0rd!"); err != nil {
+		t.Errorf("Validate() = %v; want = nil", err)
+	}
+	if err := policy.Validate("Pässw0rd"); err != nil {
+		t.Errorf("Validate() = %v; want = nil", err)
+	}
+
+	cases := []struct {
+		password string
+		want     PasswordRequirement
+		msg      string
+	}{
+		{"Pa0!", PasswordMinLength, "password must contain at least 8 characters; got 4"},
+		{"Passw0rd!Passw0rd!", PasswordMaxLength, "password must contain at most 12 characters; got 18"},
+		{"PASSW0RD!", PasswordLowercaseCharacter, "password must contain a lowercase character"},
+		{"passw0rd!", PasswordUppercaseCharacter, "password must contain an uppercase character"},
+		{"Password!", PasswordNumericCharacter, "password must contain a numeric character"},
+		{"Passw0rd", PasswordNonAlphanumericCharacter, "password must contain a non-alphanumeric character"},
+	}
+	for _, tc := range cases {
+		err := policy.Validate(tc.password)
+		pe, ok := err.(*PasswordPolicyError)
+		if !ok || pe.Requirement != tc.want || err.Error() != tc.msg {
+			t.Errorf("Validate(%q) = %v; want = %s (%q)", tc.password, err, tc.want, tc.msg)
+		}
+	}
+
+	policy.EnforcementState = PasswordPolicyOff
+	if err := policy.Validate("a"); err != nil {
+		t.Errorf("Validate() = %v; want = nil", err)
+	}
+}
@@ -27,6 +27,20 @@ import (
 // ProjectConfig represents the properties to update on the provided project config.
 type ProjectConfig struct {
 	MultiFactorConfig *MultiFactorConfig `json:"mfa,omitEmpty"`
+	MultiTenantConfig *MultiTenantConfig `json:"multiTenant,omitempty"`
+	RecaptchaConfig   *RecaptchaConfig   `json:"recaptchaConfig,omitempty"`
+
+	PasswordPolicyConfig    *PasswordPolicyConfig    `json:"passwordPolicyConfig,omitempty"`
+	BlockingFunctionsConfig *BlockingFunctionsConfig `json:"blockingFunctions,omitempty"`
+}
+
+// MultiTenantConfig represents the multi-tenancy settings of a project.
+//
+// AllowTenants indicates whether tenants can be created in the project, which requires
+// multi-tenancy to be enabled via the Cloud Console UI. The settings are read-only in this SDK.
+type MultiTenantConfig struct {
+	AllowTenants          bool   `json:"allowTenants"`
+	DefaultTenantLocation string `json:"defaultTenantLocation,omitempty"`
 }
 
 func (base *baseClient) GetProjectConfig(ctx context.Context) (*ProjectConfig, error) {
@@ -74,6 +88,7 @@ type ProjectConfigToUpdate struct {
 
 const (
 	multiFactorConfigProjectKey = "mfa"
+	recaptchaConfigKey          = "recaptchaConfig"
 )
 
 // MultiFactorConfig configures the project's multi-factor settings
@@ -81,6 +96,22 @@ func (pc *ProjectConfigToUpdate) MultiFactorConfig(multiFactorConfig MultiFactor
 	return pc.set(multiFactorConfigProjectKey, multiFactorConfig)
 }
 
+// RecaptchaConfig configures the project's reCAPTCHA Enterprise settings.
+func (pc *ProjectConfigToUpdate) RecaptchaConfig(recaptchaConfig RecaptchaConfig) *ProjectConfigToUpdate {
+	return pc.set(recaptchaConfigKey, recaptchaConfig)
+}
+
+// PasswordPolicyConfig configures the project's password policy.
+func (pc *ProjectConfigToUpdate) PasswordPolicyConfig(passwordPolicyConfig PasswordPolicyConfig) *ProjectConfigToUpdate {
+	return pc.set(passwordPolicyConfigKey, passwordPolicyConfig)
+}
+
+// BlockingFunctionsConfig configures the project's blocking functions. The given config replaces
+// all existing triggers.
+func (pc *ProjectConfigToUpdate) BlockingFunctionsConfig(blockingFunctionsConfig BlockingFunctionsConfig) *ProjectConfigToUpdate {
+	return pc.set(blockingFunctionsConfigKey, blockingFunctionsConfig)
+}
+
 func (pc *ProjectConfigToUpdate) set(key string, value interface{}) *ProjectConfigToUpdate {
 	pc.ensureParams().Set(key, value)
 	return pc
@@ -108,5 +139,11 @@ func (pc *ProjectConfigToUpdate) validate() error {
 			return err
 		}
 	}
-	return nil
+	if err := validateRecaptchaConfig(req); err != nil {
+		return err
+	}
+	if err := validatePasswordPolicyConfig(req); err != nil {
+		return err
+	}
+	return validateBlockingFunctionsConfig(req)
 }
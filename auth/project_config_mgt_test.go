@@ -67,6 +67,56 @@ func TestGetProjectConfig(t *testing.T) {
 	}
 }
 
+func TestGetProjectConfigWithSMSMultiFactor(t *testing.T) {
+	resp := `{
+		"mfa": {
+			"state": "ENABLED",
+			"enabledProviders": ["PHONE_SMS"]
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	projectConfig, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ProjectConfig{
+		MultiFactorConfig: &MultiFactorConfig{
+			State:            Enabled,
+			EnabledProviders: []string{PhoneSMSProvider},
+		},
+	}
+	if !reflect.DeepEqual(projectConfig, want) {
+		t.Errorf("GetProjectConfig() = %#v, want = %#v", projectConfig, want)
+	}
+}
+
+func TestGetProjectConfigWithMultiTenant(t *testing.T) {
+	resp := `{
+		"multiTenant": {
+			"allowTenants": true,
+			"defaultTenantLocation": "organizations/123"
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	projectConfig, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ProjectConfig{
+		MultiTenantConfig: &MultiTenantConfig{
+			AllowTenants:          true,
+			DefaultTenantLocation: "organizations/123",
+		},
+	}
+	if !reflect.DeepEqual(projectConfig, want) {
+		t.Errorf("GetProjectConfig() = %#v, want = %#v", projectConfig, want)
+	}
+}
+
 func TestUpdateProjectConfig(t *testing.T) {
 	s := echoServer([]byte(projectConfigResponse), t)
 	defer s.Close()
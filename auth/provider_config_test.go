@@ -155,6 +155,44 @@ func TestOIDCProviderConfigError(t *testing.T) {
 	}
 }
 
+func TestGetOIDCProviderConfigs(t *testing.T) {
+	s := echoServer(nil, t)
+	defer s.Close()
+	s.Client.baseClient.httpClient.RetryConfig = nil
+	s.Srv.Config.Handler = providerConfigLookupHandler(
+		"/projects/mock-project-id/oauthIdpConfigs/oidc.provider", oidcConfigResponse)
+
+	ids := []string{"oidc.provider", "oidc.missing", "invalid"}
+	result, err := s.Client.GetOIDCProviderConfigs(context.Background(), ids, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Configs) != 1 || !reflect.DeepEqual(result.Configs["oidc.provider"], oidcProviderConfig) {
+		t.Errorf("GetOIDCProviderConfigs().Configs = %#v; want = {oidc.provider: %#v}", result.Configs, oidcProviderConfig)
+	}
+	if len(result.Errors) != 2 {
+		t.Errorf("GetOIDCProviderConfigs().Errors = %v; want = 2 errors", result.Errors)
+	}
+	if err := result.Errors["oidc.missing"]; !IsConfigurationNotFound(err) {
+		t.Errorf("GetOIDCProviderConfigs().Errors[oidc.missing] = %v; want = ConfigurationNotFound", err)
+	}
+	wantErr := `invalid OIDC provider id: "invalid"`
+	if err := result.Errors["invalid"]; err == nil || err.Error() != wantErr {
+		t.Errorf("GetOIDCProviderConfigs().Errors[invalid] = %v; want = %q", err, wantErr)
+	}
+}
+
+func TestGetOIDCProviderConfigsTooMany(t *testing.T) {
+	client := &baseClient{}
+	ids := make([]string, maxConfigs+1)
+	result, err := client.GetOIDCProviderConfigs(context.Background(), ids, nil)
+	want := "ids must not contain more than 100 elements"
+	if result != nil || err == nil || err.Error() != want {
+		t.Errorf("GetOIDCProviderConfigs() = (%v, %v); want = (nil, %q)", result, err, want)
+	}
+}
+
 func TestCreateOIDCProviderConfig(t *testing.T) {
 	s := echoServer([]byte(oidcConfigResponse), t)
 	defer s.Close()
@@ -565,6 +603,46 @@ func TestUpdateOIDCProviderConfigInvalidInput(t *testing.T) {
 	}
 }
 
+func TestEnableDisableOIDCProviderConfig(t *testing.T) {
+	s := echoServer([]byte(oidcConfigResponse), t)
+	defer s.Close()
+
+	cases := []struct {
+		name    string
+		update  func(context.Context, string) (*OIDCProviderConfig, error)
+		enabled bool
+	}{
+		{"Enable", s.Client.EnableOIDCProviderConfig, true},
+		{"Disable", s.Client.DisableOIDCProviderConfig, false},
+	}
+	for _, tc := range cases {
+		s.Req = nil
+		oidc, err := tc.update(context.Background(), "oidc.provider")
+		if err != nil {
+			t.Fatalf("%sOIDCProviderConfig() = %v", tc.name, err)
+		}
+
+		if !reflect.DeepEqual(oidc, oidcProviderConfig) {
+			t.Errorf("%sOIDCProviderConfig() = %#v; want = %#v", tc.name, oidc, oidcProviderConfig)
+		}
+		wantBody := map[string]interface{}{
+			"enabled": tc.enabled,
+		}
+		if err := checkUpdateOIDCConfigRequest(s, wantBody, []string{"enabled"}); err != nil {
+			t.Errorf("%sOIDCProviderConfig() %v", tc.name, err)
+		}
+	}
+}
+
+func TestDisableOIDCProviderConfigInvalidID(t *testing.T) {
+	client := &baseClient{}
+	want := "invalid OIDC provider id: "
+	_, err := client.DisableOIDCProviderConfig(context.Background(), "saml.config")
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("DisableOIDCProviderConfig() = %v; want = %q", err, want)
+	}
+}
+
 func TestDeleteOIDCProviderConfig(t *testing.T) {
 	s := echoServer([]byte("{}"), t)
 	defer s.Close()
@@ -737,6 +815,58 @@ func TestSAMLProviderConfigError(t *testing.T) {
 	}
 }
 
+func TestGetSAMLProviderConfigs(t *testing.T) {
+	s := echoServer(nil, t)
+	defer s.Close()
+	s.Client.baseClient.httpClient.RetryConfig = nil
+	s.Srv.Config.Handler = providerConfigLookupHandler(
+		"/projects/mock-project-id/inboundSamlConfigs/saml.provider", samlConfigResponse)
+
+	ids := []string{"saml.provider", "saml.missing", "invalid"}
+	result, err := s.Client.GetSAMLProviderConfigs(context.Background(), ids, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Configs) != 1 || !reflect.DeepEqual(result.Configs["saml.provider"], samlProviderConfig) {
+		t.Errorf("GetSAMLProviderConfigs().Configs = %#v; want = {saml.provider: %#v}", result.Configs, samlProviderConfig)
+	}
+	if len(result.Errors) != 2 {
+		t.Errorf("GetSAMLProviderConfigs().Errors = %v; want = 2 errors", result.Errors)
+	}
+	if err := result.Errors["saml.missing"]; !IsConfigurationNotFound(err) {
+		t.Errorf("GetSAMLProviderConfigs().Errors[saml.missing] = %v; want = ConfigurationNotFound", err)
+	}
+	wantErr := `invalid SAML provider id: "invalid"`
+	if err := result.Errors["invalid"]; err == nil || err.Error() != wantErr {
+		t.Errorf("GetSAMLProviderConfigs().Errors[invalid] = %v; want = %q", err, wantErr)
+	}
+}
+
+func TestGetSAMLProviderConfigsTooMany(t *testing.T) {
+	client := &baseClient{}
+	ids := make([]string, maxConfigs+1)
+	result, err := client.GetSAMLProviderConfigs(context.Background(), ids, nil)
+	want := "ids must not contain more than 100 elements"
+	if result != nil || err == nil || err.Error() != want {
+		t.Errorf("GetSAMLProviderConfigs() = (%v, %v); want = (nil, %q)", result, err, want)
+	}
+}
+
+// providerConfigLookupHandler serves the given config for requests to foundPath, and a
+// CONFIGURATION_NOT_FOUND error for all other paths. It is safe for concurrent use.
+func providerConfigLookupHandler(foundPath, config string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == foundPath {
+			w.Write([]byte(config))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(notFoundResponse))
+	})
+}
+
 func TestCreateSAMLProviderConfig(t *testing.T) {
 	s := echoServer([]byte(samlConfigResponse), t)
 	defer s.Close()
@@ -1193,6 +1323,46 @@ func TestUpdateSAMLProviderConfigInvalidInput(t *testing.T) {
 	}
 }
 
+func TestEnableDisableSAMLProviderConfig(t *testing.T) {
+	s := echoServer([]byte(samlConfigResponse), t)
+	defer s.Close()
+
+	cases := []struct {
+		name    string
+		update  func(context.Context, string) (*SAMLProviderConfig, error)
+		enabled bool
+	}{
+		{"Enable", s.Client.EnableSAMLProviderConfig, true},
+		{"Disable", s.Client.DisableSAMLProviderConfig, false},
+	}
+	for _, tc := range cases {
+		s.Req = nil
+		saml, err := tc.update(context.Background(), "saml.provider")
+		if err != nil {
+			t.Fatalf("%sSAMLProviderConfig() = %v", tc.name, err)
+		}
+
+		if !reflect.DeepEqual(saml, samlProviderConfig) {
+			t.Errorf("%sSAMLProviderConfig() = %#v; want = %#v", tc.name, saml, samlProviderConfig)
+		}
+		wantBody := map[string]interface{}{
+			"enabled": tc.enabled,
+		}
+		if err := checkUpdateSAMLConfigRequest(s, wantBody, []string{"enabled"}); err != nil {
+			t.Errorf("%sSAMLProviderConfig() %v", tc.name, err)
+		}
+	}
+}
+
+func TestDisableSAMLProviderConfigInvalidID(t *testing.T) {
+	client := &baseClient{}
+	want := "invalid SAML provider id: "
+	_, err := client.DisableSAMLProviderConfig(context.Background(), "oidc.config")
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("DisableSAMLProviderConfig() = %v; want = %q", err, want)
+	}
+}
+
 func TestDeleteSAMLProviderConfig(t *testing.T) {
 	s := echoServer([]byte("{}"), t)
 	defer s.Close()
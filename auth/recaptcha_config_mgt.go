@@ -0,0 +1,83 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+)
+
+// RecaptchaEnforcementState is the reCAPTCHA Enterprise enforcement state of a sign-in provider.
+type RecaptchaEnforcementState string
+
+// These constants represent the possible values for the RecaptchaEnforcementState type.
+const (
+	// RecaptchaOff disables reCAPTCHA for the provider.
+	RecaptchaOff RecaptchaEnforcementState = "OFF"
+
+	// RecaptchaAudit assesses sign-in requests with reCAPTCHA, and records the results without
+	// blocking any requests.
+	RecaptchaAudit RecaptchaEnforcementState = "AUDIT"
+
+	// RecaptchaEnforce requires sign-in requests to pass the reCAPTCHA assessment.
+	RecaptchaEnforce RecaptchaEnforcementState = "ENFORCE"
+)
+
+// RecaptchaConfig represents the reCAPTCHA Enterprise configuration of a tenant or project, which
+// protects the sign-in providers against abuse.
+//
+// An empty enforcement state leaves the state of the provider unset, which the backend treats as
+// RecaptchaOff.
+type RecaptchaConfig struct {
+	// The enforcement state of reCAPTCHA for the email/password provider.
+	EmailPasswordEnforcementState RecaptchaEnforcementState `json:"emailPasswordEnforcementState,omitempty"`
+	// The enforcement state of reCAPTCHA for the phone provider.
+	PhoneEnforcementState RecaptchaEnforcementState `json:"phoneEnforcementState,omitempty"`
+}
+
+func (rc *RecaptchaConfig) validate() error {
+	if rc == nil {
+		return nil
+	}
+	if err := rc.EmailPasswordEnforcementState.validate(); err != nil {
+		return fmt.Errorf("\"EmailPasswordEnforcementState\" %v", err)
+	}
+	if err := rc.PhoneEnforcementState.validate(); err != nil {
+		return fmt.Errorf("\"PhoneEnforcementState\" %v", err)
+	}
+	return nil
+}
+
+// validateRecaptchaConfig validates the reCAPTCHA config in the given project or tenant update
+// request, if any.
+func validateRecaptchaConfig(req map[string]interface{}) error {
+	val, ok := req[recaptchaConfigKey]
+	if !ok {
+		return nil
+	}
+	recaptchaConfig, ok := val.(RecaptchaConfig)
+	if !ok {
+		return fmt.Errorf("invalid type for RecaptchaConfig: %v", val)
+	}
+	return recaptchaConfig.validate()
+}
+
+func (s RecaptchaEnforcementState) validate() error {
+	switch s {
+	case "", RecaptchaOff, RecaptchaAudit, RecaptchaEnforce:
+		return nil
+	default:
+		return fmt.Errorf("must be 'OFF', 'AUDIT' or 'ENFORCE'; got %q", string(s))
+	}
+}
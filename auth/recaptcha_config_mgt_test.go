@@ -0,0 +1,148 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRecaptchaConfig(t *testing.T) {
+	cases := []RecaptchaConfig{
+		{},
+		{EmailPasswordEnforcementState: RecaptchaOff},
+		{PhoneEnforcementState: RecaptchaAudit},
+		{EmailPasswordEnforcementState: RecaptchaEnforce, PhoneEnforcementState: RecaptchaAudit},
+	}
+	for i, rc := range cases {
+		if err := rc.validate(); err != nil {
+			t.Errorf("[%d] RecaptchaConfig.validate() = %v; want = nil", i, err)
+		}
+	}
+}
+
+func TestRecaptchaConfigInvalidState(t *testing.T) {
+	cases := []struct {
+		config RecaptchaConfig
+		want   string
+	}{
+		{
+			RecaptchaConfig{EmailPasswordEnforcementState: "enforce"},
+			`"EmailPasswordEnforcementState" must be 'OFF', 'AUDIT' or 'ENFORCE'; got "enforce"`,
+		},
+		{
+			RecaptchaConfig{PhoneEnforcementState: "ON"},
+			`"PhoneEnforcementState" must be 'OFF', 'AUDIT' or 'ENFORCE'; got "ON"`,
+		},
+	}
+	for _, tc := range cases {
+		if err := tc.config.validate(); err == nil || err.Error() != tc.want {
+			t.Errorf("RecaptchaConfig.validate() = %v; want = %q", err, tc.want)
+		}
+	}
+}
+
+func TestGetProjectConfigWithRecaptcha(t *testing.T) {
+	resp := `{
+		"recaptchaConfig": {
+			"emailPasswordEnforcementState": "AUDIT",
+			"phoneEnforcementState": "ENFORCE"
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	projectConfig, err := s.Client.GetProjectConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ProjectConfig{
+		RecaptchaConfig: &RecaptchaConfig{
+			EmailPasswordEnforcementState: RecaptchaAudit,
+			PhoneEnforcementState:         RecaptchaEnforce,
+		},
+	}
+	if !reflect.DeepEqual(projectConfig, want) {
+		t.Errorf("GetProjectConfig() = %#v, want = %#v", projectConfig, want)
+	}
+}
+
+func TestUpdateProjectConfigRecaptcha(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	options := (&ProjectConfigToUpdate{}).RecaptchaConfig(RecaptchaConfig{
+		EmailPasswordEnforcementState: RecaptchaEnforce,
+		PhoneEnforcementState:         RecaptchaOff,
+	})
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody := map[string]interface{}{
+		"recaptchaConfig": map[string]interface{}{
+			"emailPasswordEnforcementState": "ENFORCE",
+			"phoneEnforcementState":         "OFF",
+		},
+	}
+	if err := checkUpdateProjectConfigRequest(s, wantBody, []string{"recaptchaConfig"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateTenantRecaptcha(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	options := (&TenantToUpdate{}).RecaptchaConfig(RecaptchaConfig{
+		EmailPasswordEnforcementState: RecaptchaAudit,
+	})
+	if _, err := s.Client.TenantManager.UpdateTenant(context.Background(), "tenantID", options); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody := map[string]interface{}{
+		"recaptchaConfig": map[string]interface{}{
+			"emailPasswordEnforcementState": "AUDIT",
+		},
+	}
+	if err := checkUpdateTenantRequest(s, wantBody, []string{"recaptchaConfig"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRecaptchaConfigInvalid(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	invalid := RecaptchaConfig{PhoneEnforcementState: "BLOCK"}
+	want := `"PhoneEnforcementState" must be 'OFF', 'AUDIT' or 'ENFORCE'; got "BLOCK"`
+	project := (&ProjectConfigToUpdate{}).RecaptchaConfig(invalid)
+	if _, err := s.Client.UpdateProjectConfig(context.Background(), project); err == nil || err.Error() != want {
+		t.Errorf("UpdateProjectConfig() = %v; want = %q", err, want)
+	}
+	update := (&TenantToUpdate{}).RecaptchaConfig(invalid)
+	if _, err := s.Client.TenantManager.UpdateTenant(context.Background(), "tenantID", update); err == nil || err.Error() != want {
+		t.Errorf("UpdateTenant() = %v; want = %q", err, want)
+	}
+	create := (&TenantToCreate{}).RecaptchaConfig(invalid)
+	if _, err := s.Client.TenantManager.CreateTenant(context.Background(), create); err == nil || err.Error() != want {
+		t.Errorf("CreateTenant() = %v; want = %q", err, want)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("requests = %d; want = 0", len(s.Req))
+	}
+}
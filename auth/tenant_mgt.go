@@ -42,6 +42,10 @@ import (
 //
 // All other settings of a tenant will also be inherited. These will need to be managed from the
 // Cloud Console UI.
+//
+// DisableAuth and TestPhoneNumbers are read-only here, and reflect the settings managed from the
+// Cloud Console UI. TestPhoneNumbers maps the fictional phone numbers of the tenant to their
+// verification codes.
 type Tenant struct {
 	ID                    string             `json:"name"`
 	DisplayName           string             `json:"displayName"`
@@ -49,6 +53,11 @@ type Tenant struct {
 	EnableEmailLinkSignIn bool               `json:"enableEmailLinkSignin"`
 	EnableAnonymousUsers  bool               `json:"enableAnonymousUser"`
 	MultiFactorConfig     *MultiFactorConfig `json:"mfaConfig"`
+	DisableAuth           bool               `json:"disableAuth"`
+	TestPhoneNumbers      map[string]string  `json:"testPhoneNumbers"`
+	RecaptchaConfig       *RecaptchaConfig   `json:"recaptchaConfig,omitempty"`
+
+	PasswordPolicyConfig *PasswordPolicyConfig `json:"passwordPolicyConfig,omitempty"`
 }
 
 // TenantClient is used for managing users, configuring SAML/OIDC providers, and generating email
@@ -155,7 +164,7 @@ func (tm *TenantManager) UpdateTenant(ctx context.Context, tenantID string, tena
 	if err := tenant.validate(); err != nil {
 		return nil, err
 	}
-	mask := tenant.params.UpdateMask()
+	mask := append(tenant.params.UpdateMask(), tenant.cleared...)
 	if len(mask) == 0 {
 		return nil, errors.New("no parameters specified in the update request")
 	}
@@ -191,6 +200,155 @@ func (tm *TenantManager) DeleteTenant(ctx context.Context, tenantID string) erro
 	return err
 }
 
+// DeleteTenantOptions specifies additional safety checks performed by DeleteTenantWithOptions.
+type DeleteTenantOptions struct {
+	// RequireEmpty causes the deletion to fail with a tenant-not-empty error if the tenant still
+	// has one or more user accounts. Use IsTenantNotEmpty() to check for this error.
+	RequireEmpty bool
+}
+
+// DeleteTenantWithOptions deletes the tenant with the given ID, subject to the checks specified in
+// opts. If opts is nil, this behaves the same as DeleteTenant.
+//
+// The emptiness check and the deletion are not atomic. Users created in the tenant after the check
+// has completed are deleted along with the tenant.
+func (tm *TenantManager) DeleteTenantWithOptions(
+	ctx context.Context, tenantID string, opts *DeleteTenantOptions) error {
+	if tenantID == "" {
+		return errors.New("tenantID must not be empty")
+	}
+
+	if opts != nil && opts.RequireEmpty {
+		if err := tm.checkTenantEmpty(ctx, tenantID); err != nil {
+			return err
+		}
+	}
+
+	return tm.DeleteTenant(ctx, tenantID)
+}
+
+func (tm *TenantManager) checkTenantEmpty(ctx context.Context, tenantID string) error {
+	client, err := tm.AuthForTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	it := client.Users(ctx, "")
+	it.PageInfo().MaxSize = 1
+	if _, err := it.Next(); err != iterator.Done {
+		if err != nil {
+			return err
+		}
+		return &internal.FirebaseError{
+			ErrorCode: internal.FailedPrecondition,
+			String:    fmt.Sprintf("tenant %q is not empty", tenantID),
+			Ext: map[string]interface{}{
+				authErrorCode: tenantNotEmpty,
+			},
+		}
+	}
+	return nil
+}
+
+// TenantConfig is a snapshot of the settings of a tenant, which can be serialized to JSON to back
+// up a tenant, and restored in the same or another project.
+//
+// The snapshot covers the tenant-level settings: display name, sign-in methods, multi-factor,
+// reCAPTCHA and password policy configurations. It does not include the users or the OIDC/SAML
+// provider configurations of the tenant, which must be exported separately via a TenantClient.
+type TenantConfig struct {
+	DisplayName           string             `json:"displayName"`
+	AllowPasswordSignUp   bool               `json:"allowPasswordSignup"`
+	EnableEmailLinkSignIn bool               `json:"enableEmailLinkSignin"`
+	EnableAnonymousUsers  bool               `json:"enableAnonymousUser"`
+	MultiFactorConfig     *MultiFactorConfig `json:"mfaConfig,omitempty"`
+	RecaptchaConfig       *RecaptchaConfig   `json:"recaptchaConfig,omitempty"`
+
+	PasswordPolicyConfig *PasswordPolicyConfig `json:"passwordPolicyConfig,omitempty"`
+}
+
+// ExportTenant returns a snapshot of the settings of the tenant with the given ID.
+func (tm *TenantManager) ExportTenant(ctx context.Context, tenantID string) (*TenantConfig, error) {
+	tenant, err := tm.Tenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TenantConfig{
+		DisplayName:           tenant.DisplayName,
+		AllowPasswordSignUp:   tenant.AllowPasswordSignUp,
+		EnableEmailLinkSignIn: tenant.EnableEmailLinkSignIn,
+		EnableAnonymousUsers:  tenant.EnableAnonymousUsers,
+		MultiFactorConfig:     tenant.MultiFactorConfig,
+		RecaptchaConfig:       tenant.RecaptchaConfig,
+		PasswordPolicyConfig:  tenant.PasswordPolicyConfig,
+	}, nil
+}
+
+// ImportTenant creates a new tenant with the settings in the given snapshot.
+//
+// Tenant IDs are assigned by the backend, so the new tenant has a different ID from the tenant
+// the snapshot was exported from. Use ApplyTenantConfig to restore the settings of an existing
+// tenant instead.
+func (tm *TenantManager) ImportTenant(ctx context.Context, config *TenantConfig) (*Tenant, error) {
+	if config == nil {
+		return nil, errors.New("tenant config must not be nil")
+	}
+
+	tenant := (&TenantToCreate{}).
+		DisplayName(config.DisplayName).
+		AllowPasswordSignUp(config.AllowPasswordSignUp).
+		EnableEmailLinkSignIn(config.EnableEmailLinkSignIn).
+		EnableAnonymousUsers(config.EnableAnonymousUsers)
+	if config.hasMultiFactorConfig() {
+		tenant.MultiFactorConfig(*config.MultiFactorConfig)
+	}
+	if config.RecaptchaConfig != nil {
+		tenant.RecaptchaConfig(*config.RecaptchaConfig)
+	}
+	if config.PasswordPolicyConfig != nil {
+		tenant.PasswordPolicyConfig(*config.PasswordPolicyConfig)
+	}
+	return tm.CreateTenant(ctx, tenant)
+}
+
+// ApplyTenantConfig overwrites the settings of the tenant with the given ID with the settings in
+// the given snapshot.
+//
+// The multi-factor, reCAPTCHA and password policy configurations that are absent from the snapshot
+// are cleared on the tenant, so that its settings match the snapshot.
+func (tm *TenantManager) ApplyTenantConfig(ctx context.Context, tenantID string, config *TenantConfig) (*Tenant, error) {
+	if config == nil {
+		return nil, errors.New("tenant config must not be nil")
+	}
+
+	tenant := (&TenantToUpdate{}).
+		DisplayName(config.DisplayName).
+		AllowPasswordSignUp(config.AllowPasswordSignUp).
+		EnableEmailLinkSignIn(config.EnableEmailLinkSignIn).
+		EnableAnonymousUsers(config.EnableAnonymousUsers)
+	if config.hasMultiFactorConfig() {
+		tenant.MultiFactorConfig(*config.MultiFactorConfig)
+	} else {
+		tenant.clear(multiFactorConfigTenantKey)
+	}
+	if config.RecaptchaConfig != nil {
+		tenant.RecaptchaConfig(*config.RecaptchaConfig)
+	} else {
+		tenant.clear(recaptchaConfigKey)
+	}
+	if config.PasswordPolicyConfig != nil {
+		tenant.PasswordPolicyConfig(*config.PasswordPolicyConfig)
+	} else {
+		tenant.clear(passwordPolicyConfigKey)
+	}
+	return tm.UpdateTenant(ctx, tenantID, tenant)
+}
+
+func (c *TenantConfig) hasMultiFactorConfig() bool {
+	return !c.MultiFactorConfig.isEmpty()
+}
+
 // Tenants returns an iterator over tenants in the project.
 //
 // If nextPageToken is empty, the iterator will start at the beginning. Otherwise,
@@ -209,6 +367,27 @@ func (tm *TenantManager) Tenants(ctx context.Context, nextPageToken string) *Ten
 	return it
 }
 
+// TenantCount returns the number of tenants in the project.
+//
+// The Identity Toolkit API does not report the total number of tenants, nor the tenant quota of
+// the project. Hence TenantCount pages through all the tenants, which takes one RPC call per
+// page of up to 100 tenants. Tenants created or deleted while the count is in progress may or may
+// not be counted.
+func (tm *TenantManager) TenantCount(ctx context.Context) (int, error) {
+	var count int
+	it := tm.Tenants(ctx, "")
+	for {
+		_, err := it.Next()
+		if err == iterator.Done {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
 func (tm *TenantManager) makeRequest(ctx context.Context, req *internal.Request, v interface{}) (*internal.Response, error) {
 	if tm.projectID == "" {
 		return nil, errors.New("project id not available")
@@ -258,6 +437,16 @@ func (t *TenantToCreate) MultiFactorConfig(multiFactorConfig MultiFactorConfig)
 	return t.set(multiFactorConfigTenantKey, multiFactorConfig)
 }
 
+// RecaptchaConfig configures the tenant's reCAPTCHA Enterprise settings.
+func (t *TenantToCreate) RecaptchaConfig(recaptchaConfig RecaptchaConfig) *TenantToCreate {
+	return t.set(recaptchaConfigKey, recaptchaConfig)
+}
+
+// PasswordPolicyConfig configures the tenant's password policy.
+func (t *TenantToCreate) PasswordPolicyConfig(passwordPolicyConfig PasswordPolicyConfig) *TenantToCreate {
+	return t.set(passwordPolicyConfigKey, passwordPolicyConfig)
+}
+
 func (t *TenantToCreate) set(key string, value interface{}) *TenantToCreate {
 	t.ensureParams().Set(key, value)
 	return t
@@ -285,12 +474,16 @@ func (t *TenantToCreate) validate() error {
 			return err
 		}
 	}
-	return nil
+	if err := validateRecaptchaConfig(req); err != nil {
+		return err
+	}
+	return validatePasswordPolicyConfig(req)
 }
 
 // TenantToUpdate represents the options used to update an existing tenant.
 type TenantToUpdate struct {
-	params nestedMap
+	params  nestedMap
+	cleared []string
 }
 
 // DisplayName sets the display name of the new tenant.
@@ -320,6 +513,16 @@ func (t *TenantToUpdate) MultiFactorConfig(multiFactorConfig MultiFactorConfig)
 	return t.set(multiFactorConfigTenantKey, multiFactorConfig)
 }
 
+// RecaptchaConfig configures the tenant's reCAPTCHA Enterprise settings.
+func (t *TenantToUpdate) RecaptchaConfig(recaptchaConfig RecaptchaConfig) *TenantToUpdate {
+	return t.set(recaptchaConfigKey, recaptchaConfig)
+}
+
+// PasswordPolicyConfig configures the tenant's password policy.
+func (t *TenantToUpdate) PasswordPolicyConfig(passwordPolicyConfig PasswordPolicyConfig) *TenantToUpdate {
+	return t.set(passwordPolicyConfigKey, passwordPolicyConfig)
+}
+
 func (t *TenantToUpdate) set(key string, value interface{}) *TenantToUpdate {
 	if t.params == nil {
 		t.params = make(nestedMap)
@@ -329,6 +532,13 @@ func (t *TenantToUpdate) set(key string, value interface{}) *TenantToUpdate {
 	return t
 }
 
+// clear resets the given setting of the tenant, by including it in the update mask without
+// specifying a value for it.
+func (t *TenantToUpdate) clear(key string) *TenantToUpdate {
+	t.cleared = append(t.cleared, key)
+	return t
+}
+
 func (t *TenantToUpdate) validate() error {
 	req := make(map[string]interface{})
 	for k, v := range t.params {
@@ -344,7 +554,10 @@ func (t *TenantToUpdate) validate() error {
 			return err
 		}
 	}
-	return nil
+	if err := validateRecaptchaConfig(req); err != nil {
+		return err
+	}
+	return validatePasswordPolicyConfig(req)
 }
 
 // TenantIterator is an iterator over tenants.
@@ -1183,6 +1183,38 @@ func TestTenant(t *testing.T) {
 	}
 }
 
+func TestTenantReadOnlySettings(t *testing.T) {
+	resp := `{
+		"name": "projects/mock-project-id/tenants/tenantID",
+		"displayName": "Test Tenant",
+		"allowPasswordSignup": true,
+		"disableAuth": true,
+		"testPhoneNumbers": {
+			"+16505550101": "123456"
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	tenant, err := s.Client.TenantManager.Tenant(context.Background(), "tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Tenant{
+		ID:                  "tenantID",
+		DisplayName:         "Test Tenant",
+		AllowPasswordSignUp: true,
+		DisableAuth:         true,
+		TestPhoneNumbers: map[string]string{
+			"+16505550101": "123456",
+		},
+	}
+	if !reflect.DeepEqual(tenant, want) {
+		t.Errorf("Tenant() = %#v; want = %#v", tenant, want)
+	}
+}
+
 func TestTenantEmptyID(t *testing.T) {
 	tm := &TenantManager{}
 	wantErr := "tenantID must not be empty"
@@ -1254,6 +1286,30 @@ func TestCreateTenant(t *testing.T) {
 	}
 }
 
+func TestCreateTenantWithSMSMultiFactor(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	options := (&TenantToCreate{}).
+		MultiFactorConfig(MultiFactorConfig{
+			State:            Enabled,
+			EnabledProviders: []string{PhoneSMSProvider},
+		})
+	if _, err := s.Client.TenantManager.CreateTenant(context.Background(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBody := map[string]interface{}{
+		"mfaConfig": map[string]interface{}{
+			"state":            "ENABLED",
+			"enabledProviders": []interface{}{"PHONE_SMS"},
+		},
+	}
+	if err := checkCreateTenantRequest(s, wantBody); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestCreateTenantMinimal(t *testing.T) {
 	s := echoServer([]byte(tenantResponse), t)
 	defer s.Close()
@@ -1503,6 +1559,331 @@ func TestDeleteTenantError(t *testing.T) {
 	}
 }
 
+func TestDeleteTenantWithOptionsRequireEmpty(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	opts := &DeleteTenantOptions{RequireEmpty: true}
+	if err := s.Client.TenantManager.DeleteTenantWithOptions(context.Background(), "tenantID", opts); err != nil {
+		t.Fatalf("DeleteTenantWithOptions() = %v", err)
+	}
+
+	if len(s.Req) != 2 {
+		t.Fatalf("DeleteTenantWithOptions() requests = %d; want = 2", len(s.Req))
+	}
+
+	req := s.Req[0]
+	wantURL := "/projects/mock-project-id/tenants/tenantID/accounts:batchGet"
+	if req.Method != http.MethodGet || req.URL.Path != wantURL {
+		t.Errorf("DeleteTenantWithOptions() = %s %q; want = GET %q", req.Method, req.URL.Path, wantURL)
+	}
+	if got := req.URL.Query().Get("maxResults"); got != "1" {
+		t.Errorf("DeleteTenantWithOptions() maxResults = %q; want = %q", got, "1")
+	}
+
+	req = s.Req[1]
+	wantURL = "/projects/mock-project-id/tenants/tenantID"
+	if req.Method != http.MethodDelete || req.URL.Path != wantURL {
+		t.Errorf("DeleteTenantWithOptions() = %s %q; want = DELETE %q", req.Method, req.URL.Path, wantURL)
+	}
+}
+
+func TestDeleteTenantWithOptionsNotEmpty(t *testing.T) {
+	s := echoServer([]byte(`{"users": [{"localId": "testuser"}]}`), t)
+	defer s.Close()
+
+	opts := &DeleteTenantOptions{RequireEmpty: true}
+	err := s.Client.TenantManager.DeleteTenantWithOptions(context.Background(), "tenantID", opts)
+	if err == nil || !IsTenantNotEmpty(err) || !errorutils.IsFailedPrecondition(err) {
+		t.Fatalf("DeleteTenantWithOptions() = %v; want = TenantNotEmpty", err)
+	}
+	want := `tenant "tenantID" is not empty`
+	if err.Error() != want {
+		t.Errorf("DeleteTenantWithOptions() = %q; want = %q", err.Error(), want)
+	}
+
+	if len(s.Req) != 1 {
+		t.Errorf("DeleteTenantWithOptions() requests = %d; want = 1", len(s.Req))
+	}
+}
+
+func TestDeleteTenantWithOptionsNil(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	if err := s.Client.TenantManager.DeleteTenantWithOptions(context.Background(), "tenantID", nil); err != nil {
+		t.Fatalf("DeleteTenantWithOptions() = %v", err)
+	}
+
+	if len(s.Req) != 1 || s.Req[0].Method != http.MethodDelete {
+		t.Errorf("DeleteTenantWithOptions() requests = %v; want = single DELETE", s.Req)
+	}
+}
+
+func TestDeleteTenantWithOptionsEmptyID(t *testing.T) {
+	tm := &TenantManager{}
+	wantErr := "tenantID must not be empty"
+
+	err := tm.DeleteTenantWithOptions(context.Background(), "", &DeleteTenantOptions{RequireEmpty: true})
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("DeleteTenantWithOptions('') = %v; want = %q", err, wantErr)
+	}
+}
+
+var testTenantConfig = &TenantConfig{
+	DisplayName:           testTenant.DisplayName,
+	AllowPasswordSignUp:   testTenant.AllowPasswordSignUp,
+	EnableEmailLinkSignIn: testTenant.EnableEmailLinkSignIn,
+	EnableAnonymousUsers:  testTenant.EnableAnonymousUsers,
+	MultiFactorConfig:     testTenant.MultiFactorConfig,
+}
+
+var testTenantConfigBody = map[string]interface{}{
+	"displayName":           testTenant.DisplayName,
+	"allowPasswordSignup":   testTenant.AllowPasswordSignUp,
+	"enableEmailLinkSignin": testTenant.EnableEmailLinkSignIn,
+	"enableAnonymousUser":   testTenant.EnableAnonymousUsers,
+	"mfaConfig": map[string]interface{}{
+		"providerConfigs": []interface{}{
+			map[string]interface{}{
+				"state": "ENABLED",
+				"totpProviderConfig": map[string]interface{}{
+					"adjacentIntervals": float64(5),
+				},
+			},
+		},
+	},
+}
+
+func TestExportTenant(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	config, err := s.Client.TenantManager.ExportTenant(context.Background(), "tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, testTenantConfig) {
+		t.Errorf("ExportTenant() = %#v; want = %#v", config, testTenantConfig)
+	}
+
+	// The snapshot must survive a round trip through JSON.
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored TenantConfig
+	if err := json.Unmarshal(b, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&restored, testTenantConfig) {
+		t.Errorf("ExportTenant() JSON round trip = %#v; want = %#v", restored, testTenantConfig)
+	}
+}
+
+func TestExportTenantError(t *testing.T) {
+	s := echoServer([]byte(tenantNotFoundResponse), t)
+	defer s.Close()
+	s.Status = http.StatusNotFound
+
+	config, err := s.Client.TenantManager.ExportTenant(context.Background(), "tenantID")
+	if config != nil || !IsTenantNotFound(err) {
+		t.Errorf("ExportTenant() = (%v, %v); want = (nil, TenantNotFound)", config, err)
+	}
+}
+
+func TestImportTenant(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	tenant, err := s.Client.TenantManager.ImportTenant(context.Background(), testTenantConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tenant, testTenant) {
+		t.Errorf("ImportTenant() = %#v; want = %#v", tenant, testTenant)
+	}
+	if err := checkCreateTenantRequest(s, testTenantConfigBody); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportTenantWithoutMultiFactorConfig(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	config := &TenantConfig{
+		DisplayName:       "Test Tenant",
+		MultiFactorConfig: &MultiFactorConfig{},
+	}
+	if _, err := s.Client.TenantManager.ImportTenant(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	wantBody := map[string]interface{}{
+		"displayName":           "Test Tenant",
+		"allowPasswordSignup":   false,
+		"enableEmailLinkSignin": false,
+		"enableAnonymousUser":   false,
+	}
+	if err := checkCreateTenantRequest(s, wantBody); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyTenantConfig(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	tenant, err := s.Client.TenantManager.ApplyTenantConfig(context.Background(), "tenantID", testTenantConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tenant, testTenant) {
+		t.Errorf("ApplyTenantConfig() = %#v; want = %#v", tenant, testTenant)
+	}
+	// The reCAPTCHA and password policy configs are absent from the snapshot, and hence cleared.
+	wantMask := []string{
+		"allowPasswordSignup", "displayName", "enableAnonymousUser", "enableEmailLinkSignin",
+		"mfaConfig", "passwordPolicyConfig", "recaptchaConfig",
+	}
+	if err := checkUpdateTenantRequest(s, testTenantConfigBody, wantMask); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyTenantConfigClearsAbsentSettings(t *testing.T) {
+	s := echoServer([]byte(tenantResponse), t)
+	defer s.Close()
+
+	config := &TenantConfig{DisplayName: "Test Tenant"}
+	if _, err := s.Client.TenantManager.ApplyTenantConfig(context.Background(), "tenantID", config); err != nil {
+		t.Fatal(err)
+	}
+	wantBody := map[string]interface{}{
+		"displayName":           "Test Tenant",
+		"allowPasswordSignup":   false,
+		"enableEmailLinkSignin": false,
+		"enableAnonymousUser":   false,
+	}
+	wantMask := []string{
+		"allowPasswordSignup", "displayName", "enableAnonymousUser", "enableEmailLinkSignin",
+		"mfaConfig", "passwordPolicyConfig", "recaptchaConfig",
+	}
+	if err := checkUpdateTenantRequest(s, wantBody, wantMask); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTenantConfigWithRecaptchaAndPasswordPolicy(t *testing.T) {
+	resp := `{
+		"name": "projects/mock-project-id/tenants/tenantID",
+		"displayName": "Test Tenant",
+		"recaptchaConfig": {
+			"emailPasswordEnforcementState": "ENFORCE",
+			"phoneEnforcementState": "AUDIT"
+		},
+		"passwordPolicyConfig": {
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"forceUpgradeOnSignin": true,
+			"passwordPolicyVersions": [
+				{"customStrengthOptions": {"minPasswordLength": 8, "containsNumericCharacter": true}}
+			]
+		}
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	tm := s.Client.TenantManager
+
+	config, err := tm.ExportTenant(context.Background(), "tenantID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &TenantConfig{
+		DisplayName: "Test Tenant",
+		RecaptchaConfig: &RecaptchaConfig{
+			EmailPasswordEnforcementState: RecaptchaEnforce,
+			PhoneEnforcementState:         RecaptchaAudit,
+		},
+		PasswordPolicyConfig: &PasswordPolicyConfig{
+			EnforcementState:     PasswordPolicyEnforce,
+			ForceUpgradeOnSignIn: true,
+			Constraints: &PasswordConstraints{
+				MinLength:      8,
+				RequireNumeric: true,
+			},
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("ExportTenant() = %#v; want = %#v", config, want)
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored TenantConfig
+	if err := json.Unmarshal(b, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&restored, want) {
+		t.Errorf("ExportTenant() JSON round trip = %#v; want = %#v", restored, want)
+	}
+
+	wantBody := map[string]interface{}{
+		"displayName":           "Test Tenant",
+		"allowPasswordSignup":   false,
+		"enableEmailLinkSignin": false,
+		"enableAnonymousUser":   false,
+		"recaptchaConfig": map[string]interface{}{
+			"emailPasswordEnforcementState": "ENFORCE",
+			"phoneEnforcementState":         "AUDIT",
+		},
+		"passwordPolicyConfig": map[string]interface{}{
+			"passwordPolicyEnforcementState": "ENFORCE",
+			"forceUpgradeOnSignin":           true,
+			"passwordPolicyVersions": []interface{}{
+				map[string]interface{}{
+					"customStrengthOptions": map[string]interface{}{
+						"minPasswordLength":        float64(8),
+						"containsNumericCharacter": true,
+					},
+				},
+			},
+		},
+	}
+
+	s.Req = nil
+	if _, err := tm.ImportTenant(context.Background(), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCreateTenantRequest(s, wantBody); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Req = nil
+	if _, err := tm.ApplyTenantConfig(context.Background(), "tenantID", &restored); err != nil {
+		t.Fatal(err)
+	}
+	wantMask := []string{
+		"allowPasswordSignup", "displayName", "enableAnonymousUser", "enableEmailLinkSignin",
+		"mfaConfig", "passwordPolicyConfig", "recaptchaConfig",
+	}
+	if err := checkUpdateTenantRequest(s, wantBody, wantMask); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportTenantNilConfig(t *testing.T) {
+	tm := &TenantManager{}
+	want := "tenant config must not be nil"
+	if tenant, err := tm.ImportTenant(context.Background(), nil); tenant != nil || err == nil || err.Error() != want {
+		t.Errorf("ImportTenant(nil) = (%v, %v); want = (nil, %q)", tenant, err, want)
+	}
+	if tenant, err := tm.ApplyTenantConfig(context.Background(), "tenantID", nil); tenant != nil || err == nil || err.Error() != want {
+		t.Errorf("ApplyTenantConfig(nil) = (%v, %v); want = (nil, %q)", tenant, err, want)
+	}
+}
+
 func TestTenants(t *testing.T) {
 	template := `{
                 "tenants": [
@@ -1582,6 +1963,35 @@ func TestTenantsError(t *testing.T) {
 	}
 }
 
+func TestTenantCount(t *testing.T) {
+	response := fmt.Sprintf(`{"tenants": [%s, %s]}`, tenantResponse, tenantResponse2)
+	s := echoServer([]byte(response), t)
+	defer s.Close()
+
+	count, err := s.Client.TenantManager.TenantCount(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("TenantCount() = %d; want = 2", count)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("TenantCount() = %d requests; want = 1", len(s.Req))
+	}
+}
+
+func TestTenantCountError(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+	s.Client.TenantManager.httpClient.RetryConfig = nil
+
+	count, err := s.Client.TenantManager.TenantCount(context.Background())
+	if count != 0 || !errorutils.IsInternal(err) {
+		t.Errorf("TenantCount() = (%d, %v); want = (0, %q)", count, err, "internal-error")
+	}
+}
+
 func checkCreateTenantRequest(s *mockAuthServer, wantBody interface{}) error {
 	req := s.Req[0]
 	if req.Method != http.MethodPost {
@@ -175,6 +175,11 @@ func newIAMSigner(ctx context.Context, config *internal.AuthConfig) (*iamSigner,
 	if err != nil {
 		return nil, err
 	}
+	config.Tracker.TrackHTTPClient(hc.Client)
+	hc.Opts = []internal.HTTPOption{
+		internal.WithClientVersion(config.Version),
+	}
+	hc.MaxResponseSize = config.MaxResponseSize
 
 	return &iamSigner{
 		mutex:        &sync.Mutex{},
@@ -122,6 +122,7 @@ func TestIAMSigner(t *testing.T) {
 	conf := &internal.AuthConfig{
 		Opts:             optsWithTokenSource,
 		ServiceAccountID: "test-service-account",
+		Version:          "test.version",
 	}
 	signer, err := newIAMSigner(ctx, conf)
 	if err != nil {
@@ -140,6 +141,12 @@ func TestIAMSigner(t *testing.T) {
 	wantSignature := "test-signature"
 	server := iamServer(t, email, wantSignature)
 	defer server.Close()
+	var clientVersion string
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientVersion = r.Header.Get("X-Client-Version")
+		handler.ServeHTTP(w, r)
+	})
 	signer.iamHost = server.URL
 
 	signature, err := signer.Sign(ctx, []byte("input"))
@@ -149,6 +156,9 @@ func TestIAMSigner(t *testing.T) {
 	if string(signature) != wantSignature {
 		t.Errorf("Sign() = %q; want = %q", string(signature), wantSignature)
 	}
+	if want := "Go/Admin/test.version"; clientVersion != want {
+		t.Errorf("X-Client-Version = %q; want = %q", clientVersion, want)
+	}
 }
 
 func TestIAMSignerHTTPError(t *testing.T) {
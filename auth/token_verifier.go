@@ -27,7 +27,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,11 +46,13 @@ const (
 	sessionCookieCertURL      = "https://www.googleapis.com/identitytoolkit/v3/relyingparty/publicKeys"
 	sessionCookieIssuerPrefix = "https://session.firebase.google.com/"
 	clockSkewSeconds          = 300
+	maxClockSkew              = 10 * time.Minute
 	certificateFetchFailed    = "CERTIFICATE_FETCH_FAILED"
 	idTokenExpired            = "ID_TOKEN_EXPIRED"
 	idTokenInvalid            = "ID_TOKEN_INVALID"
 	sessionCookieExpired      = "SESSION_COOKIE_EXPIRED"
 	sessionCookieInvalid      = "SESSION_COOKIE_INVALID"
+	sessionCookieKeyExpired   = "SESSION_COOKIE_SIGNING_KEY_EXPIRED"
 )
 
 // IsCertificateFetchFailed checks if the given error was caused by a failure to fetch public key
@@ -85,7 +89,18 @@ func IsSessionCookieExpired(err error) bool {
 // expired or revoked.
 func IsSessionCookieInvalid(err error) bool {
 	return hasAuthErrorCode(err, sessionCookieInvalid) || IsSessionCookieExpired(err) ||
-		IsSessionCookieRevoked(err) || IsUserDisabled(err)
+		IsSessionCookieRevoked(err) || IsUserDisabled(err) || IsSessionCookieSigningKeyExpired(err)
+}
+
+// IsSessionCookieSigningKeyExpired checks if the given error was due to a session cookie signed
+// with a key that Firebase Auth no longer publishes.
+//
+// Session cookies may be valid for up to two weeks, and can therefore outlive the rotation of the
+// key they were signed with. Such cookies cannot be verified anymore, and the user must sign in
+// again to obtain a new one. When IsSessionCookieSigningKeyExpired returns true,
+// IsSessionCookieInvalid is guaranteed to return true.
+func IsSessionCookieSigningKeyExpired(err error) bool {
+	return hasAuthErrorCode(err, sessionCookieKeyExpired)
 }
 
 // tokenVerifier verifies different types of Firebase token strings, including ID tokens and
@@ -95,11 +110,20 @@ type tokenVerifier struct {
 	articledShortName string
 	docURL            string
 	projectID         string
+	audiences         []string
 	issuerPrefix      string
 	invalidTokenCode  string
 	expiredTokenCode  string
-	keySource         keySource
-	clock             internal.Clock
+	// unknownKeyCode, if set, is the error code reported for tokens whose key ID (kid) is not
+	// among the public keys, instead of invalidTokenCode.
+	unknownKeyCode string
+	keySource      keySource
+	clock          internal.Clock
+	clockSkew      time.Duration
+
+	// strictEmulatorProjectID enables the project ID checks of the iss and aud claims for tokens
+	// verified in emulator mode, which are skipped by default.
+	strictEmulatorProjectID bool
 }
 
 func newIDTokenVerifier(ctx context.Context, projectID string) (*tokenVerifier, error) {
@@ -135,18 +159,49 @@ func newSessionCookieVerifier(ctx context.Context, projectID string) (*tokenVeri
 		issuerPrefix:      sessionCookieIssuerPrefix,
 		invalidTokenCode:  sessionCookieInvalid,
 		expiredTokenCode:  sessionCookieExpired,
+		unknownKeyCode:    sessionCookieKeyExpired,
 		keySource:         newHTTPKeySource(sessionCookieCertURL, noAuthHTTPClient),
 		clock:             internal.SystemClock,
 	}, nil
 }
 
+// withAudiences returns a copy of the tokenVerifier that accepts any of the given audiences instead
+// of the project ID. The copy shares the key source of the original.
+func (tv *tokenVerifier) withAudiences(audiences []string) *tokenVerifier {
+	cp := *tv
+	cp.audiences = audiences
+	return &cp
+}
+
+// withClockSkew returns a copy of the tokenVerifier that tolerates the given clock skew when
+// checking the iat and exp claims, capped at maxClockSkew. The copy shares the key source of the
+// original.
+func (tv *tokenVerifier) withClockSkew(skew time.Duration) *tokenVerifier {
+	if skew > maxClockSkew {
+		skew = maxClockSkew
+	}
+	cp := *tv
+	cp.clockSkew = skew
+	return &cp
+}
+
+// leewaySeconds returns the clock skew tolerated by the tokenVerifier, in seconds.
+func (tv *tokenVerifier) leewaySeconds() int64 {
+	if tv.clockSkew == 0 {
+		return clockSkewSeconds
+	}
+	return int64(tv.clockSkew / time.Second)
+}
+
 // VerifyToken Verifies that the given token string is a valid Firebase JWT.
 //
 // VerifyToken considers a token string to be valid if all the following conditions are met:
 //   - The token string is a valid RS256 JWT.
 //   - The JWT contains a valid key ID (kid) claim.
 //   - The JWT contains valid issuer (iss) and audience (aud) claims that match the issuerPrefix
-//     and projectID of the tokenVerifier.
+//     and projectID of the tokenVerifier. If the tokenVerifier has a set of audiences, the
+//     audience must match one of them instead. In emulator mode the claims are not compared to
+//     the project ID, unless strictEmulatorProjectID is set.
 //   - The JWT contains a valid subject (sub) claim.
 //   - The JWT is not expired, and it has been issued some time in the past.
 //   - The JWT is signed by a Firebase Auth backend server as determined by the keySource.
@@ -207,7 +262,8 @@ func (tv *tokenVerifier) verifyContent(token string, isEmulator bool) (*Token, e
 }
 
 func (tv *tokenVerifier) verifyTimestamps(payload *Token) error {
-	if (payload.IssuedAt - clockSkewSeconds) > tv.clock.Now().Unix() {
+	leeway := tv.leewaySeconds()
+	if (payload.IssuedAt - leeway) > tv.clock.Now().Unix() {
 		return &internal.FirebaseError{
 			ErrorCode: internal.InvalidArgument,
 			String:    fmt.Sprintf("%s issued at future timestamp: %d", tv.shortName, payload.IssuedAt),
@@ -215,7 +271,7 @@ func (tv *tokenVerifier) verifyTimestamps(payload *Token) error {
 		}
 	}
 
-	if (payload.Expires + clockSkewSeconds) < tv.clock.Now().Unix() {
+	if (payload.Expires + leeway) < tv.clock.Now().Unix() {
 		return &internal.FirebaseError{
 			ErrorCode: internal.InvalidArgument,
 			String:    fmt.Sprintf("%s has expired at: %d", tv.shortName, payload.Expires),
@@ -236,7 +292,16 @@ func (tv *tokenVerifier) verifySignature(ctx context.Context, token string) erro
 		}
 	}
 
-	if !tv.verifySignatureWithKeys(ctx, token, keys) {
+	if !verifySignatureWithKeys(token, keys) {
+		if kid := keyID(token); tv.unknownKeyCode != "" && kid != "" && !hasKeyID(keys, kid) {
+			return &internal.FirebaseError{
+				ErrorCode: internal.InvalidArgument,
+				String: fmt.Sprintf(
+					"%s was signed with key %q, which is no longer published; the user must sign in again",
+					tv.shortName, kid),
+				Ext: map[string]interface{}{authErrorCode: tv.unknownKeyCode},
+			}
+		}
 		return &internal.FirebaseError{
 			ErrorCode: internal.InvalidArgument,
 			String:    "failed to verify token signature",
@@ -247,12 +312,25 @@ func (tv *tokenVerifier) verifySignature(ctx context.Context, token string) erro
 	return nil
 }
 
-func (tv *tokenVerifier) verifyHeaderAndBody(token string, isEmulator bool) (*Token, error) {
-	var (
-		header  jwtHeader
-		payload Token
-	)
+// keyID returns the key ID (kid) in the header of the given JWT, or an empty string if the header
+// cannot be decoded.
+func keyID(token string) string {
+	var h jwtHeader
+	decode(strings.Split(token, ".")[0], &h)
+	return h.KeyID
+}
+
+func hasKeyID(keys []*publicKey, kid string) bool {
+	for _, k := range keys {
+		if k.Kid == kid {
+			return true
+		}
+	}
+	return false
+}
 
+func (tv *tokenVerifier) verifyHeaderAndBody(token string, isEmulator bool) (*Token, error) {
+	var header jwtHeader
 	segments := strings.Split(token, ".")
 	if len(segments) != 3 {
 		return nil, errors.New("incorrect number of segments")
@@ -262,7 +340,8 @@ func (tv *tokenVerifier) verifyHeaderAndBody(token string, isEmulator bool) (*To
 		return nil, err
 	}
 
-	if err := decode(segments[1], &payload); err != nil {
+	payload, err := decodeTokenPayload(segments[1])
+	if err != nil {
 		return nil, err
 	}
 
@@ -277,11 +356,17 @@ func (tv *tokenVerifier) verifyHeaderAndBody(token string, isEmulator bool) (*To
 		return nil, fmt.Errorf("%s has invalid algorithm; expected 'RS256' but got %q",
 			tv.shortName, header.Algorithm)
 	}
-	if payload.Audience != tv.projectID {
+	checkProjectID := !isEmulator || tv.strictEmulatorProjectID
+	if len(tv.audiences) > 0 {
+		if !tv.isAcceptedAudience(payload.Audience) {
+			return nil, fmt.Errorf("%s has invalid 'aud' (audience) claim; expected one of %q but got %q",
+				tv.shortName, tv.audiences, payload.Audience)
+		}
+	} else if checkProjectID && payload.Audience != tv.projectID {
 		return nil, fmt.Errorf("%s has invalid 'aud' (audience) claim; expected %q but got %q; %s",
 			tv.shortName, tv.projectID, payload.Audience, tv.getProjectIDMatchMessage())
 	}
-	if payload.Issuer != issuer {
+	if checkProjectID && payload.Issuer != issuer {
 		return nil, fmt.Errorf("%s has invalid 'iss' (issuer) claim; expected %q but got %q; %s",
 			tv.shortName, issuer, payload.Issuer, tv.getProjectIDMatchMessage())
 	}
@@ -293,10 +378,46 @@ func (tv *tokenVerifier) verifyHeaderAndBody(token string, isEmulator bool) (*To
 			tv.shortName)
 	}
 
+	return payload, nil
+}
+
+func (tv *tokenVerifier) isAcceptedAudience(aud string) bool {
+	for _, a := range tv.audiences {
+		if a == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeUnverified decodes the payload of the given JWT (e.g. an ID token or a session cookie)
+// without verifying it.
+//
+// No checks whatsoever are performed on the token: its signature, issuer, audience and expiry
+// are all ignored. The returned Token must therefore never be used for authorization decisions.
+// This function is only intended for debugging and logging purposes, such as inspecting a token
+// that failed verification. Use VerifyIDToken() or VerifySessionCookie() to obtain a trusted
+// Token.
+func DecodeUnverified(token string) (*Token, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, errors.New("incorrect number of segments")
+	}
+
+	return decodeTokenPayload(segments[1])
+}
+
+// decodeTokenPayload decodes a JWT payload segment into a Token, separating the custom claims
+// from the standard ones.
+func decodeTokenPayload(segment string) (*Token, error) {
+	var payload Token
+	if err := decode(segment, &payload); err != nil {
+		return nil, err
+	}
 	payload.UID = payload.Subject
 
 	var customClaims map[string]interface{}
-	if err := decode(segments[1], &customClaims); err != nil {
+	if err := decode(segment, &customClaims); err != nil {
 		return nil, err
 	}
 	for _, standardClaim := range []string{"iss", "aud", "exp", "iat", "sub", "uid"} {
@@ -307,7 +428,7 @@ func (tv *tokenVerifier) verifyHeaderAndBody(token string, isEmulator bool) (*To
 	return &payload, nil
 }
 
-func (tv *tokenVerifier) verifySignatureWithKeys(ctx context.Context, token string, keys []*publicKey) bool {
+func verifySignatureWithKeys(token string, keys []*publicKey) bool {
 	segments := strings.Split(token, ".")
 	var h jwtHeader
 	decode(segments[0], &h)
@@ -364,16 +485,38 @@ type keySource interface {
 	Keys(context.Context) ([]*publicKey, error)
 }
 
+// trackResources registers the HTTP client used to fetch public keys with the given tracker.
+func (tv *tokenVerifier) trackResources(t *internal.ResourceTracker) {
+	if ks, ok := tv.keySource.(*httpKeySource); ok {
+		t.TrackHTTPClient(ks.HTTPClient)
+	}
+}
+
+// limitResponseSize sets the maximum size of the responses read when fetching public keys.
+func (tv *tokenVerifier) limitResponseSize(limit int64) {
+	if ks, ok := tv.keySource.(*httpKeySource); ok {
+		ks.MaxResponseSize = limit
+	}
+}
+
 // httpKeySource fetches RSA public keys from a remote HTTP server, and caches them in
 // memory. It also handles cache! invalidation and refresh based on the standard HTTP
 // cache-control headers.
+//
+// The keys are parsed as a map of key IDs to PEM-encoded certificates, unless ParseKeys is set.
+// Responses without a max-age directive are rejected, unless DefaultMaxAge is set. Responses
+// larger than MaxResponseSize are rejected, or larger than internal.DefaultMaxResponseSize if it
+// is zero.
 type httpKeySource struct {
-	KeyURI     string
-	HTTPClient *http.Client
-	CachedKeys []*publicKey
-	ExpiryTime time.Time
-	Clock      internal.Clock
-	Mutex      *sync.Mutex
+	KeyURI          string
+	HTTPClient      *http.Client
+	CachedKeys      []*publicKey
+	ExpiryTime      time.Time
+	Clock           internal.Clock
+	Mutex           *sync.Mutex
+	ParseKeys       func([]byte) ([]*publicKey, error)
+	DefaultMaxAge   time.Duration
+	MaxResponseSize int64
 }
 
 func newHTTPKeySource(uri string, hc *http.Client) *httpKeySource {
@@ -417,7 +560,7 @@ func (k *httpKeySource) refreshKeys(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
-	contents, err := ioutil.ReadAll(resp.Body)
+	contents, err := internal.ReadResponseBody(resp.Body, k.MaxResponseSize)
 	if err != nil {
 		return err
 	}
@@ -427,14 +570,21 @@ func (k *httpKeySource) refreshKeys(ctx context.Context) error {
 			resp.StatusCode, string(contents))
 	}
 
-	newKeys, err := parsePublicKeys(contents)
+	parse := k.ParseKeys
+	if parse == nil {
+		parse = parsePublicKeys
+	}
+	newKeys, err := parse(contents)
 	if err != nil {
 		return err
 	}
 
 	maxAge, err := findMaxAge(resp)
 	if err != nil {
-		return err
+		if k.DefaultMaxAge == 0 {
+			return err
+		}
+		maxAge = &k.DefaultMaxAge
 	}
 
 	k.CachedKeys = append([]*publicKey(nil), newKeys...)
@@ -476,6 +626,97 @@ func parsePublicKey(kid string, key []byte) (*publicKey, error) {
 	return &publicKey{kid, pk}, nil
 }
 
+// jwksFileKeySource loads RSA public keys from a JSON Web Key Set (JWKS) file on the local file
+// system, and caches them in memory. The file is reloaded whenever its modification time changes.
+// If a reload fails, the previously loaded keys remain in use.
+type jwksFileKeySource struct {
+	Path       string
+	CachedKeys []*publicKey
+	ModTime    time.Time
+	Mutex      *sync.Mutex
+}
+
+func newJWKSFileKeySource(path string) *jwksFileKeySource {
+	return &jwksFileKeySource{
+		Path:  path,
+		Mutex: &sync.Mutex{},
+	}
+}
+
+// Keys returns the RSA public keys in the JWKS file. Reloads the file if it has been modified
+// since it was last read.
+func (k *jwksFileKeySource) Keys(ctx context.Context) ([]*publicKey, error) {
+	k.Mutex.Lock()
+	defer k.Mutex.Unlock()
+	info, err := os.Stat(k.Path)
+	if err == nil && (len(k.CachedKeys) == 0 || !info.ModTime().Equal(k.ModTime)) {
+		err = k.reloadKeys(info.ModTime())
+	}
+	if err != nil && len(k.CachedKeys) == 0 {
+		return nil, err
+	}
+	return k.CachedKeys, nil
+}
+
+func (k *jwksFileKeySource) reloadKeys(modTime time.Time) error {
+	contents, err := ioutil.ReadFile(k.Path)
+	if err != nil {
+		return err
+	}
+
+	newKeys, err := parseJWKS(contents)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS file %q: %v", k.Path, err)
+	}
+
+	k.CachedKeys = newKeys
+	k.ModTime = modTime
+	return nil
+}
+
+func parseJWKS(jwks []byte) ([]*publicKey, error) {
+	var keySet struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(jwks, &keySet); err != nil {
+		return nil, err
+	}
+
+	var result []*publicKey
+	for _, key := range keySet.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus in key %q: %v", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent in key %q: %v", key.Kid, err)
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA parameters in key %q", key.Kid)
+		}
+		result = append(result, &publicKey{
+			Kid: key.Kid,
+			Key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+		})
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no RSA keys found")
+	}
+	return result, nil
+}
+
 func findMaxAge(resp *http.Response) (*time.Duration, error) {
 	cc := resp.Header.Get("cache-control")
 	for _, value := range strings.Split(cc, ",") {
@@ -16,11 +16,16 @@ package auth
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -132,6 +137,20 @@ func TestHTTPKeySourceTransportError(t *testing.T) {
 	}
 }
 
+func TestHTTPKeySourceResponseTooLarge(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/public_certs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hc, _ := newTestHTTPClient(data)
+	ks := newHTTPKeySource("http://mock.url", hc)
+	ks.MaxResponseSize = int64(len(data) - 1)
+	if keys, err := ks.Keys(context.Background()); keys != nil || !internal.IsResponseTooLarge(err) {
+		t.Errorf("Keys() = (%v, %v); want = (nil, ResponseTooLarge)", keys, err)
+	}
+}
+
 func TestFindMaxAge(t *testing.T) {
 	cases := []struct {
 		cc   string
@@ -284,3 +303,164 @@ func verifyHTTPKeySource(ks *httpKeySource, rc *mockReadCloser) error {
 	}
 	return nil
 }
+
+func TestDecodeUnverified(t *testing.T) {
+	token := getIDToken(mockIDTokenPayload{
+		"aud": "other-project",
+		"exp": testClock.Now().Unix() - 7200,
+		"sub": "some-uid",
+	})
+
+	decoded, err := DecodeUnverified(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.UID != "some-uid" || decoded.Subject != "some-uid" {
+		t.Errorf("DecodeUnverified().UID = %q; want = %q", decoded.UID, "some-uid")
+	}
+	if decoded.Audience != "other-project" {
+		t.Errorf("DecodeUnverified().Audience = %q; want = %q", decoded.Audience, "other-project")
+	}
+	if want := testClock.Now().Unix() - 7200; decoded.Expires != want {
+		t.Errorf("DecodeUnverified().Expires = %d; want = %d", decoded.Expires, want)
+	}
+	if decoded.Firebase.SignInProvider != "custom" {
+		t.Errorf("DecodeUnverified().Firebase.SignInProvider = %q; want = %q", decoded.Firebase.SignInProvider, "custom")
+	}
+	if decoded.Claims["admin"] != true {
+		t.Errorf("DecodeUnverified().Claims[admin] = %v; want = true", decoded.Claims["admin"])
+	}
+	if _, ok := decoded.Claims["sub"]; ok {
+		t.Errorf("DecodeUnverified().Claims contains standard claim 'sub'")
+	}
+}
+
+func TestDecodeUnverifiedError(t *testing.T) {
+	cases := []string{"", "not.a.token", "only.two", "a.b.c.d"}
+	for _, tc := range cases {
+		if decoded, err := DecodeUnverified(tc); decoded != nil || err == nil {
+			t.Errorf("DecodeUnverified(%q) = (%v, %v); want = (nil, error)", tc, decoded, err)
+		}
+	}
+}
+
+func TestJWKSFileKeySource(t *testing.T) {
+	path := writeJWKSFile(t, "mock-key-id-1")
+	tv, err := newIDTokenVerifier(context.Background(), testProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tv.keySource = newJWKSFileKeySource(path)
+	tv.clock = testClock
+
+	token := getIDToken(nil)
+	ft, err := tv.VerifyToken(context.Background(), token, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ft.UID != "1234567890" {
+		t.Errorf("VerifyToken().UID = %q; want = %q", ft.UID, "1234567890")
+	}
+
+	// Rewrite the file without the signing key, and make sure the change is picked up.
+	writeJWKSFileAt(t, path, "other-key-id")
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tv.VerifyToken(context.Background(), token, false); err == nil {
+		t.Errorf("VerifyToken() after reload = nil; want = error")
+	}
+}
+
+func TestJWKSFileKeySourceKeepsKeysOnReloadError(t *testing.T) {
+	path := writeJWKSFile(t, "mock-key-id-1")
+	ks := newJWKSFileKeySource(path)
+	if _, err := ks.Keys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ks.Keys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Kid != "mock-key-id-1" {
+		t.Errorf("Keys() = %v; want = [mock-key-id-1]", keys)
+	}
+}
+
+func TestJWKSFileKeySourceError(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"NotJSON":    "not json",
+		"NoKeys":     `{"keys": []}`,
+		"NoRSAKey":   `{"keys": [{"kty": "EC", "kid": "ec-key"}]}`,
+		"BadModulus": `{"keys": [{"kty": "RSA", "kid": "k", "n": "!!!", "e": "AQAB"}]}`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name+".json")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if keys, err := newJWKSFileKeySource(path).Keys(context.Background()); keys != nil || err == nil {
+			t.Errorf("Keys(%s) = (%v, %v); want = (nil, error)", name, keys, err)
+		}
+	}
+
+	ks := newJWKSFileKeySource(filepath.Join(dir, "missing.json"))
+	if keys, err := ks.Keys(context.Background()); keys != nil || err == nil {
+		t.Errorf("Keys(missing) = (%v, %v); want = (nil, error)", keys, err)
+	}
+}
+
+func writeJWKSFile(t *testing.T, kid string) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKSFileAt(t, path, kid)
+	return path
+}
+
+// writeJWKSFileAt writes the public key of the mock signing key to a JWKS file, under the given
+// key ID.
+func writeJWKSFileAt(t *testing.T, path, kid string) {
+	if err := ioutil.WriteFile(path, mockJWKS(t, kid), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mockJWKS returns a JWKS containing the public key of the mock signing key, under the given key
+// ID.
+func mockJWKS(t *testing.T, kid string) []byte {
+	data, err := ioutil.ReadFile("../testdata/public_certs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := parsePublicKeys(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jwks []map[string]string
+	for _, k := range keys {
+		if k.Kid != "mock-key-id-1" {
+			continue
+		}
+		jwks = append(jwks, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(k.Key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Key.E)).Bytes()),
+		})
+	}
+	b, err := json.Marshal(map[string]interface{}{"keys": jwks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
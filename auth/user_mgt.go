@@ -21,7 +21,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -39,16 +42,29 @@ const (
 
 	// Maximum number of users allowed to batch delete at a time.
 	maxDeleteAccountsBatchSize = 1000
-	createUserMethod           = "createUser"
-	updateUserMethod           = "updateUser"
-	phoneMultiFactorID         = "phone"
-	totpMultiFactorID          = "totp"
+
+	// Maximum number of users allowed to batch create at a time.
+	maxCreateAccountsBatchSize = 1000
+
+	// Bounds of the duration of session cookies.
+	minSessionCookieDuration = 5 * time.Minute
+	maxSessionCookieDuration = 14 * 24 * time.Hour
+
+	createUserMethod   = "createUser"
+	updateUserMethod   = "updateUser"
+	phoneMultiFactorID = "phone"
+	totpMultiFactorID  = "totp"
 )
 
 // 'REDACTED', encoded as a base64 string.
 var b64Redacted = base64.StdEncoding.EncodeToString([]byte("REDACTED"))
 
 // UserInfo is a collection of standard profile information for a user.
+//
+// In the ProviderUserInfo of a UserRecord, FederatedID and ScreenName are set when reported by the
+// provider, and RawData holds all the fields of the provider info returned by the backend,
+// including those not exposed as typed fields. The backend does not report when each provider was
+// last used; UserMetadata.LastLogInTimestamp is the last sign-in time across all providers.
 type UserInfo struct {
 	DisplayName string `json:"displayName,omitempty"`
 	Email       string `json:"email,omitempty"`
@@ -57,8 +73,11 @@ type UserInfo struct {
 	// In the ProviderUserInfo[] ProviderID can be a short domain name (e.g. google.com),
 	// or the identity of an OpenID identity provider.
 	// In UserRecord.UserInfo it will return the constant string "firebase".
-	ProviderID string `json:"providerId,omitempty"`
-	UID        string `json:"rawId,omitempty"`
+	ProviderID  string                 `json:"providerId,omitempty"`
+	UID         string                 `json:"rawId,omitempty"`
+	FederatedID string                 `json:"federatedId,omitempty"`
+	ScreenName  string                 `json:"screenName,omitempty"`
+	RawData     map[string]interface{} `json:"-"`
 }
 
 // multiFactorInfoResponse describes the `mfaInfo` of the user record API response
@@ -81,6 +100,30 @@ type PhoneMultiFactorInfo struct {
 // TOTPMultiFactorInfo describes a user enrolled in TOTP second factor.
 type TOTPMultiFactorInfo struct{}
 
+// GenerateTOTPProvisioningURI returns the otpauth:// URI that authenticator apps use to register
+// a TOTP shared secret, typically presented to the user as a QR code.
+//
+// The secret must be the base32-encoded shared secret issued by the Firebase TOTP backend. The
+// URI specifies the SHA1 algorithm, 6 digits and a 30 second period, which are the parameters used
+// by the backend. The issuer is omitted from the URI if empty.
+func GenerateTOTPProvisioningURI(accountName, issuer, secret string) string {
+	// Colons separate the issuer from the account name in the label, and must be escaped.
+	escape := func(s string) string {
+		return strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+	}
+	label := escape(accountName)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", "6")
+	query.Set("period", "30")
+	if issuer != "" {
+		label = escape(issuer) + ":" + label
+		query.Set("issuer", issuer)
+	}
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
 type multiFactorEnrollments struct {
 	Enrollments []*multiFactorInfoResponse `json:"enrollments"`
 }
@@ -124,6 +167,33 @@ type UserRecord struct {
 	MultiFactor            *MultiFactorSettings
 }
 
+// DisabledReasonClaim is the custom claim in which UserToUpdate.DisableWithReason() records the
+// reason a user account was disabled.
+//
+// This is a convention of this SDK. The Firebase Auth service does not attach any meaning to it.
+const DisabledReasonClaim = "disabledReason"
+
+// DisabledReason returns the reason recorded by UserToUpdate.DisableWithReason(), or an empty
+// string if the user is not disabled or no reason was recorded.
+func (r *UserRecord) DisabledReason() string {
+	if !r.Disabled {
+		return ""
+	}
+	reason, _ := r.CustomClaims[DisabledReasonClaim].(string)
+	return reason
+}
+
+// ProviderInfo returns the identity linked to the user account by the given provider (e.g.
+// "google.com" or "apple.com"), or nil if the user has not linked that provider.
+func (r *UserRecord) ProviderInfo(providerID string) *UserInfo {
+	for _, info := range r.ProviderUserInfo {
+		if info != nil && info.ProviderID == providerID {
+			return info
+		}
+	}
+	return nil
+}
+
 // UserToCreate is the parameter struct for the CreateUser function.
 type UserToCreate struct {
 	params map[string]interface{}
@@ -253,7 +323,24 @@ func (u *UserToCreate) validatedRequest() (map[string]interface{}, error) {
 
 // UserToUpdate is the parameter struct for the UpdateUser function.
 type UserToUpdate struct {
-	params map[string]interface{}
+	params         map[string]interface{}
+	allowEmpty     bool
+	disabledReason *string
+	passwordHash   *passwordHashUpdate
+}
+
+type passwordHashUpdate struct {
+	hash      []byte
+	salt      []byte
+	algorithm UserImportHash
+}
+
+// AllowEmptyUpdate specifies whether UpdateUser should accept a UserToUpdate with no parameters set.
+// When enabled, such an update is treated as a no-op and UpdateUser simply returns the current
+// UserRecord. By default, updating a user without any parameters results in an error.
+func (u *UserToUpdate) AllowEmptyUpdate(allow bool) *UserToUpdate {
+	u.allowEmpty = allow
+	return u
 }
 
 // CustomClaims setter.
@@ -266,6 +353,17 @@ func (u *UserToUpdate) Disabled(disabled bool) *UserToUpdate {
 	return u.set("disableUser", disabled)
 }
 
+// DisableWithReason disables the user account, and records the given reason in the
+// DisabledReasonClaim custom claim.
+//
+// The reason is merged into the user's existing custom claims. If CustomClaims is not also set
+// on this UserToUpdate, UpdateUser fetches the current claims of the user before applying the
+// update. The recorded reason can be read back via UserRecord.DisabledReason().
+func (u *UserToUpdate) DisableWithReason(reason string) *UserToUpdate {
+	u.disabledReason = &reason
+	return u.set("disableUser", true)
+}
+
 // DisplayName setter. Set to empty string to remove the display name from the user account.
 func (u *UserToUpdate) DisplayName(name string) *UserToUpdate {
 	return u.set("displayName", name)
@@ -286,6 +384,24 @@ func (u *UserToUpdate) Password(pw string) *UserToUpdate {
 	return u.set("password", pw)
 }
 
+// PasswordHash sets the password of the user to a value that has already been hashed with the
+// given algorithm, along with the salt used (which may be nil). This allows migrating the
+// credentials of individual users from another system, for example when they first sign in.
+//
+// The Firebase Auth update API does not accept password hashes. Therefore, UpdateUser reads the
+// current account and re-imports it with the password hash, as ImportUsers() would. The two steps
+// are not atomic, and the account is overwritten by the import: changes made to it concurrently
+// may be lost. The password hash must not be combined with any other changes, such as Password or
+// DisplayName. Those must be made with a separate call to UpdateUser.
+func (u *UserToUpdate) PasswordHash(hash, salt []byte, algorithm UserImportHash) *UserToUpdate {
+	u.passwordHash = &passwordHashUpdate{
+		hash:      hash,
+		salt:      salt,
+		algorithm: algorithm,
+	}
+	return u
+}
+
 // PhoneNumber setter. Set to empty string to remove the phone number and the corresponding auth provider
 // from the user account.
 func (u *UserToUpdate) PhoneNumber(phone string) *UserToUpdate {
@@ -315,16 +431,13 @@ func (u *UserToUpdate) ProviderToLink(userProvider *UserProvider) *UserToUpdate
 	return u.set("linkProviderUserInfo", userProvider)
 }
 
-// ProvidersToDelete unlinks this user from the specified providers.
+// ProvidersToDelete unlinks this user from the specified providers. Passing an empty list clears
+// any providers previously specified, and leaves the providers of the user unchanged.
 func (u *UserToUpdate) ProvidersToDelete(providerIds []string) *UserToUpdate {
-	// skip setting the value to empty if it's already empty.
+	// never send an empty list, so the providers of the user are not affected.
 	if len(providerIds) == 0 {
-		if u.params == nil {
-			return u
-		}
-		if _, ok := u.params["providersToDelete"]; !ok {
-			return u
-		}
+		delete(u.params, "providersToDelete")
+		return u
 	}
 
 	return u.set("providersToDelete", providerIds)
@@ -509,11 +622,40 @@ func marshalCustomClaims(claims map[string]interface{}) (string, error) {
 		s = "{}" // claims map has been explicitly set to nil for deletion.
 	}
 	if len(s) > maxLenPayloadCC {
-		return "", fmt.Errorf("serialized custom claims must not exceed %d characters", maxLenPayloadCC)
+		return "", fmt.Errorf(
+			"serialized custom claims must not exceed %d characters; got %d characters (largest claims: %s)",
+			maxLenPayloadCC, len(s), strings.Join(claimSizes(claims), ", "))
 	}
 	return s, nil
 }
 
+// claimSizes returns the top-level keys of the given claims map along with the number of characters
+// each one contributes to the serialized payload, largest first.
+func claimSizes(claims map[string]interface{}) []string {
+	type claimSize struct {
+		key  string
+		size int
+	}
+	var sizes []claimSize
+	for k, v := range claims {
+		kb, _ := json.Marshal(k)
+		vb, _ := json.Marshal(v)
+		sizes = append(sizes, claimSize{k, len(kb) + len(vb) + 1})
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].size != sizes[j].size {
+			return sizes[i].size > sizes[j].size
+		}
+		return sizes[i].key < sizes[j].key
+	})
+
+	result := make([]string, len(sizes))
+	for i, cs := range sizes {
+		result[i] = fmt.Sprintf("%q=%d", cs.key, cs.size)
+	}
+	return result
+}
+
 // Error handlers.
 
 const (
@@ -737,6 +879,28 @@ func (c *baseClient) GetUser(ctx context.Context, uid string) (*UserRecord, erro
 	})
 }
 
+// UserExists checks if a user with the specified user ID exists.
+//
+// Unlike GetUser, a missing user is not reported as an error, and the user data returned by the
+// backend is not parsed. An error is only returned if the lookup itself fails.
+func (c *baseClient) UserExists(ctx context.Context, uid string) (bool, error) {
+	if err := validateUID(uid); err != nil {
+		return false, err
+	}
+
+	query := &userQuery{
+		field: "localId",
+		value: uid,
+	}
+	var parsed struct {
+		Users []json.RawMessage `json:"users"`
+	}
+	if _, err := c.post(ctx, "/accounts:lookup", query.build(), &parsed); err != nil {
+		return false, err
+	}
+	return len(parsed.Users) > 0, nil
+}
+
 // GetUserByEmail gets the user data corresponding to the specified email.
 func (c *baseClient) GetUserByEmail(ctx context.Context, email string) (*UserRecord, error) {
 	if err := validateEmail(email); err != nil {
@@ -1063,6 +1227,28 @@ type userQueryResponse struct {
 	MFAInfo            []*multiFactorInfoResponse `json:"mfaInfo,omitempty"`
 }
 
+// UnmarshalJSON unmarshals a user record API response, retaining all the fields of each provider
+// info in the RawData of the corresponding UserInfo.
+func (r *userQueryResponse) UnmarshalJSON(b []byte) error {
+	type userQueryResponseInternal userQueryResponse
+	if err := json.Unmarshal(b, (*userQueryResponseInternal)(r)); err != nil {
+		return err
+	}
+
+	var raw struct {
+		ProviderUserInfo []map[string]interface{} `json:"providerUserInfo"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for i, data := range raw.ProviderUserInfo {
+		if i < len(r.ProviderUserInfo) && r.ProviderUserInfo[i] != nil {
+			r.ProviderUserInfo[i].RawData = data
+		}
+	}
+	return nil
+}
+
 func (r *userQueryResponse) makeUserRecord() (*UserRecord, error) {
 	exported, err := r.makeExportedUserRecord()
 	if err != nil {
@@ -1176,6 +1362,101 @@ func (c *baseClient) CreateUser(ctx context.Context, user *UserToCreate) (*UserR
 	return c.GetUser(ctx, uid)
 }
 
+// CreateUserIdempotent creates a new user with the specified properties, such that retrying the
+// operation does not fail because an earlier attempt already created the user.
+//
+// The UserToCreate must specify a UID, which serves as the idempotency key of the operation. When
+// a request times out or fails after reaching the backend, the user may have been created even
+// though the request failed. Retrying with the same UID then fails with a uid-already-exists
+// error (see IsUIDAlreadyExists()) instead of creating a duplicate account, and this function
+// treats that error as success and returns the existing user.
+//
+// The request is retried like all other requests of the client: after network errors and
+// UNAVAILABLE responses, up to 4 times with exponential backoff, for a total of up to 5 attempts.
+// No retries are made on top of those. The same reconciliation applies when the caller itself
+// retries a call that returned an error.
+//
+// Since the existing user is returned as is, the UID must identify the user being created, for
+// example by deriving it from the ID of the user in the caller's own system. If an unrelated
+// account already has the UID, that account is returned.
+func (c *baseClient) CreateUserIdempotent(ctx context.Context, user *UserToCreate) (*UserRecord, error) {
+	var uid string
+	if user != nil {
+		uid, _ = user.params["localId"].(string)
+	}
+	if uid == "" {
+		return nil, errors.New("uid must be specified for idempotent user creation")
+	}
+
+	if _, err := c.createUser(ctx, user); err != nil && !IsUIDAlreadyExists(err) {
+		return nil, err
+	}
+	return c.GetUser(ctx, uid)
+}
+
+// BatchCreateResult represents the result of the CreateUsers() API.
+type BatchCreateResult struct {
+	// The number of users that were created successfully (possibly zero).
+	SuccessCount int
+
+	// The number of users that failed to be created (possibly zero).
+	FailureCount int
+
+	// The UserRecords of the created users, in the same order as the input. The entry at the
+	// index of a user that could not be created is nil.
+	Users []*UserRecord
+
+	// A list of BatchCreateErrorInfo instances describing the errors that were encountered
+	// while creating the users. Length of this list is equal to the value of FailureCount.
+	Errors []*BatchCreateErrorInfo
+}
+
+// BatchCreateErrorInfo represents an error encountered while creating a user as part of the
+// CreateUsers() API.
+type BatchCreateErrorInfo struct {
+	Index int
+	Err   error
+}
+
+// CreateUsers creates the given user accounts with the specified properties.
+//
+// Since the backend does not support batch account creation, this issues one request per user,
+// with a bounded number of requests in flight at any time. Failures are reported per user in the
+// Errors field of the result, and do not prevent the remaining users from being created. To
+// migrate users along with their password hashes, use ImportUsers() instead.
+//
+// A maximum of 1000 users may be supplied. The concurrency and rate of the requests can be
+// controlled via opts, which may be nil.
+func (c *baseClient) CreateUsers(
+	ctx context.Context, users []*UserToCreate, opts *BatchOptions) (*BatchCreateResult, error) {
+	if len(users) == 0 {
+		return nil, errors.New("users list must not be empty")
+	}
+	if len(users) > maxCreateAccountsBatchSize {
+		return nil, fmt.Errorf("users list must not contain more than %d elements", maxCreateAccountsBatchSize)
+	}
+
+	result := &BatchCreateResult{
+		Users: make([]*UserRecord, len(users)),
+	}
+	errs := make([]error, len(users))
+	runConcurrently(ctx, len(users), opts, func(idx int) {
+		result.Users[idx], errs[idx] = c.CreateUser(ctx, users[idx])
+	})
+
+	for idx, err := range errs {
+		if err != nil {
+			result.Errors = append(result.Errors, &BatchCreateErrorInfo{
+				Index: idx,
+				Err:   err,
+			})
+		}
+	}
+	result.FailureCount = len(result.Errors)
+	result.SuccessCount = len(users) - result.FailureCount
+	return result, nil
+}
+
 func (c *baseClient) createUser(ctx context.Context, user *UserToCreate) (string, error) {
 	if user == nil {
 		user = &UserToCreate{}
@@ -1193,15 +1474,289 @@ func (c *baseClient) createUser(ctx context.Context, user *UserToCreate) (string
 	return result.UID, err
 }
 
+// ValidateCreate runs the client-side validation that CreateUser performs on the given
+// UserToCreate, without making any RPC calls, and returns the first error found.
+//
+// This checks the format of the UID, email address, phone number, photo URL and password, and the
+// multi-factor settings of the user. A user that passes the validation may still be rejected by
+// the backend, for instance if its email address is already in use.
+func ValidateCreate(user *UserToCreate) error {
+	if user == nil {
+		return nil
+	}
+	_, err := user.validatedRequest()
+	return err
+}
+
+// ValidateUpdate runs the client-side validation that UpdateUser performs on the given
+// UserToUpdate, without making any RPC calls, and returns the first error found.
+//
+// In addition to the checks of ValidateCreate, this checks the size of the custom claims, the
+// provider to link and the password hash of the update. The current custom claims of the user are
+// not fetched, hence a disabled reason is validated against the custom claims set on the
+// UserToUpdate only.
+func ValidateUpdate(user *UserToUpdate) error {
+	if user == nil {
+		return fmt.Errorf("update parameters must not be nil or empty")
+	}
+	if err := user.validatePasswordHash(); err != nil {
+		return err
+	}
+	if (user.passwordHash != nil || user.allowEmpty) && len(user.params) == 0 {
+		return nil
+	}
+
+	if user.disabledReason != nil {
+		claims, _ := user.params["customClaims"].(map[string]interface{})
+		user = user.withDisabledReason(claims)
+	}
+	_, err := user.validatedRequest()
+	return err
+}
+
+// DiffUser returns a UserToUpdate that contains only the changes of the desired update that differ
+// from the current state of the user, and whether it contains any changes.
+//
+// This allows reconciling user accounts with a desired state without making no-op writes. Profile
+// attributes, flags and custom claims are compared with the values in the UserRecord, providers
+// to link or delete are compared with the linked providers of the user, and a disabled reason is
+// compared with UserRecord.DisabledReason(). Passwords, password hashes and multi-factor settings
+// cannot be compared reliably, and are always included when specified. The desired update is not
+// modified.
+func DiffUser(current *UserRecord, desired *UserToUpdate) (*UserToUpdate, bool) {
+	diff := &UserToUpdate{}
+	if desired == nil {
+		return diff, false
+	}
+	if current == nil || current.UserInfo == nil {
+		current = &UserRecord{UserInfo: &UserInfo{}}
+	}
+	diff.allowEmpty = desired.allowEmpty
+	diff.passwordHash = desired.passwordHash
+
+	claims, hasClaims := desired.params["customClaims"].(map[string]interface{})
+	if desired.disabledReason != nil {
+		reason := *desired.disabledReason
+		claimsChanged := false
+		if hasClaims {
+			merged := map[string]interface{}{DisabledReasonClaim: reason}
+			for k, v := range claims {
+				if k != DisabledReasonClaim {
+					merged[k] = v
+				}
+			}
+			claimsChanged = !claimsEqual(current.CustomClaims, merged)
+		}
+		if current.DisabledReason() != reason || claimsChanged {
+			diff.DisableWithReason(reason)
+			if hasClaims {
+				diff.CustomClaims(claims)
+			}
+		}
+	}
+
+	for key, value := range desired.params {
+		var changed bool
+		switch key {
+		case "customClaims", "disableUser":
+			if desired.disabledReason != nil {
+				continue
+			}
+			if key == "customClaims" {
+				changed = !claimsEqual(current.CustomClaims, claims)
+			} else {
+				changed = value.(bool) != current.Disabled
+			}
+		case "displayName":
+			changed = value.(string) != current.DisplayName
+		case "email":
+			changed = value.(string) != current.Email
+		case "phoneNumber":
+			changed = value.(string) != current.PhoneNumber
+		case "photoUrl":
+			changed = value.(string) != current.PhotoURL
+		case "emailVerified":
+			changed = value.(bool) != current.EmailVerified
+		case "linkProviderUserInfo":
+			changed = true
+			if p, ok := value.(*UserProvider); ok && p != nil {
+				info := current.ProviderInfo(p.ProviderID)
+				changed = info == nil || info.UID != p.UID
+			}
+		case "providersToDelete":
+			var linked []string
+			for _, id := range value.([]string) {
+				if current.ProviderInfo(id) != nil {
+					linked = append(linked, id)
+				}
+			}
+			if len(linked) > 0 {
+				diff.set(key, linked)
+			}
+			continue
+		default:
+			changed = true
+		}
+		if changed {
+			diff.set(key, value)
+		}
+	}
+
+	return diff, len(diff.params) > 0 || diff.passwordHash != nil
+}
+
+// claimsEqual compares two sets of custom claims as they would be serialized, so that numbers are
+// compared regardless of their Go types. A nil set of claims is equal to an empty set.
+func claimsEqual(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	normalize := func(claims map[string]interface{}) interface{} {
+		b, err := json.Marshal(claims)
+		if err != nil {
+			return nil
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil
+		}
+		return v
+	}
+	na, nb := normalize(a), normalize(b)
+	return na != nil && reflect.DeepEqual(na, nb)
+}
+
 // UpdateUser updates an existing user account with the specified properties.
 func (c *baseClient) UpdateUser(
 	ctx context.Context, uid string, user *UserToUpdate) (ur *UserRecord, err error) {
+	if user != nil && user.passwordHash != nil {
+		return c.updateUserWithPasswordHash(ctx, uid, user)
+	}
+	if user != nil && user.allowEmpty && len(user.params) == 0 {
+		return c.GetUser(ctx, uid)
+	}
 	if err := c.updateUser(ctx, uid, user); err != nil {
 		return nil, err
 	}
 	return c.GetUser(ctx, uid)
 }
 
+// validatePasswordHash checks the password hash update of the UserToUpdate, if any.
+func (u *UserToUpdate) validatePasswordHash() error {
+	ph := u.passwordHash
+	if ph == nil {
+		return nil
+	}
+	if len(ph.hash) == 0 {
+		return errors.New("password hash must not be empty")
+	}
+	if ph.algorithm == nil {
+		return errors.New("password hash algorithm must not be nil")
+	}
+	if len(u.params) > 0 {
+		return errors.New("password hash must not be combined with other updates")
+	}
+	return nil
+}
+
+// withDisabledReason returns a copy of the UserToUpdate that sets the given custom claims along
+// with the disabled reason claim.
+func (u *UserToUpdate) withDisabledReason(claims map[string]interface{}) *UserToUpdate {
+	merged := make(map[string]interface{})
+	for k, v := range claims {
+		merged[k] = v
+	}
+	merged[DisabledReasonClaim] = *u.disabledReason
+
+	result := &UserToUpdate{}
+	for k, v := range u.params {
+		result.set(k, v)
+	}
+	return result.CustomClaims(merged)
+}
+
+// updateUserWithPasswordHash re-imports the given user account with the new password hash, since
+// the import API is the only one that accepts password hashes.
+func (c *baseClient) updateUserWithPasswordHash(
+	ctx context.Context, uid string, user *UserToUpdate) (*UserRecord, error) {
+	if err := validateUID(uid); err != nil {
+		return nil, err
+	}
+	if err := user.validatePasswordHash(); err != nil {
+		return nil, err
+	}
+
+	// The import replaces the whole account, so it must carry all the existing attributes. This
+	// also ensures the account exists, since an import would create it otherwise.
+	current, err := c.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ph := user.passwordHash
+	imported := userToImportFromRecord(current).PasswordHash(ph.hash)
+	if len(ph.salt) > 0 {
+		imported.PasswordSalt(ph.salt)
+	}
+	result, err := c.ImportUsers(ctx, []*UserToImport{imported}, WithHash(ph.algorithm))
+	if err != nil {
+		return nil, err
+	}
+	if result.FailureCount > 0 {
+		return nil, fmt.Errorf("failed to set password hash: %s", result.Errors[0].Reason)
+	}
+	return c.GetUser(ctx, uid)
+}
+
+// userToImportFromRecord returns a UserToImport that recreates the given user account. Password
+// and phone identities are omitted from the provider data, since they are implied by the password
+// hash and the phone number of the account.
+func userToImportFromRecord(r *UserRecord) *UserToImport {
+	u := (&UserToImport{}).
+		UID(r.UID).
+		Disabled(r.Disabled).
+		EmailVerified(r.EmailVerified)
+	if r.Email != "" {
+		u.Email(r.Email)
+	}
+	if r.DisplayName != "" {
+		u.DisplayName(r.DisplayName)
+	}
+	if r.PhotoURL != "" {
+		u.PhotoURL(r.PhotoURL)
+	}
+	if r.PhoneNumber != "" {
+		u.PhoneNumber(r.PhoneNumber)
+	}
+	if r.UserMetadata != nil {
+		u.Metadata(r.UserMetadata)
+	}
+	if len(r.CustomClaims) > 0 {
+		u.CustomClaims(r.CustomClaims)
+	}
+	if r.MultiFactor != nil && len(r.MultiFactor.EnrolledFactors) > 0 {
+		u.MFASettings(*r.MultiFactor)
+	}
+
+	var providers []*UserProvider
+	for _, p := range r.ProviderUserInfo {
+		if p.ProviderID == "password" || p.ProviderID == "phone" {
+			continue
+		}
+		providers = append(providers, &UserProvider{
+			UID:         p.UID,
+			ProviderID:  p.ProviderID,
+			Email:       p.Email,
+			DisplayName: p.DisplayName,
+			PhotoURL:    p.PhotoURL,
+		})
+	}
+	if len(providers) > 0 {
+		u.ProviderData(providers)
+	}
+	return u
+}
+
 // RevokeRefreshTokens revokes all refresh tokens issued to a user.
 //
 // RevokeRefreshTokens updates the user's TokensValidAfterMillis to the current UTC second.
@@ -1222,6 +1777,9 @@ func (c *baseClient) RevokeRefreshTokens(ctx context.Context, uid string) error
 // can be accessed via the user's ID token JWT. If a reserved OIDC claim is specified (sub, iat,
 // iss, etc), an error is thrown. Claims payload must also not be larger then 1000 characters
 // when serialized into a JSON string.
+//
+// The given claims replace all the existing claims of the user. Passing a nil or an empty map
+// removes all the claims, as does ClearCustomUserClaims().
 func (c *baseClient) SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error {
 	if customClaims == nil || len(customClaims) == 0 {
 		customClaims = map[string]interface{}{}
@@ -1229,6 +1787,15 @@ func (c *baseClient) SetCustomUserClaims(ctx context.Context, uid string, custom
 	return c.updateUser(ctx, uid, (&UserToUpdate{}).CustomClaims(customClaims))
 }
 
+// ClearCustomUserClaims removes all the custom claims of an existing user account.
+//
+// The claims are removed in a single update, without fetching the current claims of the user. As
+// with SetCustomUserClaims(), the change propagates to the ID tokens of the user when they are
+// refreshed. This also removes the DisabledReasonClaim of a disabled user, if any.
+func (c *baseClient) ClearCustomUserClaims(ctx context.Context, uid string) error {
+	return c.SetCustomUserClaims(ctx, uid, nil)
+}
+
 func (c *baseClient) updateUser(ctx context.Context, uid string, user *UserToUpdate) error {
 	if err := validateUID(uid); err != nil {
 		return err
@@ -1237,6 +1804,13 @@ func (c *baseClient) updateUser(ctx context.Context, uid string, user *UserToUpd
 		return fmt.Errorf("update parameters must not be nil or empty")
 	}
 
+	if user.disabledReason != nil {
+		var err error
+		if user, err = c.withDisabledReasonClaim(ctx, uid, user); err != nil {
+			return err
+		}
+	}
+
 	request, err := user.validatedRequest()
 	if err != nil {
 		return err
@@ -1247,6 +1821,23 @@ func (c *baseClient) updateUser(ctx context.Context, uid string, user *UserToUpd
 	return err
 }
 
+// withDisabledReasonClaim returns a copy of the given UserToUpdate, with the disabled reason merged
+// into the custom claims to be set. The current claims of the user are fetched if the update does
+// not specify any custom claims.
+func (c *baseClient) withDisabledReasonClaim(
+	ctx context.Context, uid string, user *UserToUpdate) (*UserToUpdate, error) {
+	claims, ok := user.params["customClaims"].(map[string]interface{})
+	if !ok {
+		current, err := c.GetUser(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		claims = current.CustomClaims
+	}
+
+	return user.withDisabledReason(claims), nil
+}
+
 // DeleteUser deletes the user by the given UID.
 func (c *baseClient) DeleteUser(ctx context.Context, uid string) error {
 	if err := validateUID(uid); err != nil {
@@ -1361,8 +1952,8 @@ func (c *baseClient) createSessionCookie(
 		return "", errors.New("id token must not be empty")
 	}
 
-	if expiresIn < 5*time.Minute || expiresIn > 14*24*time.Hour {
-		return "", errors.New("expiry duration must be between 5 minutes and 14 days")
+	if expiresIn < minSessionCookieDuration || expiresIn > maxSessionCookieDuration {
+		return "", sessionCookieDurationError(expiresIn)
 	}
 
 	payload := map[string]interface{}{
@@ -1376,6 +1967,25 @@ func (c *baseClient) createSessionCookie(
 	return result.SessionCookie, err
 }
 
+func sessionCookieDurationError(expiresIn time.Duration) error {
+	msg := fmt.Sprintf("session cookie duration must be between %s and %s; got %v",
+		shortDuration(minSessionCookieDuration), shortDuration(maxSessionCookieDuration), expiresIn)
+	if expiresIn > 0 && expiresIn < time.Second {
+		// A number of seconds converted to a time.Duration is interpreted as nanoseconds.
+		msg += "; the duration must be a time.Duration such as 24 * time.Hour, not a number of seconds"
+	}
+	return errors.New(msg)
+}
+
+// shortDuration formats a whole number of minutes or hours without the zero components printed by
+// time.Duration, e.g. 5m rather than 5m0s.
+func shortDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
 func (c *baseClient) post(
 	ctx context.Context,
 	path string,
@@ -27,6 +27,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -53,10 +54,24 @@ var testUser = &UserRecord{
 			PhotoURL:    "http://www.example.com/testuser/photo.png",
 			Email:       "testuser@example.com",
 			UID:         "testuid",
+			FederatedID: "testuser@example.com",
+			RawData: map[string]interface{}{
+				"providerId":  "password",
+				"displayName": "Test User",
+				"photoUrl":    "http://www.example.com/testuser/photo.png",
+				"federatedId": "testuser@example.com",
+				"email":       "testuser@example.com",
+				"rawId":       "testuid",
+			},
 		}, {
 			ProviderID:  "phone",
 			PhoneNumber: "+1234567890",
 			UID:         "testuid",
+			RawData: map[string]interface{}{
+				"providerId":  "phone",
+				"phoneNumber": "+1234567890",
+				"rawId":       "testuid",
+			},
 		},
 	},
 	TokensValidAfterMillis: 1494364393000,
@@ -107,10 +122,24 @@ var testUserWithoutMFA = &UserRecord{
 			PhotoURL:    "http://www.example.com/testusernomfa/photo.png",
 			Email:       "testusernomfa@example.com",
 			UID:         "testuid",
+			FederatedID: "testusernomfa@example.com",
+			RawData: map[string]interface{}{
+				"providerId":  "password",
+				"displayName": "Test User Without MFA",
+				"photoUrl":    "http://www.example.com/testusernomfa/photo.png",
+				"federatedId": "testusernomfa@example.com",
+				"email":       "testusernomfa@example.com",
+				"rawId":       "testuid",
+			},
 		}, {
 			ProviderID:  "phone",
 			PhoneNumber: "+1234567890",
 			UID:         "testuid",
+			RawData: map[string]interface{}{
+				"providerId":  "phone",
+				"phoneNumber": "+1234567890",
+				"rawId":       "testuid",
+			},
 		},
 	},
 	TokensValidAfterMillis: 1494364393000,
@@ -544,6 +573,49 @@ func TestGetNonExistingUser(t *testing.T) {
 	}
 }
 
+func TestUserExists(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+
+	exists, err := s.Client.UserExists(context.Background(), "ignored_id")
+	if !exists || err != nil {
+		t.Errorf("UserExists() = (%v, %v); want = (true, nil)", exists, err)
+	}
+
+	want := `{"localId":["ignored_id"]}`
+	if got := string(s.Rbody); got != want {
+		t.Errorf("UserExists() Req = %v; want = %v", got, want)
+	}
+}
+
+func TestUserExistsNonExistingUser(t *testing.T) {
+	s := echoServer([]byte(`{"kind": "identitytoolkit#GetAccountInfoResponse"}`), t)
+	defer s.Close()
+
+	exists, err := s.Client.UserExists(context.Background(), "id-nonexisting")
+	if exists || err != nil {
+		t.Errorf("UserExists(non-existing) = (%v, %v); want = (false, nil)", exists, err)
+	}
+}
+
+func TestUserExistsError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "INTERNAL_ERROR"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+	s.Client.httpClient.RetryConfig = nil
+
+	exists, err := s.Client.UserExists(context.Background(), "uid")
+	if exists || !errorutils.IsInternal(err) {
+		t.Errorf("UserExists() = (%v, %v); want = (false, internal-error)", exists, err)
+	}
+
+	for _, uid := range []string{"", strings.Repeat("a", 129)} {
+		if exists, err := s.Client.UserExists(context.Background(), uid); exists || err == nil {
+			t.Errorf("UserExists(%q) = (%v, %v); want = (false, error)", uid, exists, err)
+		}
+	}
+}
+
 func TestListUsers(t *testing.T) {
 	testListUsersResponse, err := ioutil.ReadFile("../testdata/list_users.json")
 	if err != nil {
@@ -731,6 +803,20 @@ func TestInvalidCreateUser(t *testing.T) {
 		if err.Error() != tc.want {
 			t.Errorf("[%d] CreateUser() = %v; want = %v", i, err.Error(), tc.want)
 		}
+		if err := ValidateCreate(tc.params); err == nil || err.Error() != tc.want {
+			t.Errorf("[%d] ValidateCreate() = %v; want = %v", i, err, tc.want)
+		}
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	if err := ValidateCreate(nil); err != nil {
+		t.Errorf("ValidateCreate(nil) = %v; want = nil", err)
+	}
+	for _, tc := range createUserCases {
+		if err := ValidateCreate(tc.params); err != nil {
+			t.Errorf("ValidateCreate(%#v) = %v; want = nil", tc.params, err)
+		}
 	}
 }
 
@@ -880,6 +966,186 @@ func TestCreateUser(t *testing.T) {
 	}
 }
 
+func TestCreateUsers(t *testing.T) {
+	s := echoServer(nil, t)
+	defer s.Close()
+	s.Client.baseClient.httpClient.RetryConfig = nil
+
+	var mu sync.Mutex
+	var created []string
+	s.Srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/projects/mock-project-id/accounts":
+			var req map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			uid := req["localId"].(string)
+			if uid == "existing" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": {"message": "DUPLICATE_LOCAL_ID"}}`))
+				return
+			}
+			mu.Lock()
+			created = append(created, uid)
+			mu.Unlock()
+			fmt.Fprintf(w, `{"localId": %q}`, uid)
+		case "/projects/mock-project-id/accounts:lookup":
+			w.Write(testGetUserResponse)
+		default:
+			t.Errorf("unexpected request path: %q", r.URL.Path)
+		}
+	})
+
+	users := []*UserToCreate{
+		(&UserToCreate{}).UID("user1"),
+		(&UserToCreate{}).UID("existing"),
+		(&UserToCreate{}).UID("user2").Email("invalid"),
+		(&UserToCreate{}).UID("user3"),
+	}
+	result, err := s.Client.CreateUsers(context.Background(), users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.SuccessCount != 2 || result.FailureCount != 2 || len(result.Errors) != 2 {
+		t.Fatalf("CreateUsers() = %#v; want = {SuccessCount: 2, FailureCount: 2}", result)
+	}
+	if len(result.Users) != len(users) {
+		t.Fatalf("len(CreateUsers().Users) = %d; want = %d", len(result.Users), len(users))
+	}
+	for _, idx := range []int{0, 3} {
+		if !reflect.DeepEqual(result.Users[idx], testUser) {
+			t.Errorf("CreateUsers().Users[%d] = %#v; want = %#v", idx, result.Users[idx], testUser)
+		}
+	}
+	for _, idx := range []int{1, 2} {
+		if result.Users[idx] != nil {
+			t.Errorf("CreateUsers().Users[%d] = %#v; want = nil", idx, result.Users[idx])
+		}
+	}
+
+	if result.Errors[0].Index != 1 || !IsUIDAlreadyExists(result.Errors[0].Err) {
+		t.Errorf("CreateUsers().Errors[0] = %#v; want = {Index: 1, Err: UIDAlreadyExists}", result.Errors[0])
+	}
+	wantErr := `malformed email string: "invalid"`
+	if result.Errors[1].Index != 2 || result.Errors[1].Err.Error() != wantErr {
+		t.Errorf("CreateUsers().Errors[1] = %#v; want = {Index: 2, Err: %q}", result.Errors[1], wantErr)
+	}
+
+	sort.Strings(created)
+	if want := []string{"user1", "user3"}; !reflect.DeepEqual(created, want) {
+		t.Errorf("CreateUsers() created = %v; want = %v", created, want)
+	}
+}
+
+func TestCreateUsersInvalidInput(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{},
+	}
+	cases := []struct {
+		users []*UserToCreate
+		want  string
+	}{
+		{nil, "users list must not be empty"},
+		{make([]*UserToCreate, 1001), "users list must not contain more than 1000 elements"},
+	}
+	for _, tc := range cases {
+		result, err := client.CreateUsers(context.Background(), tc.users, nil)
+		if result != nil || err == nil || err.Error() != tc.want {
+			t.Errorf("CreateUsers(%d) = (%v, %v); want = (nil, %q)", len(tc.users), result, err, tc.want)
+		}
+	}
+}
+
+func TestCreateUserIdempotent(t *testing.T) {
+	s := echoServer(nil, t)
+	defer s.Close()
+
+	var attempts int
+	var lookups int
+	s.Srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/projects/mock-project-id/accounts":
+			attempts++
+			switch attempts {
+			case 1:
+				// The user is created, but the response is lost. The HTTP client retries the
+				// request.
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error": {"message": "UNAVAILABLE"}}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": {"message": "DUPLICATE_LOCAL_ID"}}`))
+			}
+		case "/projects/mock-project-id/accounts:lookup":
+			lookups++
+			w.Write(testGetUserResponse)
+		default:
+			t.Errorf("unexpected request path: %q", r.URL.Path)
+		}
+	})
+
+	user, err := s.Client.CreateUserIdempotent(context.Background(), (&UserToCreate{}).UID("testuser"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(user, testUser) {
+		t.Errorf("CreateUserIdempotent() = %#v; want = %#v", user, testUser)
+	}
+	if attempts != 2 || lookups != 1 {
+		t.Errorf("CreateUserIdempotent() = (%d attempts, %d lookups); want = (2, 1)", attempts, lookups)
+	}
+}
+
+func TestCreateUserIdempotentError(t *testing.T) {
+	s := echoServer(nil, t)
+	defer s.Close()
+	// Keep the default retry policy, without waiting between the retries.
+	s.Client.baseClient.httpClient.RetryConfig.ExpBackoffFactor = 0
+
+	cases := []struct {
+		status   int
+		message  string
+		attempts int
+	}{
+		{http.StatusServiceUnavailable, "UNAVAILABLE", 5},
+		{http.StatusInternalServerError, "INTERNAL_ERROR", 1},
+		{http.StatusBadRequest, "EMAIL_EXISTS", 1},
+	}
+	for _, tc := range cases {
+		var attempts int
+		s.Srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/projects/mock-project-id/accounts" {
+				t.Errorf("unexpected request path: %q", r.URL.Path)
+			}
+			attempts++
+			w.WriteHeader(tc.status)
+			fmt.Fprintf(w, `{"error": {"message": %q}}`, tc.message)
+		})
+
+		user, err := s.Client.CreateUserIdempotent(context.Background(), (&UserToCreate{}).UID("testuser"))
+		if user != nil || err == nil {
+			t.Errorf("CreateUserIdempotent(%s) = (%v, %v); want = (nil, error)", tc.message, user, err)
+		}
+		if attempts != tc.attempts {
+			t.Errorf("CreateUserIdempotent(%s) = %d attempts; want = %d", tc.message, attempts, tc.attempts)
+		}
+	}
+}
+
+func TestCreateUserIdempotentNoUID(t *testing.T) {
+	client := &Client{baseClient: &baseClient{}}
+	want := "uid must be specified for idempotent user creation"
+	for _, user := range []*UserToCreate{nil, {}, (&UserToCreate{}).Email("user@example.com")} {
+		if got, err := client.CreateUserIdempotent(context.Background(), user); got != nil || err == nil || err.Error() != want {
+			t.Errorf("CreateUserIdempotent(%v) = (%v, %v); want = (nil, %q)", user, got, err, want)
+		}
+	}
+}
+
 func TestInvalidUpdateUser(t *testing.T) {
 	cases := []struct {
 		params *UserToUpdate
@@ -902,7 +1168,7 @@ func TestInvalidUpdateUser(t *testing.T) {
 			"phone number must be a valid, E.164 compliant identifier",
 		}, {
 			(&UserToUpdate{}).CustomClaims(map[string]interface{}{"a": strings.Repeat("a", 993)}),
-			"serialized custom claims must not exceed 1000 characters",
+			`serialized custom claims must not exceed 1000 characters; got 1001 characters (largest claims: "a"=999)`,
 		}, {
 			(&UserToUpdate{}).Password("short"),
 			"password must be a string at least 6 characters long",
@@ -1000,6 +1266,159 @@ func TestInvalidUpdateUser(t *testing.T) {
 		if err.Error() != tc.want {
 			t.Errorf("[%d] UpdateUser() = %v; want = %v", i, err.Error(), tc.want)
 		}
+		if err := ValidateUpdate(tc.params); err == nil || err.Error() != tc.want {
+			t.Errorf("[%d] ValidateUpdate() = %v; want = %v", i, err, tc.want)
+		}
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	for _, tc := range updateUserCases {
+		if err := ValidateUpdate(tc.params); err != nil {
+			t.Errorf("ValidateUpdate(%#v) = %v; want = nil", tc.params, err)
+		}
+	}
+
+	valid := []*UserToUpdate{
+		(&UserToUpdate{}).AllowEmptyUpdate(true),
+		(&UserToUpdate{}).PasswordHash([]byte("password"), nil, mockHash{}),
+		(&UserToUpdate{}).DisableWithReason("fraud"),
+	}
+	for i, tc := range valid {
+		if err := ValidateUpdate(tc); err != nil {
+			t.Errorf("ValidateUpdate(%d) = %v; want = nil", i, err)
+		}
+	}
+}
+
+func TestValidateUpdateError(t *testing.T) {
+	cases := []struct {
+		params *UserToUpdate
+		want   string
+	}{
+		{
+			(&UserToUpdate{}).PasswordHash(nil, nil, mockHash{}),
+			"password hash must not be empty",
+		}, {
+			(&UserToUpdate{}).PasswordHash([]byte("password"), nil, nil),
+			"password hash algorithm must not be nil",
+		}, {
+			(&UserToUpdate{}).Password("password").PasswordHash([]byte("password"), nil, mockHash{}),
+			"password hash must not be combined with other updates",
+		}, {
+			(&UserToUpdate{}).DisableWithReason("fraud").PasswordHash([]byte("password"), nil, mockHash{}),
+			"password hash must not be combined with other updates",
+		}, {
+			(&UserToUpdate{}).
+				CustomClaims(map[string]interface{}{"a": strings.Repeat("a", 970)}).
+				DisableWithReason("fraud"),
+			`serialized custom claims must not exceed 1000 characters; got 1003 characters (largest claims: "a"=976, "disabledReason"=24)`,
+		},
+	}
+	for i, tc := range cases {
+		if err := ValidateUpdate(tc.params); err == nil || err.Error() != tc.want {
+			t.Errorf("[%d] ValidateUpdate() = %v; want = %v", i, err, tc.want)
+		}
+	}
+}
+
+func TestDiffUser(t *testing.T) {
+	unchanged := (&UserToUpdate{}).
+		DisplayName("Test User").
+		Email("testuser@example.com").
+		EmailVerified(true).
+		PhoneNumber("+1234567890").
+		PhotoURL("http://www.example.com/testuser/photo.png").
+		Disabled(false).
+		CustomClaims(map[string]interface{}{"package": "gold", "admin": true}).
+		ProviderToLink(&UserProvider{ProviderID: "password", UID: "testuid"}).
+		ProvidersToDelete([]string{"google.com"})
+	diff, changed := DiffUser(testUser, unchanged)
+	if changed || len(diff.params) != 0 {
+		t.Errorf("DiffUser(unchanged) = (%v, %v); want = (empty, false)", diff.params, changed)
+	}
+
+	desired := (&UserToUpdate{}).
+		DisplayName("New Name").
+		Email("testuser@example.com").
+		PhotoURL("").
+		EmailVerified(false).
+		Password("secret").
+		CustomClaims(map[string]interface{}{"admin": true}).
+		ProviderToLink(&UserProvider{ProviderID: "google.com", UID: "googleuid"}).
+		ProvidersToDelete([]string{"phone", "google.com"})
+	diff, changed = DiffUser(testUser, desired)
+	if !changed {
+		t.Errorf("DiffUser() = false; want = true")
+	}
+	want := map[string]interface{}{
+		"displayName":          "New Name",
+		"photoUrl":             "",
+		"emailVerified":        false,
+		"password":             "secret",
+		"customClaims":         map[string]interface{}{"admin": true},
+		"linkProviderUserInfo": &UserProvider{ProviderID: "google.com", UID: "googleuid"},
+		"providersToDelete":    []string{"phone"},
+	}
+	if !reflect.DeepEqual(diff.params, want) {
+		t.Errorf("DiffUser() = %#v; want = %#v", diff.params, want)
+	}
+	if len(desired.params) != 8 {
+		t.Errorf("DiffUser() modified the desired update: %#v", desired.params)
+	}
+}
+
+func TestDiffUserDisabledReason(t *testing.T) {
+	disabled := &UserRecord{
+		UserInfo:     &UserInfo{UID: "testuser"},
+		Disabled:     true,
+		CustomClaims: map[string]interface{}{"admin": true, DisabledReasonClaim: "fraud"},
+	}
+	cases := []struct {
+		name    string
+		desired *UserToUpdate
+		changed bool
+	}{
+		{"SameReason", (&UserToUpdate{}).DisableWithReason("fraud"), false},
+		{"SameReasonAndClaims", (&UserToUpdate{}).
+			DisableWithReason("fraud").
+			CustomClaims(map[string]interface{}{"admin": true}), false},
+		{"OtherReason", (&UserToUpdate{}).DisableWithReason("abuse"), true},
+		{"OtherClaims", (&UserToUpdate{}).
+			DisableWithReason("fraud").
+			CustomClaims(map[string]interface{}{"admin": false}), true},
+		{"Enabled", (&UserToUpdate{}).Disabled(false), true},
+	}
+	for _, tc := range cases {
+		diff, changed := DiffUser(disabled, tc.desired)
+		if changed != tc.changed {
+			t.Errorf("DiffUser(%s) = %v; want = %v", tc.name, changed, tc.changed)
+		}
+		if changed && !reflect.DeepEqual(diff, tc.desired) {
+			t.Errorf("DiffUser(%s) = %#v; want = %#v", tc.name, diff, tc.desired)
+		}
+	}
+
+	diff, changed := DiffUser(testUser, (&UserToUpdate{}).DisableWithReason("fraud"))
+	if !changed || diff.disabledReason == nil || *diff.disabledReason != "fraud" {
+		t.Errorf("DiffUser(enabled user) = (%#v, %v); want = (DisableWithReason(fraud), true)", diff, changed)
+	}
+}
+
+func TestDiffUserAlwaysChanged(t *testing.T) {
+	cases := []*UserToUpdate{
+		(&UserToUpdate{}).Password("secret"),
+		(&UserToUpdate{}).PasswordHash([]byte("password"), nil, mockHash{}),
+		(&UserToUpdate{}).revokeRefreshTokens(),
+	}
+	for i, desired := range cases {
+		if diff, changed := DiffUser(testUser, desired); !changed || !reflect.DeepEqual(diff, desired) {
+			t.Errorf("[%d] DiffUser() = (%#v, %v); want = (%#v, true)", i, diff, changed, desired)
+		}
+	}
+
+	if diff, changed := DiffUser(testUser, nil); changed || len(diff.params) != 0 {
+		t.Errorf("DiffUser(nil) = (%#v, %v); want = (empty, false)", diff, changed)
 	}
 }
 
@@ -1148,6 +1567,14 @@ var updateUserCases = []struct {
 			"deleteProvider": []string{"email", "phone"},
 		},
 	},
+	{
+		(&UserToUpdate{}).DisplayName("a").ProvidersToDelete([]string{}),
+		map[string]interface{}{"displayName": "a"},
+	},
+	{
+		(&UserToUpdate{}).DisplayName("a").ProvidersToDelete([]string{"google.com"}).ProvidersToDelete(nil),
+		map[string]interface{}{"displayName": "a"},
+	},
 	{
 		(&UserToUpdate{}).ProviderToLink(&UserProvider{
 			ProviderID: "email",
@@ -1207,87 +1634,469 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
-func TestRevokeRefreshTokens(t *testing.T) {
-	resp := `{
-		"kind": "identitytoolkit#SetAccountInfoResponse",
-		"localId": "expectedUserID"
-	}`
-	s := echoServer([]byte(resp), t)
+func TestUpdateUserPasswordHash(t *testing.T) {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(testGetUserResponse, &resp); err != nil {
+		t.Fatal(err)
+	}
+	account := resp["users"].([]interface{})[0].(map[string]interface{})
+	account["providerUserInfo"] = append(account["providerUserInfo"].([]interface{}), map[string]interface{}{
+		"providerId":  "google.com",
+		"rawId":       "google_uid",
+		"email":       "testuser@gmail.com",
+		"displayName": "Google User",
+	})
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := echoServer(b, t)
 	defer s.Close()
-	before := time.Now().Unix()
-	if err := s.Client.RevokeRefreshTokens(context.Background(), "some_uid"); err != nil {
-		t.Error(err)
+	bodies := recordRequestBodies(s)
+
+	user := (&UserToUpdate{}).
+		PasswordHash([]byte("password"), []byte("salt"), mockHash{key: "key", rounds: 8})
+	if _, err := s.Client.UpdateUser(context.Background(), "testuser", user); err != nil {
+		t.Fatal(err)
+	}
+
+	wantPaths := []string{"accounts:lookup", "accounts:batchCreate", "accounts:lookup"}
+	if len(s.Req) != len(wantPaths) {
+		t.Fatalf("UpdateUser() = %d requests; want = %d", len(s.Req), len(wantPaths))
+	}
+	for i, p := range wantPaths {
+		if want := "/projects/mock-project-id/" + p; s.Req[i].URL.Path != want {
+			t.Errorf("UpdateUser() Req[%d] = %q; want = %q", i, s.Req[i].URL.Path, want)
+		}
 	}
-	after := time.Now().Unix()
 
 	var req struct {
-		ValidSince string `json:"validSince"`
+		HashAlgorithm string                   `json:"hashAlgorithm"`
+		Users         []map[string]interface{} `json:"users"`
 	}
-	if err := json.Unmarshal(s.Rbody, &req); err != nil {
+	importBody := (*bodies)[1]
+	if err := json.Unmarshal(importBody, &req); err != nil {
 		t.Fatal(err)
 	}
+	if req.HashAlgorithm != "MOCKHASH" || len(req.Users) != 1 {
+		t.Fatalf("UpdateUser() import request = %s", string(importBody))
+	}
 
-	validSince, err := strconv.ParseInt(req.ValidSince, 10, 64)
-	if err != nil {
+	// The import overwrites the account, so it must carry all the existing attributes.
+	imported := req.Users[0]
+	want := map[string]interface{}{
+		"localId":       "testuser",
+		"email":         "testuser@example.com",
+		"phoneNumber":   "+1234567890",
+		"displayName":   "Test User",
+		"emailVerified": true,
+		"disabled":      false,
+		"passwordHash":  base64.RawURLEncoding.EncodeToString([]byte("password")),
+		"salt":          base64.RawURLEncoding.EncodeToString([]byte("salt")),
+	}
+	for k, v := range want {
+		if imported[k] != v {
+			t.Errorf("UpdateUser() imported[%q] = %v; want = %v", k, imported[k], v)
+		}
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(imported["customAttributes"].(string)), &claims); err != nil {
 		t.Fatal(err)
 	}
+	wantClaims := map[string]interface{}{"admin": true, "package": "gold"}
+	if !reflect.DeepEqual(claims, wantClaims) {
+		t.Errorf("UpdateUser() imported claims = %v; want = %v", claims, wantClaims)
+	}
 
-	if validSince > after || validSince < before {
-		t.Errorf("validSince = %d, expecting time between %d and %d", validSince, before, after)
+	providers, _ := imported["providerUserInfo"].([]interface{})
+	wantProviders := []interface{}{
+		map[string]interface{}{
+			"rawId":       "google_uid",
+			"providerId":  "google.com",
+			"email":       "testuser@gmail.com",
+			"displayName": "Google User",
+		},
+	}
+	if !reflect.DeepEqual(providers, wantProviders) {
+		t.Errorf("UpdateUser() imported providers = %v; want = %v", providers, wantProviders)
 	}
 
-	wantPath := "/projects/mock-project-id/accounts:update"
-	if s.Req[0].RequestURI != wantPath {
-		t.Errorf("RevokeRefreshTokens() URL = %q; want = %q", s.Req[0].RequestURI, wantPath)
+	mfa, _ := imported["mfaInfo"].([]interface{})
+	if len(mfa) != 2 {
+		t.Fatalf("UpdateUser() imported mfaInfo = %v; want = 2 factors", imported["mfaInfo"])
+	}
+	for i, id := range []string{"enrolledPhoneFactor", "enrolledTOTPFactor"} {
+		if got := mfa[i].(map[string]interface{})["mfaEnrollmentId"]; got != id {
+			t.Errorf("UpdateUser() imported mfaInfo[%d] = %v; want = %q", i, got, id)
+		}
 	}
 }
 
-func TestRevokeRefreshTokensInvalidUID(t *testing.T) {
-	resp := `{
-		"kind": "identitytoolkit#SetAccountInfoResponse",
-		"localId": "expectedUserID"
-	}`
-	s := echoServer([]byte(resp), t)
+func TestUpdateUserPasswordHashUserNotFound(t *testing.T) {
+	s := echoServer([]byte(`{"users": []}`), t)
 	defer s.Close()
 
-	we := "uid must be a non-empty string"
-	if err := s.Client.RevokeRefreshTokens(context.Background(), ""); err == nil || err.Error() != we {
-		t.Errorf("RevokeRefreshTokens(); err = %s; want err = %s", err.Error(), we)
+	user := (&UserToUpdate{}).PasswordHash([]byte("password"), nil, mockHash{key: "key"})
+	got, err := s.Client.UpdateUser(context.Background(), "testuser", user)
+	if got != nil || !IsUserNotFound(err) {
+		t.Errorf("UpdateUser() = (%v, %v); want = (nil, user not found)", got, err)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("UpdateUser() = %d requests; want = 1", len(s.Req))
 	}
 }
 
-func TestInvalidSetCustomClaims(t *testing.T) {
-	cases := []struct {
-		cc   map[string]interface{}
-		want string
-	}{
-		{
-			map[string]interface{}{"a": strings.Repeat("a", 993)},
-			"serialized custom claims must not exceed 1000 characters",
-		},
-		{
-			map[string]interface{}{"a": func() {}},
-			"custom claims marshaling error: json: unsupported type: func()",
-		},
-	}
+func TestUpdateUserPasswordHashError(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
 
-	for _, res := range reservedClaims {
-		s := struct {
-			cc   map[string]interface{}
-			want string
-		}{
-			map[string]interface{}{res: true},
-			fmt.Sprintf("claim %q is reserved and must not be set", res),
+	cases := []*UserToUpdate{
+		(&UserToUpdate{}).PasswordHash(nil, nil, mockHash{}),
+		(&UserToUpdate{}).PasswordHash([]byte("password"), nil, nil),
+		(&UserToUpdate{}).Password("password").PasswordHash([]byte("password"), nil, mockHash{}),
+		(&UserToUpdate{}).DisplayName("name").PasswordHash([]byte("password"), nil, mockHash{}),
+	}
+	for i, tc := range cases {
+		if user, err := s.Client.UpdateUser(context.Background(), "testuser", tc); user != nil || err == nil {
+			t.Errorf("UpdateUser(%d) = (%v, %v); want = (nil, error)", i, user, err)
 		}
-		cases = append(cases, s)
 	}
-
-	client := &Client{
-		baseClient: &baseClient{},
+	if len(s.Req) != 0 {
+		t.Errorf("UpdateUser() = %d requests; want = 0", len(s.Req))
 	}
-	for _, tc := range cases {
-		err := client.SetCustomUserClaims(context.Background(), "uid", tc.cc)
-		if err == nil {
+}
+
+func TestUpdateUserPasswordHashImportFailure(t *testing.T) {
+	resp := `{
+		"users": [{"localId": "testuser"}],
+		"error": [{"index": 0, "message": "Invalid password hash"}]
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	user := (&UserToUpdate{}).PasswordHash([]byte("password"), nil, mockHash{key: "key"})
+	got, err := s.Client.UpdateUser(context.Background(), "testuser", user)
+	if got != nil || err == nil || !strings.Contains(err.Error(), "Invalid password hash") {
+		t.Errorf("UpdateUser() = (%v, %v); want = (nil, error)", got, err)
+	}
+}
+
+// recordRequestBodies makes the given server record the bodies of all the requests it receives,
+// in order.
+func recordRequestBodies(s *mockAuthServer) *[][]byte {
+	bodies := &[][]byte{}
+	handler := s.Srv.Config.Handler
+	s.Srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, b)
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		handler.ServeHTTP(w, r)
+	})
+	return bodies
+}
+
+func TestUpdateUserAllowEmpty(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+
+	user, err := s.Client.UpdateUser(context.Background(), "uid", (&UserToUpdate{}).AllowEmptyUpdate(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(user, testUser) {
+		t.Errorf("UpdateUser() = %#v; want = %#v", user, testUser)
+	}
+
+	if len(s.Req) != 1 {
+		t.Fatalf("UpdateUser() requests = %d; want = 1", len(s.Req))
+	}
+	wantPath := "/projects/mock-project-id/accounts:lookup"
+	if s.Req[0].RequestURI != wantPath {
+		t.Errorf("UpdateUser() URL = %q; want = %q", s.Req[0].RequestURI, wantPath)
+	}
+}
+
+func TestUpdateUserAllowEmptyWithParams(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+
+	params := (&UserToUpdate{}).AllowEmptyUpdate(true).DisplayName("a")
+	if _, err := s.Client.UpdateUser(context.Background(), "uid", params); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Req) != 2 {
+		t.Fatalf("UpdateUser() requests = %d; want = 2", len(s.Req))
+	}
+	wantPath := "/projects/mock-project-id/accounts:update"
+	if s.Req[0].RequestURI != wantPath {
+		t.Errorf("UpdateUser() URL = %q; want = %q", s.Req[0].RequestURI, wantPath)
+	}
+}
+
+func TestUpdateUserAllowEmptyInvalidUID(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{},
+	}
+	user, err := client.UpdateUser(context.Background(), "", (&UserToUpdate{}).AllowEmptyUpdate(true))
+	if user != nil || err == nil {
+		t.Errorf("UpdateUser('') = (%v, %v); want = (nil, error)", user, err)
+	}
+}
+
+func TestUpdateUserDisableWithReason(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+
+	params := (&UserToUpdate{}).DisplayName("a").DisableWithReason("fraud")
+	if err := s.Client.updateUser(context.Background(), "uid", params); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Req) != 2 {
+		t.Fatalf("updateUser() requests = %d; want = 2", len(s.Req))
+	}
+	if s.Req[0].URL.Path != "/projects/mock-project-id/accounts:lookup" {
+		t.Errorf("updateUser() URL[0] = %q; want = lookup", s.Req[0].URL.Path)
+	}
+	if s.Req[1].URL.Path != "/projects/mock-project-id/accounts:update" {
+		t.Errorf("updateUser() URL[1] = %q; want = update", s.Req[1].URL.Path)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(s.Rbody, &got); err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(got["customAttributes"].(string)), &claims); err != nil {
+		t.Fatal(err)
+	}
+	wantClaims := map[string]interface{}{
+		"admin":          true,
+		"package":        "gold",
+		"disabledReason": "fraud",
+	}
+	if !reflect.DeepEqual(claims, wantClaims) {
+		t.Errorf("updateUser() claims = %v; want = %v", claims, wantClaims)
+	}
+	if got["disableUser"] != true || got["displayName"] != "a" {
+		t.Errorf("updateUser() request = %v; want disableUser = true, displayName = a", got)
+	}
+
+	// The caller's parameters are not modified.
+	if _, ok := params.params["customClaims"]; ok {
+		t.Errorf("DisableWithReason() modified the UserToUpdate: %v", params.params)
+	}
+}
+
+func TestUpdateUserDisableWithReasonAndClaims(t *testing.T) {
+	s := echoServer(testGetUserResponse, t)
+	defer s.Close()
+
+	params := (&UserToUpdate{}).
+		CustomClaims(map[string]interface{}{"role": "none"}).
+		DisableWithReason("fraud")
+	if err := s.Client.updateUser(context.Background(), "uid", params); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Req) != 1 {
+		t.Fatalf("updateUser() requests = %d; want = 1", len(s.Req))
+	}
+	want := `{"customAttributes":"{\"disabledReason\":\"fraud\",\"role\":\"none\"}","disableUser":true,"localId":"uid"}`
+	if string(s.Rbody) != want {
+		t.Errorf("updateUser() request = %s; want = %s", string(s.Rbody), want)
+	}
+}
+
+func TestDisabledReason(t *testing.T) {
+	cases := []struct {
+		user *UserRecord
+		want string
+	}{
+		{&UserRecord{}, ""},
+		{&UserRecord{Disabled: true}, ""},
+		{&UserRecord{Disabled: true, CustomClaims: map[string]interface{}{"disabledReason": 1}}, ""},
+		{&UserRecord{CustomClaims: map[string]interface{}{"disabledReason": "fraud"}}, ""},
+		{&UserRecord{Disabled: true, CustomClaims: map[string]interface{}{"disabledReason": "fraud"}}, "fraud"},
+	}
+	for i, tc := range cases {
+		if got := tc.user.DisabledReason(); got != tc.want {
+			t.Errorf("[%d] DisabledReason() = %q; want = %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestGenerateTOTPProvisioningURI(t *testing.T) {
+	cases := []struct {
+		accountName, issuer, secret, want string
+	}{
+		{
+			"user@example.com", "Example App", "JBSWY3DPEHPK3PXP",
+			"otpauth://totp/Example%20App:user@example.com?algorithm=SHA1&digits=6&" +
+				"issuer=Example+App&period=30&secret=JBSWY3DPEHPK3PXP",
+		},
+		{
+			"user@example.com", "", "JBSWY3DPEHPK3PXP",
+			"otpauth://totp/user@example.com?algorithm=SHA1&digits=6&period=30&secret=JBSWY3DPEHPK3PXP",
+		},
+		{
+			"a/b:c", "Acme", "SECRET",
+			"otpauth://totp/Acme:a%2Fb%3Ac?algorithm=SHA1&digits=6&issuer=Acme&period=30&secret=SECRET",
+		},
+	}
+	for _, tc := range cases {
+		got := GenerateTOTPProvisioningURI(tc.accountName, tc.issuer, tc.secret)
+		if got != tc.want {
+			t.Errorf("GenerateTOTPProvisioningURI(%q, %q, %q) = %q; want = %q",
+				tc.accountName, tc.issuer, tc.secret, got, tc.want)
+		}
+	}
+}
+
+func TestProviderInfo(t *testing.T) {
+	google := &UserInfo{ProviderID: "google.com", UID: "google_uid"}
+	apple := &UserInfo{ProviderID: "apple.com", UID: "apple_uid"}
+	user := &UserRecord{
+		ProviderUserInfo: []*UserInfo{google, nil, apple},
+	}
+
+	if got := user.ProviderInfo("google.com"); got != google {
+		t.Errorf("ProviderInfo(google.com) = %v; want = %v", got, google)
+	}
+	if got := user.ProviderInfo("apple.com"); got != apple {
+		t.Errorf("ProviderInfo(apple.com) = %v; want = %v", got, apple)
+	}
+	if got := user.ProviderInfo("phone"); got != nil {
+		t.Errorf("ProviderInfo(phone) = %v; want = nil", got)
+	}
+	if got := (&UserRecord{}).ProviderInfo("google.com"); got != nil {
+		t.Errorf("ProviderInfo() on user without providers = %v; want = nil", got)
+	}
+}
+
+func TestUpdateUserOmitsProviderFields(t *testing.T) {
+	s := echoServer([]byte(`{"localId": "uid"}`), t)
+	defer s.Close()
+
+	cases := []*UserToUpdate{
+		(&UserToUpdate{}).DisplayName("a"),
+		(&UserToUpdate{}).DisplayName(""),
+		(&UserToUpdate{}).Email("a@a"),
+		(&UserToUpdate{}).Disabled(true),
+		(&UserToUpdate{}).PhotoURL("http://photo"),
+		(&UserToUpdate{}).CustomClaims(map[string]interface{}{"a": "b"}),
+		(&UserToUpdate{}).DisplayName("a").ProvidersToDelete(nil),
+	}
+	providerFields := []string{"deleteProvider", "providersToDelete", "linkProviderUserInfo", "providerUserInfo"}
+	for _, params := range cases {
+		if err := s.Client.updateUser(context.Background(), "uid", params); err != nil {
+			t.Fatalf("updateUser(%v) = %v", params.params, err)
+		}
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(s.Rbody, &got); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range providerFields {
+			if v, ok := got[field]; ok {
+				t.Errorf("updateUser(%v) request contains %q = %v; want omitted", params.params, field, v)
+			}
+		}
+	}
+}
+
+func TestRevokeRefreshTokens(t *testing.T) {
+	resp := `{
+		"kind": "identitytoolkit#SetAccountInfoResponse",
+		"localId": "expectedUserID"
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+	before := time.Now().Unix()
+	if err := s.Client.RevokeRefreshTokens(context.Background(), "some_uid"); err != nil {
+		t.Error(err)
+	}
+	after := time.Now().Unix()
+
+	var req struct {
+		ValidSince string `json:"validSince"`
+	}
+	if err := json.Unmarshal(s.Rbody, &req); err != nil {
+		t.Fatal(err)
+	}
+
+	validSince, err := strconv.ParseInt(req.ValidSince, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if validSince > after || validSince < before {
+		t.Errorf("validSince = %d, expecting time between %d and %d", validSince, before, after)
+	}
+
+	wantPath := "/projects/mock-project-id/accounts:update"
+	if s.Req[0].RequestURI != wantPath {
+		t.Errorf("RevokeRefreshTokens() URL = %q; want = %q", s.Req[0].RequestURI, wantPath)
+	}
+}
+
+func TestRevokeRefreshTokensInvalidUID(t *testing.T) {
+	resp := `{
+		"kind": "identitytoolkit#SetAccountInfoResponse",
+		"localId": "expectedUserID"
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	we := "uid must be a non-empty string"
+	if err := s.Client.RevokeRefreshTokens(context.Background(), ""); err == nil || err.Error() != we {
+		t.Errorf("RevokeRefreshTokens(); err = %s; want err = %s", err.Error(), we)
+	}
+}
+
+func TestInvalidSetCustomClaims(t *testing.T) {
+	cases := []struct {
+		cc   map[string]interface{}
+		want string
+	}{
+		{
+			map[string]interface{}{"a": strings.Repeat("a", 993)},
+			`serialized custom claims must not exceed 1000 characters; got 1001 characters (largest claims: "a"=999)`,
+		},
+		{
+			map[string]interface{}{
+				"roles": []string{strings.Repeat("r", 600)},
+				"org":   strings.Repeat("o", 390),
+				"admin": true,
+			},
+			"serialized custom claims must not exceed 1000 characters; got 1026 characters " +
+				`(largest claims: "roles"=612, "org"=398, "admin"=12)`,
+		},
+		{
+			map[string]interface{}{"a": func() {}},
+			"custom claims marshaling error: json: unsupported type: func()",
+		},
+	}
+
+	for _, res := range reservedClaims {
+		s := struct {
+			cc   map[string]interface{}
+			want string
+		}{
+			map[string]interface{}{res: true},
+			fmt.Sprintf("claim %q is reserved and must not be set", res),
+		}
+		cases = append(cases, s)
+	}
+
+	client := &Client{
+		baseClient: &baseClient{},
+	}
+	for _, tc := range cases {
+		err := client.SetCustomUserClaims(context.Background(), "uid", tc.cc)
+		if err == nil {
 			t.Errorf("SetCustomUserClaims() = nil; want error: %s", tc.want)
 		}
 		if err.Error() != tc.want {
@@ -1347,6 +2156,46 @@ func TestSetCustomUserClaims(t *testing.T) {
 	}
 }
 
+func TestClearCustomUserClaims(t *testing.T) {
+	resp := `{
+		"kind": "identitytoolkit#SetAccountInfoResponse",
+		"localId": "uid"
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	if err := s.Client.ClearCustomUserClaims(context.Background(), "uid"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(s.Rbody, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"localId":          "uid",
+		"customAttributes": "{}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ClearCustomUserClaims() request = %v; want = %v", got, want)
+	}
+	if len(s.Req) != 1 || s.Req[0].RequestURI != "/projects/mock-project-id/accounts:update" {
+		t.Errorf("ClearCustomUserClaims() requests = %v; want = [accounts:update]", s.Req)
+	}
+}
+
+func TestClearCustomUserClaimsInvalidUID(t *testing.T) {
+	s := echoServer([]byte(`{}`), t)
+	defer s.Close()
+
+	if err := s.Client.ClearCustomUserClaims(context.Background(), ""); err == nil {
+		t.Errorf("ClearCustomUserClaims('') = nil; want error")
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("ClearCustomUserClaims('') requests = %d; want = 0", len(s.Req))
+	}
+}
+
 func TestUserProvider(t *testing.T) {
 	cases := []struct {
 		provider *UserProvider
@@ -1543,6 +2392,41 @@ func TestUserToImport(t *testing.T) {
 				"disabled": false,
 			},
 		},
+		{
+			user: (&UserToImport{}).UID("test").MFASettings(MultiFactorSettings{
+				EnrolledFactors: []*MultiFactorInfo{
+					{
+						UID:                 "phone_factor",
+						DisplayName:         "My phone",
+						EnrollmentTimestamp: 1614776780000,
+						FactorID:            "phone",
+						Phone:               &PhoneMultiFactorInfo{PhoneNumber: "+11234567890"},
+					},
+					{
+						UID:         "totp_factor",
+						DisplayName: "My authenticator",
+						FactorID:    "totp",
+						TOTP:        &TOTPMultiFactorInfo{},
+					},
+				},
+			}),
+			want: map[string]interface{}{
+				"localId": "test",
+				"mfaInfo": []*multiFactorInfoResponse{
+					{
+						MFAEnrollmentID: "phone_factor",
+						DisplayName:     "My phone",
+						PhoneInfo:       "+11234567890",
+						EnrolledAt:      "2021-03-03T13:06:20Z",
+					},
+					{
+						MFAEnrollmentID: "totp_factor",
+						DisplayName:     "My authenticator",
+						TOTPInfo:        &TOTPInfo{},
+					},
+				},
+			},
+		},
 	}
 
 	for idx, tc := range cases {
@@ -1583,7 +2467,7 @@ func TestUserToImportError(t *testing.T) {
 		},
 		{
 			(&UserToImport{}).UID("test").CustomClaims(map[string]interface{}{"key": strings.Repeat("a", 1000)}),
-			"serialized custom claims must not exceed 1000 characters",
+			`serialized custom claims must not exceed 1000 characters; got 1010 characters (largest claims: "key"=1008)`,
 		},
 		{
 			(&UserToImport{}).UID("test").ProviderData([]*UserProvider{
@@ -1601,6 +2485,24 @@ func TestUserToImportError(t *testing.T) {
 			}),
 			"user provider must specify a uid",
 		},
+		{
+			(&UserToImport{}).UID("test").MFASettings(MultiFactorSettings{
+				EnrolledFactors: []*MultiFactorInfo{{FactorID: "email"}},
+			}),
+			`unsupported second factor id: "email"`,
+		},
+		{
+			(&UserToImport{}).UID("test").MFASettings(MultiFactorSettings{
+				EnrolledFactors: []*MultiFactorInfo{{FactorID: "phone"}},
+			}),
+			`the second factor "phoneNumber" for "" must be a non-empty E.164 standard compliant identifier string`,
+		},
+		{
+			(&UserToImport{}).UID("test").MFASettings(MultiFactorSettings{
+				EnrolledFactors: []*MultiFactorInfo{nil},
+			}),
+			"enrolled second factor must not be nil",
+		},
 	}
 
 	s := echoServer([]byte("{}"), t)
@@ -1692,6 +2594,107 @@ func TestImportUsersError(t *testing.T) {
 	}
 }
 
+func TestImportUsersFromChannel(t *testing.T) {
+	resp := `{"error": [{"index": 5, "message": "Some error occurred"}]}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	users := make(chan *UserToImport)
+	go func() {
+		defer close(users)
+		for i := 0; i < 2500; i++ {
+			users <- (&UserToImport{}).UID(fmt.Sprintf("user%d", i))
+		}
+	}()
+
+	var results []*UserImportBatchResult
+	for r := range s.Client.ImportUsersFromChannel(context.Background(), users) {
+		results = append(results, r)
+	}
+
+	if len(results) != 3 || len(s.Req) != 3 {
+		t.Fatalf("ImportUsersFromChannel() = %d results, %d requests; want = 3", len(results), len(s.Req))
+	}
+	for idx, want := range []struct{ start, count int }{{0, 1000}, {1000, 1000}, {2000, 500}} {
+		r := results[idx]
+		if r.Err != nil || r.StartIndex != want.start || r.UserCount != want.count ||
+			r.SuccessCount != want.count-1 || r.FailureCount != 1 {
+			t.Errorf("[%d] result = %#v; want = {StartIndex: %d, UserCount: %d}", idx, r, want.start, want.count)
+		}
+		wantErr := ErrorInfo{Index: want.start + 5, Reason: "Some error occurred"}
+		if len(r.Errors) != 1 || *r.Errors[0] != wantErr {
+			t.Errorf("[%d] Errors = %v; want = [%#v]", idx, r.Errors, wantErr)
+		}
+	}
+
+	var body struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	if err := json.Unmarshal(s.Rbody, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Users) != 500 || body.Users[0]["localId"] != "user2000" {
+		t.Errorf("ImportUsersFromChannel() last batch = %d users starting at %v; want = 500 starting at user2000",
+			len(body.Users), body.Users[0]["localId"])
+	}
+}
+
+func TestImportUsersFromChannelBatchError(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	users := make(chan *UserToImport, 3)
+	users <- (&UserToImport{}).UID("user1")
+	users <- &UserToImport{}
+	users <- (&UserToImport{}).UID("user3")
+	close(users)
+
+	var results []*UserImportBatchResult
+	for r := range s.Client.ImportUsersFromChannel(context.Background(), users) {
+		results = append(results, r)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("ImportUsersFromChannel() = %d results; want = 1", len(results))
+	}
+	r := results[0]
+	if r.Err == nil || r.UserCount != 3 || r.FailureCount != 3 || r.SuccessCount != 0 {
+		t.Errorf("ImportUsersFromChannel() = %#v; want = {UserCount: 3, FailureCount: 3, Err: error}", r)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("ImportUsersFromChannel() requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestImportUsersFromChannelEmpty(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	users := make(chan *UserToImport)
+	close(users)
+	for r := range s.Client.ImportUsersFromChannel(context.Background(), users) {
+		t.Errorf("ImportUsersFromChannel() = %#v; want no results", r)
+	}
+	if len(s.Req) != 0 {
+		t.Errorf("ImportUsersFromChannel() requests = %d; want = 0", len(s.Req))
+	}
+}
+
+func TestImportUsersFromChannelCancel(t *testing.T) {
+	s := echoServer([]byte("{}"), t)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	users := make(chan *UserToImport)
+	results := s.Client.ImportUsersFromChannel(ctx, users)
+	users <- (&UserToImport{}).UID("user1")
+	cancel()
+
+	for r := range results {
+		t.Errorf("ImportUsersFromChannel() = %#v; want no results", r)
+	}
+}
+
 type mockHash struct {
 	key, saltSep       string
 	rounds, memoryCost int64
@@ -1915,18 +2918,7 @@ func TestMakeExportedUser(t *testing.T) {
 		LastLogInTimestamp: 1233211232000,
 		CustomAttributes:   `{"admin": true, "package": "gold"}`,
 		TenantID:           "testTenant",
-		ProviderUserInfo: []*UserInfo{
-			{
-				ProviderID:  "password",
-				DisplayName: "Test User",
-				PhotoURL:    "http://www.example.com/testuser/photo.png",
-				Email:       "testuser@example.com",
-				UID:         "testuid",
-			}, {
-				ProviderID:  "phone",
-				PhoneNumber: "+1234567890",
-				UID:         "testuid",
-			}},
+		ProviderUserInfo:   testUser.ProviderUserInfo,
 		MFAInfo: []*multiFactorInfoResponse{
 			{
 				PhoneInfo:       "+1234567890",
@@ -1965,6 +2957,119 @@ func TestMakeExportedUser(t *testing.T) {
 	}
 }
 
+func TestUserInfoRawData(t *testing.T) {
+	b := []byte(`{
+		"localId": "testuser",
+		"providerUserInfo": [
+			{
+				"providerId": "twitter.com",
+				"rawId": "twitter_uid",
+				"federatedId": "https://twitter.com/twitter_uid",
+				"screenName": "handle",
+				"displayName": "Test User",
+				"futureField": {"nested": true}
+			}
+		]
+	}`)
+	var resp userQueryResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		t.Fatal(err)
+	}
+	user, err := resp.makeUserRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.ProviderUserInfo) != 1 {
+		t.Fatalf("ProviderUserInfo = %d; want = 1", len(user.ProviderUserInfo))
+	}
+	info := *user.ProviderUserInfo[0]
+
+	want := UserInfo{
+		ProviderID:  "twitter.com",
+		UID:         "twitter_uid",
+		FederatedID: "https://twitter.com/twitter_uid",
+		ScreenName:  "handle",
+		DisplayName: "Test User",
+		RawData: map[string]interface{}{
+			"providerId":  "twitter.com",
+			"rawId":       "twitter_uid",
+			"federatedId": "https://twitter.com/twitter_uid",
+			"screenName":  "handle",
+			"displayName": "Test User",
+			"futureField": map[string]interface{}{"nested": true},
+		},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("Unmarshal() = %#v; want = %#v", info, want)
+	}
+
+	out, err := json.Marshal(&info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "futureField") {
+		t.Errorf("Marshal() = %s; want RawData omitted", string(out))
+	}
+}
+
+func TestUserRecordJSONRoundTrip(t *testing.T) {
+	// RawData is not serialized, so the record must not set it.
+	user := &UserRecord{
+		UserInfo: &UserInfo{
+			UID:         "testuser",
+			Email:       "testuser@example.com",
+			DisplayName: "Test User",
+			ProviderID:  defaultProviderID,
+		},
+		Disabled:      true,
+		EmailVerified: true,
+		ProviderUserInfo: []*UserInfo{
+			{
+				ProviderID:  "google.com",
+				UID:         "google_uid",
+				FederatedID: "https://accounts.google.com/google_uid",
+			},
+		},
+		TokensValidAfterMillis: 1494364393000,
+		UserMetadata: &UserMetadata{
+			CreationTimestamp:  1234567890000,
+			LastLogInTimestamp: 1233211232000,
+		},
+		CustomClaims: map[string]interface{}{"admin": true},
+		TenantID:     "testTenant",
+		MultiFactor:  testUser.MultiFactor,
+	}
+
+	b, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got UserRecord
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, user) {
+		t.Errorf("Unmarshal(Marshal(UserRecord)) = %#v; want = %#v", &got, user)
+	}
+
+	exported := &ExportedUserRecord{
+		UserRecord:   user,
+		PasswordHash: "passwordhash",
+		PasswordSalt: "salt",
+	}
+	b, err = json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotExported ExportedUserRecord
+	if err := json.Unmarshal(b, &gotExported); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&gotExported, exported) {
+		t.Errorf("Unmarshal(Marshal(ExportedUserRecord)) = %#v; want = %#v", &gotExported, exported)
+	}
+}
+
 func TestUnsupportedAuthFactor(t *testing.T) {
 	queryResponse := &userQueryResponse{
 		UID: "uid1",
@@ -1981,6 +3086,99 @@ func TestUnsupportedAuthFactor(t *testing.T) {
 	}
 }
 
+func TestUsersModifiedSince(t *testing.T) {
+	resp := `{
+		"users": [
+			{"localId": "inactive", "createdAt": "1000", "lastLoginAt": "2000"},
+			{"localId": "created", "createdAt": "2000000"},
+			{"localId": "signedIn", "createdAt": "1000", "lastLoginAt": "2000000"},
+			{"localId": "refreshed", "createdAt": "1000", "lastRefreshAt": "1970-01-01T00:33:20Z"},
+			{"localId": "revoked", "createdAt": "1000", "validSince": "2000"}
+		]
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	iter := s.Client.UsersModifiedSince(context.Background(), time.Unix(1000, 0))
+	var got []string
+	for {
+		user, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, user.UID)
+	}
+
+	want := []string{"created", "signedIn", "refreshed", "revoked"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UsersModifiedSince() = %v; want = %v", got, want)
+	}
+	if len(s.Req) != 1 {
+		t.Errorf("UsersModifiedSince() = %d requests; want = 1", len(s.Req))
+	}
+}
+
+func TestUsersChan(t *testing.T) {
+	s := echoServer(nil, t)
+	defer s.Close()
+	s.Srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("nextPageToken") == "" {
+			w.Write([]byte(`{"users": [{"localId": "user1"}, {"localId": "user2"}], "nextPageToken": "page2"}`))
+		} else {
+			w.Write([]byte(`{"users": [{"localId": "user3"}]}`))
+		}
+	})
+
+	users, errs := s.Client.UsersChan(context.Background())
+	var got []string
+	for user := range users {
+		got = append(got, user.UID)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"user1", "user2", "user3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UsersChan() = %v; want = %v", got, want)
+	}
+}
+
+func TestUsersChanError(t *testing.T) {
+	s := echoServer([]byte(`{"error": {"message": "INTERNAL_ERROR"}}`), t)
+	defer s.Close()
+	s.Status = http.StatusInternalServerError
+	s.Client.baseClient.httpClient.RetryConfig = nil
+
+	users, errs := s.Client.UsersChan(context.Background())
+	for user := range users {
+		t.Errorf("UsersChan() = %v; want = none", user)
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("UsersChan() = nil; want = error")
+	}
+}
+
+func TestUsersChanCancel(t *testing.T) {
+	s := echoServer([]byte(`{"users": [{"localId": "user1"}, {"localId": "user2"}], "nextPageToken": "next"}`), t)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	users, errs := s.Client.UsersChan(ctx)
+	if user := <-users; user == nil || user.UID != "user1" {
+		t.Fatalf("UsersChan() = %v; want = user1", user)
+	}
+	cancel()
+	for range users {
+	}
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("UsersChan() = %v; want = %v", err, context.Canceled)
+	}
+}
+
 func TestExportedUserRecordShouldClearRedacted(t *testing.T) {
 	queryResponse := &userQueryResponse{
 		UID:          "uid1",
@@ -1996,6 +3194,96 @@ func TestExportedUserRecordShouldClearRedacted(t *testing.T) {
 	}
 }
 
+func TestExportedUserRecordToUserToImport(t *testing.T) {
+	resp := `{
+		"users": [{
+			"localId": "user1",
+			"email": "user1@example.com",
+			"emailVerified": true,
+			"passwordHash": "----AQI",
+			"salt": "__4gc2FsdA",
+			"providerUserInfo": [
+				{"providerId": "password", "rawId": "user1@example.com", "email": "user1@example.com"},
+				{"providerId": "google.com", "rawId": "google-uid", "email": "user1@gmail.com"}
+			]
+		}]
+	}`
+	s := echoServer([]byte(resp), t)
+	defer s.Close()
+
+	exported, err := s.Client.Users(context.Background(), "").Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := exported.ToUserToImport()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = echoServer([]byte("{}"), t)
+	defer s.Close()
+	result, err := s.Client.ImportUsers(context.Background(), []*UserToImport{user}, WithHash(mockHash{
+		key:        "key",
+		saltSep:    ",",
+		rounds:     8,
+		memoryCost: 14,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessCount != 1 || result.FailureCount != 0 {
+		t.Errorf("ImportUsers() = %#v; want = {SuccessCount: 1, FailureCount: 0}", result)
+	}
+
+	var got struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	if err := json.Unmarshal(s.Rbody, &got); err != nil {
+		t.Fatal(err)
+	}
+	imported := got.Users[0]
+	if imported["passwordHash"] != exported.PasswordHash || imported["salt"] != exported.PasswordSalt {
+		t.Errorf("ImportUsers() hash = (%v, %v); want = (%q, %q)",
+			imported["passwordHash"], imported["salt"], exported.PasswordHash, exported.PasswordSalt)
+	}
+	if imported["localId"] != "user1" || imported["email"] != "user1@example.com" || imported["emailVerified"] != true {
+		t.Errorf("ImportUsers() user = %v; want = user1", imported)
+	}
+	providers, _ := imported["providerUserInfo"].([]interface{})
+	if len(providers) != 1 || providers[0].(map[string]interface{})["providerId"] != "google.com" {
+		t.Errorf("ImportUsers() providerUserInfo = %v; want = [google.com]", providers)
+	}
+}
+
+func TestExportedUserRecordToUserToImportPadded(t *testing.T) {
+	exported := &ExportedUserRecord{
+		UserRecord:   &UserRecord{UserInfo: &UserInfo{UID: "user1"}},
+		PasswordHash: "----AQI=",
+		PasswordSalt: "__4gc2FsdA==",
+	}
+	user, err := exported.ToUserToImport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.params["passwordHash"] != "----AQI" || user.params["salt"] != "__4gc2FsdA" {
+		t.Errorf("ToUserToImport() = (%v, %v); want = (%q, %q)",
+			user.params["passwordHash"], user.params["salt"], "----AQI", "__4gc2FsdA")
+	}
+}
+
+func TestExportedUserRecordToUserToImportError(t *testing.T) {
+	cases := []*ExportedUserRecord{
+		{UserRecord: &UserRecord{UserInfo: &UserInfo{UID: "user1"}}, PasswordHash: "not base64!"},
+		{UserRecord: &UserRecord{UserInfo: &UserInfo{UID: "user1"}}, PasswordSalt: "not base64!"},
+	}
+	for _, exported := range cases {
+		if user, err := exported.ToUserToImport(); user != nil || err == nil {
+			t.Errorf("ToUserToImport(%q, %q) = (%v, %v); want = (nil, error)",
+				exported.PasswordHash, exported.PasswordSalt, user, err)
+		}
+	}
+}
+
 var createSessionCookieCases = []struct {
 	expiresIn time.Duration
 	want      float64
@@ -2093,6 +3381,40 @@ func TestSessionCookieShortExpiresIn(t *testing.T) {
 	}
 }
 
+func TestSessionCookieExpiresInError(t *testing.T) {
+	client := &Client{
+		baseClient: &baseClient{},
+	}
+	cases := []struct {
+		expiresIn time.Duration
+		want      string
+	}{
+		{
+			expiresIn: time.Minute,
+			want:      "session cookie duration must be between 5m and 336h; got 1m0s",
+		},
+		{
+			expiresIn: 15 * 24 * time.Hour,
+			want:      "session cookie duration must be between 5m and 336h; got 360h0m0s",
+		},
+		{
+			expiresIn: 0,
+			want:      "session cookie duration must be between 5m and 336h; got 0s",
+		},
+		{
+			expiresIn: time.Duration(3600),
+			want: "session cookie duration must be between 5m and 336h; got 3.6µs; " +
+				"the duration must be a time.Duration such as 24 * time.Hour, not a number of seconds",
+		},
+	}
+	for _, tc := range cases {
+		cookie, err := client.SessionCookie(context.Background(), "idToken", tc.expiresIn)
+		if cookie != "" || err == nil || err.Error() != tc.want {
+			t.Errorf("SessionCookie(%v) = (%q, %v); want = (\"\", %q)", tc.expiresIn, cookie, err, tc.want)
+		}
+	}
+}
+
 func TestSessionCookieLongExpiresIn(t *testing.T) {
 	client := &Client{
 		baseClient: &baseClient{},
@@ -16,6 +16,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 )
 
@@ -104,3 +105,94 @@ func TestAuthOverrideRangeQuery(t *testing.T) {
 		},
 	})
 }
+
+func TestNewRefWithAuthOverride(t *testing.T) {
+	mock := &mockServer{Resp: "data"}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	claims := map[string]interface{}{"uid": "user2"}
+	ref, err := client.NewRefWithAuthOverride("peter", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(claims)
+	wantAO := string(b)
+
+	var got string
+	if err := ref.Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.Child("name").Set(context.Background(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.Child("name").Parent().OrderByKey().Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := testref.Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	checkAllRequests(t, mock.Reqs, []*testReq{
+		{
+			Method: "GET",
+			Path:   "/peter.json",
+			Query:  map[string]string{"auth_variable_override": wantAO},
+		},
+		{
+			Method: "PUT",
+			Path:   "/peter/name.json",
+			Body:   serialize("foo"),
+			Query:  map[string]string{"auth_variable_override": wantAO, "print": "silent"},
+		},
+		{
+			Method: "GET",
+			Path:   "/peter.json",
+			Query:  map[string]string{"auth_variable_override": wantAO, "orderBy": "\"$key\""},
+		},
+		{
+			Method: "GET",
+			Path:   "/peter.json",
+		},
+	})
+}
+
+func TestNewRefWithAuthOverrideSpecialValues(t *testing.T) {
+	mock := &mockServer{Resp: "data"}
+	srv := mock.Start(aoClient)
+	defer srv.Close()
+
+	unauth, err := aoClient.NewRefWithAuthOverride("peter", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := aoClient.NewRefWithAuthOverride("peter", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	if err := unauth.Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	checkAllRequests(t, mock.Reqs, []*testReq{
+		{
+			Method: "GET",
+			Path:   "/peter.json",
+			Query:  map[string]string{"auth_variable_override": "null"},
+		},
+		{
+			Method: "GET",
+			Path:   "/peter.json",
+		},
+	})
+}
+
+func TestNewRefWithInvalidAuthOverride(t *testing.T) {
+	ref, err := client.NewRefWithAuthOverride("peter", map[string]interface{}{"uid": func() {}})
+	if ref != nil || err == nil {
+		t.Errorf("NewRefWithAuthOverride() = (%v, %v); want = (nil, error)", ref, err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+var cacheClock internal.Clock = internal.SystemClock
+
+// readCache holds the raw JSON values read via GetCached(), keyed by database path and the auth
+// variable override used to read them.
+type readCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+}
+
+type cacheKey struct {
+	path         string
+	authOverride string
+}
+
+type cacheEntry struct {
+	data   []byte
+	expiry time.Time
+}
+
+func (c *readCache) get(key cacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !cacheClock.Now().Before(entry.expiry) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (c *readCache) put(key cacheKey, data []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[cacheKey]*cacheEntry)
+	}
+	c.entries[key] = &cacheEntry{
+		data:   data,
+		expiry: cacheClock.Now().Add(ttl),
+	}
+}
+
+// invalidate removes the entries of the given path, and of all paths above and below it. Those
+// are the entries that a write to path may render stale.
+func (c *readCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if isSameOrDescendant(key.path, path) || isSameOrDescendant(path, key.path) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *readCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+func isSameOrDescendant(path, parent string) bool {
+	return parent == "/" || path == parent || strings.HasPrefix(path, parent+"/")
+}
+
+// GetCached retrieves the value at the current database location like Get(), but serves it from
+// an in-memory cache if it was read within the given TTL.
+//
+// The cache is maintained by the Client, and shared by all references to the same location that
+// use the same auth variable override.
+// Writes made through the Client evict the affected cache entries, but changes made by other
+// clients are not observed until the cached value expires. Use InvalidateCache() or
+// Client.ClearCache() to evict entries explicitly.
+func (r *Ref) GetCached(ctx context.Context, v interface{}, ttl time.Duration) error {
+	key := cacheKey{r.Path, r.authOverride}
+	if data, ok := r.client.cache.get(key); ok {
+		return json.Unmarshal(data, v)
+	}
+
+	req := &internal.Request{
+		Method: http.MethodGet,
+	}
+	resp, err := r.sendAndUnmarshal(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return err
+	}
+
+	if ttl > 0 {
+		r.client.cache.put(key, resp.Body, ttl)
+	}
+	return nil
+}
+
+// InvalidateCache evicts the cached values of the current database location, and of all the
+// locations above and below it.
+func (r *Ref) InvalidateCache() {
+	r.client.cache.invalidate(r.Path)
+}
+
+// ClearCache evicts all values cached by GetCached().
+func (c *Client) ClearCache() {
+	c.cache.clear()
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+func setupCacheTest(t *testing.T) *internal.MockClock {
+	clock := &internal.MockClock{Timestamp: time.Now()}
+	cacheClock = clock
+	client.ClearCache()
+	t.Cleanup(func() {
+		cacheClock = internal.SystemClock
+		client.ClearCache()
+	})
+	return clock
+}
+
+func TestGetCached(t *testing.T) {
+	clock := setupCacheTest(t)
+	want := map[string]interface{}{"name": "Peter Parker", "age": float64(17)}
+	mock := &mockServer{Resp: want}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		var got map[string]interface{}
+		if err := testref.GetCached(context.Background(), &got, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("GetCached() = %v; want = %v", got, want)
+		}
+	}
+	checkOnlyRequest(t, mock.Reqs, &testReq{Method: "GET", Path: "/peter.json"})
+
+	// Other references to the same location share the cache.
+	var got map[string]interface{}
+	if err := client.NewRef("/peter/").GetCached(context.Background(), &got, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.Reqs) != 1 {
+		t.Errorf("GetCached() = %d requests; want = 1", len(mock.Reqs))
+	}
+
+	clock.Timestamp = clock.Timestamp.Add(time.Minute)
+	if err := testref.GetCached(context.Background(), &got, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.Reqs) != 2 {
+		t.Errorf("GetCached() after expiry = %d requests; want = 2", len(mock.Reqs))
+	}
+}
+
+func TestGetCachedZeroTTL(t *testing.T) {
+	setupCacheTest(t)
+	mock := &mockServer{Resp: "foo"}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		var got string
+		if err := testref.GetCached(context.Background(), &got, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(mock.Reqs) != 2 {
+		t.Errorf("GetCached() = %d requests; want = 2", len(mock.Reqs))
+	}
+}
+
+func TestGetCachedError(t *testing.T) {
+	setupCacheTest(t)
+	mock := &mockServer{
+		Resp:   map[string]string{"error": "test error"},
+		Status: http.StatusInternalServerError,
+	}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	var got string
+	if err := testref.GetCached(context.Background(), &got, time.Minute); err == nil {
+		t.Errorf("GetCached() = nil; want = error")
+	}
+	if _, ok := client.cache.get(cacheKey{testref.Path, ""}); ok {
+		t.Errorf("GetCached() cached an error response")
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func() error
+	}{
+		{"InvalidateCache", func() error {
+			testref.InvalidateCache()
+			return nil
+		}},
+		{"InvalidateCacheParent", func() error {
+			client.NewRef("/").InvalidateCache()
+			return nil
+		}},
+		{"ClearCache", func() error {
+			client.ClearCache()
+			return nil
+		}},
+		{"Set", func() error {
+			return testref.Set(context.Background(), "bar")
+		}},
+		{"SetChild", func() error {
+			return testref.Child("name").Set(context.Background(), "bar")
+		}},
+		{"DeleteParent", func() error {
+			return client.NewRef("/").Delete(context.Background())
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupCacheTest(t)
+			mock := &mockServer{Resp: "foo"}
+			srv := mock.Start(client)
+			defer srv.Close()
+
+			var got string
+			if err := testref.GetCached(context.Background(), &got, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.fn(); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := client.cache.get(cacheKey{testref.Path, ""}); ok {
+				t.Errorf("%s: cache entry not evicted", tc.name)
+			}
+		})
+	}
+}
+
+func TestCacheUnrelatedWrite(t *testing.T) {
+	setupCacheTest(t)
+	mock := &mockServer{Resp: "foo"}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	var got string
+	if err := testref.GetCached(context.Background(), &got, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.NewRef("/peterson").Set(context.Background(), "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.cache.get(cacheKey{testref.Path, ""}); !ok {
+		t.Errorf("cache entry evicted by unrelated write")
+	}
+}
+
+func TestGetCachedWithAuthOverride(t *testing.T) {
+	setupCacheTest(t)
+	mock := &mockServer{Resp: "foo"}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	userRef, err := client.NewRefWithAuthOverride("peter", map[string]interface{}{"uid": "user1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if err := testref.GetCached(context.Background(), &got, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := userRef.GetCached(context.Background(), &got, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.Reqs) != 2 {
+		t.Errorf("GetCached() = %d requests; want = 2", len(mock.Reqs))
+	}
+}
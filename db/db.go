@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"runtime"
@@ -35,6 +36,8 @@ const invalidChars = "[].#$"
 const authVarOverride = "auth_variable_override"
 const emulatorDatabaseEnvVar = "FIREBASE_DATABASE_EMULATOR_HOST"
 const emulatorNamespaceParam = "ns"
+const rtdbErrorCode = "rtdbErrorCode"
+const permissionDenied = "PERMISSION_DENIED"
 
 // errInvalidURL tells whether the given database url is invalid
 // It is invalid if it is malformed, or not of the format "host:port"
@@ -49,6 +52,8 @@ type Client struct {
 	hc           *internal.HTTPClient
 	dbURLConfig  *dbURLConfig
 	authOverride string
+	prettyPrint  bool
+	cache        readCache
 }
 
 type dbURLConfig struct {
@@ -72,12 +77,9 @@ func NewClient(ctx context.Context, c *internal.DatabaseConfig) (*Client, error)
 		return nil, err
 	}
 
-	var ao []byte
-	if c.AuthOverride == nil || len(c.AuthOverride) > 0 {
-		ao, err = json.Marshal(c.AuthOverride)
-		if err != nil {
-			return nil, err
-		}
+	ao, err := encodeAuthOverride(c.AuthOverride)
+	if err != nil {
+		return nil, err
 	}
 
 	opts := append([]option.ClientOption{}, c.Opts...)
@@ -86,22 +88,50 @@ func NewClient(ctx context.Context, c *internal.DatabaseConfig) (*Client, error)
 		opts = append(opts, option.WithTokenSource(ts))
 	}
 	ua := fmt.Sprintf(userAgentFormat, c.Version, runtime.Version())
+	if c.ClientInfo != "" {
+		ua = fmt.Sprintf("%s %s", ua, c.ClientInfo)
+	}
 	opts = append(opts, option.WithUserAgent(ua))
 	hc, _, err := internal.NewHTTPClient(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
+	c.Tracker.TrackHTTPClient(hc.Client)
 
 	hc.CreateErrFn = handleRTDBError
+	hc.Opts = []internal.HTTPOption{
+		internal.WithClientVersion(c.Version),
+	}
+	hc.MaxResponseSize = c.MaxResponseSize
 	return &Client{
 		hc:           hc,
 		dbURLConfig:  urlConfig,
-		authOverride: string(ao),
+		authOverride: ao,
+		prettyPrint:  c.PrettyPrint,
 	}, nil
 }
 
 // NewRef returns a new database reference representing the node at the specified path.
 func (c *Client) NewRef(path string) *Ref {
+	return c.newRef(path, c.authOverride)
+}
+
+// NewRefWithAuthOverride returns a new database reference representing the node at the specified
+// path, which accesses the database as the user described by the given claims.
+//
+// The claims become the auth variable of the security rules for all operations performed via the
+// returned reference, and the references and queries derived from it. This takes precedence over
+// the auth variable override of the Client. A nil map makes the operations unauthenticated, and an
+// empty map makes them run with admin privileges.
+func (c *Client) NewRefWithAuthOverride(path string, claims map[string]interface{}) (*Ref, error) {
+	ao, err := encodeAuthOverride(claims)
+	if err != nil {
+		return nil, err
+	}
+	return c.newRef(path, ao), nil
+}
+
+func (c *Client) newRef(path, authOverride string) *Ref {
 	segs := parsePath(path)
 	key := ""
 	if len(segs) > 0 {
@@ -109,26 +139,41 @@ func (c *Client) NewRef(path string) *Ref {
 	}
 
 	return &Ref{
-		Key:    key,
-		Path:   "/" + strings.Join(segs, "/"),
-		client: c,
-		segs:   segs,
+		Key:          key,
+		Path:         "/" + strings.Join(segs, "/"),
+		client:       c,
+		segs:         segs,
+		authOverride: authOverride,
 	}
 }
 
+func encodeAuthOverride(ao map[string]interface{}) (string, error) {
+	if ao != nil && len(ao) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(ao)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func (c *Client) sendAndUnmarshal(
-	ctx context.Context, req *internal.Request, v interface{}) (*internal.Response, error) {
+	ctx context.Context, req *internal.Request, authOverride string, v interface{}) (*internal.Response, error) {
 	if strings.ContainsAny(req.URL, invalidChars) {
 		return nil, fmt.Errorf("invalid path with illegal characters: %q", req.URL)
 	}
 
 	req.URL = fmt.Sprintf("%s%s.json", c.dbURLConfig.BaseURL, req.URL)
-	if c.authOverride != "" {
-		req.Opts = append(req.Opts, internal.WithQueryParam(authVarOverride, c.authOverride))
+	if authOverride != "" {
+		req.Opts = append(req.Opts, internal.WithQueryParam(authVarOverride, authOverride))
 	}
 	if c.dbURLConfig.Namespace != "" {
 		req.Opts = append(req.Opts, internal.WithQueryParam(emulatorNamespaceParam, c.dbURLConfig.Namespace))
 	}
+	if c.prettyPrint && req.Method == http.MethodGet {
+		req.Opts = append(req.Opts, internal.WithQueryParam("print", "pretty"))
+	}
 
 	return c.hc.DoAndUnmarshal(ctx, req, v)
 }
@@ -153,9 +198,28 @@ func handleRTDBError(resp *internal.Response) error {
 		err.String = fmt.Sprintf("http error status: %d; reason: %s", resp.Status, p.Error)
 	}
 
+	// Requests rejected by security rules fail with a 401 "Permission denied" response, which is
+	// otherwise indistinguishable from an authentication failure.
+	if resp.Status == http.StatusForbidden ||
+		(resp.Status == http.StatusUnauthorized && strings.EqualFold(p.Error, "permission denied")) {
+		err.Ext[rtdbErrorCode] = permissionDenied
+	}
 	return err
 }
 
+// IsPermissionDenied checks if the given error was due to the request being rejected by the
+// security rules of the database, or the caller otherwise lacking permission to access the
+// requested location.
+func IsPermissionDenied(err error) bool {
+	fe, ok := err.(*internal.FirebaseError)
+	if !ok {
+		return false
+	}
+
+	got, ok := fe.Ext[rtdbErrorCode]
+	return ok && got == permissionDenied
+}
+
 // parseURLConfig returns the dbURLConfig for the database
 // dbURL may be either:
 //   - a production url (https://foo-bar.firebaseio.com/)
@@ -164,7 +228,8 @@ func handleRTDBError(resp *internal.Response) error {
 // The following rules will apply for determining the output:
 //   - If the url does not use an https scheme it will be assumed to be an emulator url and be used.
 //   - else If the FIREBASE_DATABASE_EMULATOR_HOST environment variable is set it will be used.
-//   - else the url will be assumed to be a production url and be used.
+//   - else the url will be assumed to be a production url and be used, provided it refers to a
+//     firebaseio.com or firebasedatabase.app host.
 func parseURLConfig(dbURL string) (*dbURLConfig, bool, error) {
 	parsedURL, err := url.ParseRequestURI(dbURL)
 	if err == nil && parsedURL.Scheme != "https" {
@@ -185,6 +250,9 @@ func parseURLConfig(dbURL string) (*dbURLConfig, bool, error) {
 	if err != nil {
 		return nil, false, fmt.Errorf("%s: %w", dbURL, errInvalidURL)
 	}
+	if !isDatabaseHost(parsedURL.Hostname()) {
+		return nil, false, fmt.Errorf("%s: host must be a firebaseio.com or firebasedatabase.app domain: %w", dbURL, errInvalidURL)
+	}
 
 	return &dbURLConfig{
 		BaseURL:   dbURL,
@@ -192,6 +260,18 @@ func parseURLConfig(dbURL string) (*dbURLConfig, bool, error) {
 	}, false, nil
 }
 
+var databaseHostSuffixes = []string{".firebaseio.com", ".firebasedatabase.app"}
+
+func isDatabaseHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, suffix := range databaseHostSuffixes {
+		if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 func parseEmulatorHost(rawEmulatorHostURL string, parsedEmulatorHost *url.URL) (*dbURLConfig, error) {
 	if strings.Contains(rawEmulatorHostURL, "//") {
 		return nil, fmt.Errorf(`invalid %s: "%s". It must follow format "host:port": %w`, emulatorDatabaseEnvVar, rawEmulatorHostURL, errInvalidURL)
@@ -183,10 +183,36 @@ func TestNewClientAuthOverrides(t *testing.T) {
 	}
 }
 
+func TestNewClientWithClientInfo(t *testing.T) {
+	c, err := NewClient(context.Background(), &internal.DatabaseConfig{
+		Opts:       testOpts,
+		URL:        testURL,
+		Version:    "1.2.3",
+		ClientInfo: "my-app/4.5.6",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockServer{Resp: "foo"}
+	srv := mock.Start(c)
+	defer srv.Close()
+
+	var got string
+	if err := c.NewRef("peter").Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := testUserAgent + " my-app/4.5.6"
+	if h := mock.Reqs[0].Header.Get("User-Agent"); h != want {
+		t.Errorf("User-Agent = %q; want = %q", h, want)
+	}
+}
+
 func TestValidURLS(t *testing.T) {
 	cases := []string{
 		"https://test-db.firebaseio.com",
 		"https://test-db.firebasedatabase.app",
+		"https://test-db.europe-west1.firebasedatabase.app",
+		"https://TEST-DB.FIREBASEIO.COM",
 	}
 	for _, tc := range cases {
 		c, err := NewClient(context.Background(), &internal.DatabaseConfig{
@@ -209,6 +235,9 @@ func TestInvalidURL(t *testing.T) {
 		"http://db.firebaseio.com",
 		"http://firebase.google.com",
 		"http://localhost:9000",
+		"https://firebaseio.com",
+		"https://test-db.example.com",
+		"https://test-db.firebaseio.com.example.com",
 	}
 	for _, tc := range cases {
 		c, err := NewClient(context.Background(), &internal.DatabaseConfig{
@@ -345,6 +374,9 @@ func checkRequest(t *testing.T, got, want *testReq) {
 	if h := got.Header.Get("User-Agent"); h != testUserAgent {
 		t.Errorf("User-Agent = %q; want = %q", h, testUserAgent)
 	}
+	if h := got.Header.Get("X-Client-Version"); h != "Go/Admin/1.2.3" {
+		t.Errorf("X-Client-Version = %q; want = %q", h, "Go/Admin/1.2.3")
+	}
 
 	if got.Method != want.Method {
 		t.Errorf("Method = %q; want = %q", got.Method, want.Method)
@@ -43,9 +43,15 @@ type QueryNode interface {
 type Query struct {
 	client              *Client
 	path                string
+	authOverride        string
 	order               orderBy
 	limFirst, limLast   int
 	start, end, equalTo interface{}
+	params              []queryParam
+}
+
+type queryParam struct {
+	key, value string
 }
 
 // StartAt returns a shallow copy of the Query with v set as a lower bound of a range query.
@@ -96,6 +102,19 @@ func (q *Query) LimitToLast(n int) *Query {
 	return q2
 }
 
+// WithParam returns a shallow copy of the Query with an additional REST query parameter.
+//
+// WithParam is an escape hatch for query parameters that are not yet supported by the other
+// methods of Query, and the value is sent to the server as is. Parameters that conflict with each
+// other, or with the ones set by the other methods of Query (e.g. a second orderBy), cause the
+// Query to fail before any request is sent.
+func (q *Query) WithParam(key, value string) *Query {
+	q2 := &Query{}
+	*q2 = *q
+	q2.params = append(append([]queryParam{}, q.params...), queryParam{key, value})
+	return q2
+}
+
 // Get executes the Query and populates v with the results.
 //
 // Data deserialization is performed using https://golang.org/pkg/encoding/json/#Unmarshal, and
@@ -115,7 +134,7 @@ func (q *Query) Get(ctx context.Context, v interface{}) error {
 		URL:    q.path,
 		Opts:   []internal.HTTPOption{internal.WithQueryParams(qp)},
 	}
-	_, err := q.client.sendAndUnmarshal(ctx, req, v)
+	_, err := q.client.sendAndUnmarshal(ctx, req, q.authOverride, v)
 	return err
 }
 
@@ -167,9 +186,10 @@ func (r *Ref) OrderByValue() *Query {
 
 func newQuery(r *Ref, ob orderBy) *Query {
 	return &Query{
-		client: r.client,
-		path:   r.Path,
-		order:  ob,
+		client:       r.client,
+		path:         r.Path,
+		authOverride: r.authOverride,
+		order:        ob,
 	}
 }
 
@@ -200,7 +220,30 @@ func initQueryParams(q *Query, qp map[string]string) error {
 	if err := encodeFilter("endAt", q.end, qp); err != nil {
 		return err
 	}
-	return encodeFilter("equalTo", q.equalTo, qp)
+	if err := encodeFilter("equalTo", q.equalTo, qp); err != nil {
+		return err
+	}
+	return addRawParams(q.params, qp)
+}
+
+func addRawParams(params []queryParam, qp map[string]string) error {
+	for _, p := range params {
+		if p.key == "" {
+			return fmt.Errorf("query parameter name must not be empty")
+		} else if p.key == authVarOverride || p.key == emulatorNamespaceParam {
+			return fmt.Errorf("query parameter %q is reserved", p.key)
+		} else if _, ok := qp[p.key]; ok {
+			return fmt.Errorf("conflicting values for query parameter %q", p.key)
+		}
+		qp[p.key] = p.value
+	}
+
+	_, first := qp["limitToFirst"]
+	_, last := qp["limitToLast"]
+	if first && last {
+		return fmt.Errorf("cannot set both limitToFirst and limitToLast query parameters")
+	}
+	return nil
 }
 
 func encodeFilter(key string, val interface{}, m map[string]string) error {
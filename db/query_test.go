@@ -425,6 +425,74 @@ func TestAllParamsQuery(t *testing.T) {
 	})
 }
 
+func TestRawParamQuery(t *testing.T) {
+	want := map[string]interface{}{"m1": "Hello", "m2": "Bye"}
+	mock := &mockServer{Resp: want}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	q := testref.OrderByKey().LimitToLast(10).WithParam("endBefore", `"m3"`)
+	var got map[string]interface{}
+	if err := q.Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("WithParam() = %v; want = %v", got, want)
+	}
+	checkOnlyRequest(t, mock.Reqs, &testReq{
+		Method: "GET",
+		Path:   "/peter.json",
+		Query: map[string]string{
+			"limitToLast": "10",
+			"endBefore":   "\"m3\"",
+			"orderBy":     "\"$key\"",
+		},
+	})
+}
+
+func TestInvalidRawParamQuery(t *testing.T) {
+	mock := &mockServer{Resp: "test"}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	q := testref.OrderByChild("messages")
+	cases := []struct {
+		name string
+		q    *Query
+		want string
+	}{
+		{"EmptyKey", q.WithParam("", "foo"), "query parameter name must not be empty"},
+		{"OrderBy", q.WithParam("orderBy", `"$key"`), `conflicting values for query parameter "orderBy"`},
+		{"Duplicate", q.WithParam("endBefore", "1").WithParam("endBefore", "2"),
+			`conflicting values for query parameter "endBefore"`},
+		{"TypedLimit", q.LimitToFirst(10).WithParam("limitToFirst", "5"),
+			`conflicting values for query parameter "limitToFirst"`},
+		{"BothLimits", q.LimitToFirst(10).WithParam("limitToLast", "5"),
+			"cannot set both limitToFirst and limitToLast query parameters"},
+		{"AuthOverride", q.WithParam(authVarOverride, "{}"), `query parameter "auth_variable_override" is reserved`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got interface{}
+			if err := tc.q.Get(context.Background(), &got); got != nil || err == nil || err.Error() != tc.want {
+				t.Errorf("Get(%q) = (%v, %v); want = (nil, %q)", tc.name, got, err, tc.want)
+			}
+			if len(mock.Reqs) != 0 {
+				t.Errorf("Get(%q) = %v; want = empty", tc.name, mock.Reqs)
+			}
+		})
+	}
+}
+
+func TestWithParamDoesNotModifyQuery(t *testing.T) {
+	q := testref.OrderByChild("messages").WithParam("a", "1")
+	q1 := q.WithParam("b", "2")
+	q2 := q.WithParam("c", "3")
+	if len(q.params) != 1 || len(q1.params) != 2 || len(q2.params) != 2 || q1.params[1].key != "b" {
+		t.Errorf("WithParam() shares state: %v, %v, %v", q.params, q1.params, q2.params)
+	}
+}
+
 func TestChildQueryGetOrdered(t *testing.T) {
 	mock := &mockServer{Resp: sortableKeysResp}
 	srv := mock.Start(client)
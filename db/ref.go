@@ -33,8 +33,9 @@ type Ref struct {
 	Key  string
 	Path string
 
-	segs   []string
-	client *Client
+	segs         []string
+	client       *Client
+	authOverride string
 }
 
 // TransactionNode represents the value of a node within the scope of a transaction.
@@ -57,7 +58,7 @@ func (r *Ref) Parent() *Ref {
 	l := len(r.segs)
 	if l > 0 {
 		path := strings.Join(r.segs[:l-1], "/")
-		return r.client.NewRef(path)
+		return r.client.newRef(path, r.authOverride)
 	}
 	return nil
 }
@@ -65,7 +66,7 @@ func (r *Ref) Parent() *Ref {
 // Child returns a reference to the specified child node.
 func (r *Ref) Child(path string) *Ref {
 	fp := fmt.Sprintf("%s/%s", r.Path, path)
-	return r.client.NewRef(fp)
+	return r.client.newRef(fp, r.authOverride)
 }
 
 // Get retrieves the value at the current database location, and stores it in the value pointed to
@@ -82,6 +83,19 @@ func (r *Ref) Get(ctx context.Context, v interface{}) error {
 	return err
 }
 
+// GetRaw retrieves the value at the current database location as it was returned by the server,
+// without deserializing it.
+func (r *Ref) GetRaw(ctx context.Context) ([]byte, error) {
+	req := &internal.Request{
+		Method: http.MethodGet,
+	}
+	resp, err := r.sendAndUnmarshal(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
 // GetWithETag retrieves the value at the current database location, along with its ETag.
 func (r *Ref) GetWithETag(ctx context.Context, v interface{}) (string, error) {
 	req := &internal.Request{
@@ -112,6 +126,21 @@ func (r *Ref) GetShallow(ctx context.Context, v interface{}) error {
 	return err
 }
 
+// ChildCount returns the number of child nodes of the current database location.
+//
+// The Realtime Database does not support counting children on the server. ChildCount performs a
+// shallow read, and counts the keys in the result. While the values of the child nodes are not
+// retrieved, all the keys are, so the cost of ChildCount is proportional to the number of
+// children. Returns 0 if the location does not exist, or holds a primitive value.
+func (r *Ref) ChildCount(ctx context.Context) (int, error) {
+	var v interface{}
+	if err := r.GetShallow(ctx, &v); err != nil {
+		return 0, err
+	}
+	children, _ := v.(map[string]interface{})
+	return len(children), nil
+}
+
 // GetIfChanged retrieves the value and ETag of the current database location only if the specified
 // ETag does not match.
 //
@@ -225,6 +254,46 @@ func (r *Ref) Update(ctx context.Context, v map[string]interface{}) error {
 	return err
 }
 
+// Merge deeply merges the provided values into the current location.
+//
+// Unlike Update(), which replaces the values of the specified child keys, Merge() flattens nested
+// maps into slash-delimited paths (e.g. {"a": {"b": 1}} becomes {"a/b": 1}), and performs a single
+// multi-path update. Therefore only the leaf values are written, and the siblings of the specified
+// keys at every level of nesting are left intact. Only values of type map[string]interface{} are
+// flattened. Other values, including structs and empty maps, are written as is, and replace the
+// data at their paths. An error is returned if two keys flatten to the same path.
+func (r *Ref) Merge(ctx context.Context, v map[string]interface{}) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value argument must be a non-empty map")
+	}
+
+	paths := make(map[string]interface{})
+	if err := flattenPaths("", v, paths); err != nil {
+		return err
+	}
+	return r.Update(ctx, paths)
+}
+
+func flattenPaths(prefix string, v map[string]interface{}, paths map[string]interface{}) error {
+	for key, value := range v {
+		path := strings.Trim(key, "/")
+		if prefix != "" {
+			path = prefix + "/" + path
+		}
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			if err := flattenPaths(path, child, paths); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := paths[path]; ok {
+			return fmt.Errorf("duplicate path in merge: %q", path)
+		}
+		paths[path] = value
+	}
+	return nil
+}
+
 // UpdateFn represents a function type that can be passed into Transaction().
 type UpdateFn func(TransactionNode) (interface{}, error)
 
@@ -295,7 +364,13 @@ func (r *Ref) Delete(ctx context.Context) error {
 func (r *Ref) sendAndUnmarshal(
 	ctx context.Context, req *internal.Request, v interface{}) (*internal.Response, error) {
 	req.URL = r.Path
-	return r.client.sendAndUnmarshal(ctx, req, v)
+	resp, err := r.client.sendAndUnmarshal(ctx, req, r.authOverride, v)
+	if req.Method != http.MethodGet {
+		// Evict cached values regardless of the outcome, since a failed write may still have been
+		// applied by the server.
+		r.client.cache.invalidate(r.Path)
+	}
+	return resp, err
 }
 
 func successOrNotModified(resp *internal.Response) bool {
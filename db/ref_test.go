@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/internal"
 )
 
 type refOp func(r *Ref) error
@@ -197,6 +198,93 @@ func TestGetShallow(t *testing.T) {
 	checkAllRequests(t, mock.Reqs, want)
 }
 
+func TestChildCount(t *testing.T) {
+	mock := &mockServer{}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	cases := []struct {
+		resp interface{}
+		want int
+	}{
+		{nil, 0},
+		{"foo", 0},
+		{map[string]interface{}{"name": true, "age": true, "nestedChild": true}, 3},
+	}
+	wantQuery := map[string]string{"shallow": "true"}
+	var want []*testReq
+	for _, tc := range cases {
+		mock.Resp = tc.resp
+		got, err := testref.ChildCount(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("ChildCount(%v) = %d; want = %d", tc.resp, got, tc.want)
+		}
+		want = append(want, &testReq{Method: "GET", Path: "/peter.json", Query: wantQuery})
+	}
+	checkAllRequests(t, mock.Reqs, want)
+}
+
+func TestChildCountError(t *testing.T) {
+	mock := &mockServer{Status: http.StatusInternalServerError}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	if got, err := testref.ChildCount(context.Background()); got != 0 || err == nil {
+		t.Errorf("ChildCount() = (%d, %v); want = (0, error)", got, err)
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	mock := &mockServer{Resp: map[string]interface{}{"name": "Peter Parker"}}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	got, err := testref.GetRaw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name":"Peter Parker"}`; string(got) != want {
+		t.Errorf("GetRaw() = %q; want = %q", string(got), want)
+	}
+	checkOnlyRequest(t, mock.Reqs, &testReq{Method: "GET", Path: "/peter.json"})
+}
+
+func TestPrettyPrint(t *testing.T) {
+	ppClient, err := NewClient(context.Background(), &internal.DatabaseConfig{
+		Opts:         testOpts,
+		URL:          testURL,
+		Version:      "1.2.3",
+		AuthOverride: map[string]interface{}{},
+		PrettyPrint:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockServer{Resp: "foo"}
+	srv := mock.Start(ppClient)
+	defer srv.Close()
+
+	ref := ppClient.NewRef("peter")
+	if _, err := ref.GetRaw(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if err := ref.OrderByKey().Get(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.Set(context.Background(), "bar"); err != nil {
+		t.Fatal(err)
+	}
+	checkAllRequests(t, mock.Reqs, []*testReq{
+		{Method: "GET", Path: "/peter.json", Query: map[string]string{"print": "pretty"}},
+		{Method: "GET", Path: "/peter.json", Query: map[string]string{"print": "pretty", "orderBy": "\"$key\""}},
+		{Method: "PUT", Path: "/peter.json", Body: serialize("bar"), Query: map[string]string{"print": "silent"}},
+	})
+}
+
 func TestGetWithETag(t *testing.T) {
 	want := map[string]interface{}{"name": "Peter Parker", "age": float64(17)}
 	mock := &mockServer{
@@ -392,6 +480,41 @@ func TestPlatformErrorCodes(t *testing.T) {
 	}
 }
 
+func TestPermissionDenied(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		reason string
+		want   bool
+	}{
+		{"RulesRejection", http.StatusUnauthorized, "Permission denied", true},
+		{"Forbidden", http.StatusForbidden, "test error", true},
+		{"Unauthenticated", http.StatusUnauthorized, "Unauthorized request.", false},
+		{"NotFound", http.StatusNotFound, "test error", false},
+		{"Internal", http.StatusInternalServerError, "Permission denied", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockServer{Resp: map[string]string{"error": tc.reason}, Status: tc.status}
+			srv := mock.Start(client)
+			defer srv.Close()
+
+			var got interface{}
+			err := testref.Get(context.Background(), &got)
+			if err == nil {
+				t.Fatalf("Get() = nil; want = error")
+			}
+			if IsPermissionDenied(err) != tc.want {
+				t.Errorf("IsPermissionDenied(%v) = %v; want = %v", err, !tc.want, tc.want)
+			}
+		})
+	}
+
+	if IsPermissionDenied(fmt.Errorf("permission denied")) {
+		t.Errorf("IsPermissionDenied(non-firebase error) = true; want = false")
+	}
+}
+
 func TestInvalidPath(t *testing.T) {
 	mock := &mockServer{Resp: "test"}
 	srv := mock.Start(client)
@@ -600,6 +723,54 @@ func TestInvalidUpdate(t *testing.T) {
 	}
 }
 
+func TestMerge(t *testing.T) {
+	mock := &mockServer{}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	data := map[string]interface{}{
+		"name": "Peter Parker",
+		"address": map[string]interface{}{
+			"city": "New York",
+			"geo":  map[string]interface{}{"lat": 40.7, "lng": -74.0},
+		},
+		"/suit/":  map[string]interface{}{"color": "red"},
+		"friends": map[string]interface{}{},
+		"person":  &person{"Mary Jane", 17},
+	}
+	if err := testref.Merge(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":            "Peter Parker",
+		"address/city":    "New York",
+		"address/geo/lat": 40.7,
+		"address/geo/lng": -74.0,
+		"suit/color":      "red",
+		"friends":         map[string]interface{}{},
+		"person":          &person{"Mary Jane", 17},
+	}
+	checkOnlyRequest(t, mock.Reqs, &testReq{
+		Method: "PATCH",
+		Path:   "/peter.json",
+		Body:   serialize(want),
+		Query:  map[string]string{"print": "silent"},
+	})
+}
+
+func TestInvalidMerge(t *testing.T) {
+	cases := []map[string]interface{}{
+		nil,
+		make(map[string]interface{}),
+		{"a/b": 1, "a": map[string]interface{}{"b": 2}},
+	}
+	for _, tc := range cases {
+		if err := testref.Merge(context.Background(), tc); err == nil {
+			t.Errorf("Merge(%v) = nil; want error", tc)
+		}
+	}
+}
+
 func TestTransaction(t *testing.T) {
 	mock := &mockServer{
 		Resp:   &person{"Peter Parker", 17},
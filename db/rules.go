@@ -0,0 +1,69 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+const rulesPath = "/.settings/rules.json"
+
+// GetRules retrieves the security rules of the database.
+//
+// The rules are returned as sent by the database, and may therefore contain comments, which are
+// not valid JSON. Reading the rules requires admin privileges. The auth variable override of the
+// Client is not applied to this call.
+func (c *Client) GetRules(ctx context.Context) (json.RawMessage, error) {
+	req := &internal.Request{
+		Method: http.MethodGet,
+	}
+	resp, err := c.sendRules(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(resp.Body), nil
+}
+
+// SetRules replaces the security rules of the database with the given rules.
+//
+// The rules must be a JSON object with a top-level "rules" key. Updating the rules requires admin
+// privileges. The auth variable override of the Client is not applied to this call.
+func (c *Client) SetRules(ctx context.Context, rules json.RawMessage) error {
+	if len(rules) == 0 {
+		return errors.New("rules must not be empty")
+	}
+	if !json.Valid(rules) {
+		return errors.New("rules must be valid JSON")
+	}
+	req := &internal.Request{
+		Method: http.MethodPut,
+		Body:   internal.NewJSONEntity(rules),
+	}
+	_, err := c.sendRules(ctx, req)
+	return err
+}
+
+func (c *Client) sendRules(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+	req.URL = c.dbURLConfig.BaseURL + rulesPath
+	if c.dbURLConfig.Namespace != "" {
+		req.Opts = append(req.Opts, internal.WithQueryParam(emulatorNamespaceParam, c.dbURLConfig.Namespace))
+	}
+	return c.hc.Do(ctx, req)
+}
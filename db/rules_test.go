@@ -0,0 +1,98 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var testRules = map[string]interface{}{
+	"rules": map[string]interface{}{
+		".read":  "auth != null",
+		".write": false,
+	},
+}
+
+func TestGetRules(t *testing.T) {
+	mock := &mockServer{Resp: testRules}
+	srv := mock.Start(aoClient)
+	defer srv.Close()
+
+	rules, err := aoClient.GetRules(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(rules, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, testRules) {
+		t.Errorf("GetRules() = %v; want = %v", got, testRules)
+	}
+	// The auth variable override of the client must not be sent.
+	checkOnlyRequest(t, mock.Reqs, &testReq{Method: "GET", Path: "/.settings/rules.json"})
+}
+
+func TestSetRules(t *testing.T) {
+	mock := &mockServer{Resp: testRules}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	b, err := json.Marshal(testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetRules(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	checkOnlyRequest(t, mock.Reqs, &testReq{
+		Method: "PUT",
+		Path:   "/.settings/rules.json",
+		Body:   serialize(testRules),
+	})
+}
+
+func TestSetRulesInvalid(t *testing.T) {
+	mock := &mockServer{Resp: testRules}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	for _, rules := range []string{"", "{not json"} {
+		if err := client.SetRules(context.Background(), json.RawMessage(rules)); err == nil {
+			t.Errorf("SetRules(%q) = nil; want = error", rules)
+		}
+	}
+	if len(mock.Reqs) != 0 {
+		t.Errorf("SetRules() = %d requests; want = 0", len(mock.Reqs))
+	}
+}
+
+func TestGetRulesError(t *testing.T) {
+	mock := &mockServer{
+		Resp:   map[string]string{"error": "Permission denied"},
+		Status: http.StatusUnauthorized,
+	}
+	srv := mock.Start(client)
+	defer srv.Close()
+
+	rules, err := client.GetRules(context.Background())
+	if rules != nil || !IsPermissionDenied(err) {
+		t.Errorf("GetRules() = (%s, %v); want = (nil, permission denied)", rules, err)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dynamiclinks contains functions for creating Firebase Dynamic Links.
+//
+// Firebase Dynamic Links is deprecated and scheduled to be shut down. This package is provided
+// to support existing integrations until they are migrated.
+package dynamiclinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+const (
+	dynamicLinksEndpoint = "https://firebasedynamiclinks.googleapis.com/v1"
+	firebaseClientHeader = "X-Firebase-Client"
+)
+
+// SuffixOption specifies the length of the path component of a short Dynamic Link.
+type SuffixOption string
+
+const (
+	// ShortSuffix generates a path component that is only as long as needed to be unique.
+	ShortSuffix SuffixOption = "SHORT"
+
+	// UnguessableSuffix generates a 17-character path component that is impractical to guess.
+	// Use this option for links that encode sensitive information.
+	UnguessableSuffix SuffixOption = "UNGUESSABLE"
+)
+
+// ShortLinkRequest represents the parameters of a short link creation request.
+type ShortLinkRequest struct {
+	// LongDynamicLink is the long Dynamic Link to shorten. It must be hosted on a Dynamic Links
+	// domain configured for the project (e.g. https://example.page.link/?link=...).
+	LongDynamicLink string
+
+	// Suffix controls the length of the generated path component. If not specified, the backend
+	// defaults to UnguessableSuffix.
+	Suffix SuffixOption
+}
+
+// ShortLinkResponse represents the result of a short link creation request.
+type ShortLinkResponse struct {
+	ShortLink   string     `json:"shortLink"`
+	PreviewLink string     `json:"previewLink"`
+	Warnings    []*Warning `json:"warning,omitempty"`
+}
+
+// Warning represents a non-fatal issue detected by the backend while creating a short link.
+type Warning struct {
+	Code    string `json:"warningCode"`
+	Message string `json:"warningMessage"`
+}
+
+// Client is the interface for the Firebase Dynamic Links service.
+type Client struct {
+	endpoint   string
+	httpClient *internal.HTTPClient
+}
+
+// NewClient creates a new instance of the Firebase Dynamic Links Client.
+//
+// This function can only be invoked from within the SDK. Client applications should access the
+// Dynamic Links service through firebase.App.
+func NewClient(ctx context.Context, conf *internal.DynamicLinksConfig) (*Client, error) {
+	hc, _, err := internal.NewHTTPClient(ctx, conf.Opts...)
+	if err != nil {
+		return nil, err
+	}
+	conf.Tracker.TrackHTTPClient(hc.Client)
+
+	hc.Opts = []internal.HTTPOption{
+		internal.WithHeader(firebaseClientHeader, fmt.Sprintf("fire-admin-go/%s", conf.Version)),
+		internal.WithClientVersion(conf.Version),
+	}
+	hc.MaxResponseSize = conf.MaxResponseSize
+	return &Client{
+		endpoint:   dynamicLinksEndpoint,
+		httpClient: hc,
+	}, nil
+}
+
+// CreateShortLink creates a short Dynamic Link from the long link specified in the request.
+func (c *Client) CreateShortLink(ctx context.Context, req *ShortLinkRequest) (*ShortLinkResponse, error) {
+	payload, err := req.toPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &internal.Request{
+		Method: http.MethodPost,
+		URL:    fmt.Sprintf("%s/shortLinks", c.endpoint),
+		Body:   internal.NewJSONEntity(payload),
+	}
+	var result ShortLinkResponse
+	if _, err := c.httpClient.DoAndUnmarshal(ctx, request, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (req *ShortLinkRequest) toPayload() (map[string]interface{}, error) {
+	if req == nil {
+		return nil, errors.New("short link request must not be nil")
+	}
+	if req.LongDynamicLink == "" {
+		return nil, errors.New("long dynamic link must not be empty")
+	}
+
+	payload := map[string]interface{}{
+		"longDynamicLink": req.LongDynamicLink,
+	}
+	switch req.Suffix {
+	case "":
+	case ShortSuffix, UnguessableSuffix:
+		payload["suffix"] = map[string]interface{}{
+			"option": req.Suffix,
+		}
+	default:
+		return nil, fmt.Errorf("invalid suffix option: %q", req.Suffix)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamiclinks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/internal"
+	"google.golang.org/api/option"
+)
+
+const testLongLink = "https://example.page.link/?link=https://example.com/path"
+
+var testDynamicLinksConfig = &internal.DynamicLinksConfig{
+	Opts: []option.ClientOption{
+		option.WithTokenSource(&internal.MockTokenSource{AccessToken: "test-token"}),
+	},
+	Version: "test-version",
+}
+
+func TestCreateShortLink(t *testing.T) {
+	cases := []struct {
+		name   string
+		suffix SuffixOption
+		want   map[string]interface{}
+	}{
+		{
+			name: "default",
+			want: map[string]interface{}{
+				"longDynamicLink": testLongLink,
+			},
+		},
+		{
+			name:   "short",
+			suffix: ShortSuffix,
+			want: map[string]interface{}{
+				"longDynamicLink": testLongLink,
+				"suffix":          map[string]interface{}{"option": "SHORT"},
+			},
+		},
+		{
+			name:   "unguessable",
+			suffix: UnguessableSuffix,
+			want: map[string]interface{}{
+				"longDynamicLink": testLongLink,
+				"suffix":          map[string]interface{}{"option": "UNGUESSABLE"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		var tr *http.Request
+		var body []byte
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tr = r
+			body, _ = ioutil.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{
+				"shortLink": "https://example.page.link/abc",
+				"previewLink": "https://example.page.link/abc?d=1",
+				"warning": [{"warningCode": "UNRECOGNIZED_PARAM", "warningMessage": "unknown param"}]
+			}`))
+		}))
+
+		client, err := NewClient(context.Background(), testDynamicLinksConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.endpoint = ts.URL
+
+		resp, err := client.CreateShortLink(context.Background(), &ShortLinkRequest{
+			LongDynamicLink: testLongLink,
+			Suffix:          tc.suffix,
+		})
+		ts.Close()
+		if err != nil {
+			t.Fatalf("CreateShortLink(%s) = %v", tc.name, err)
+		}
+
+		want := &ShortLinkResponse{
+			ShortLink:   "https://example.page.link/abc",
+			PreviewLink: "https://example.page.link/abc?d=1",
+			Warnings: []*Warning{
+				{Code: "UNRECOGNIZED_PARAM", Message: "unknown param"},
+			},
+		}
+		if !reflect.DeepEqual(resp, want) {
+			t.Errorf("CreateShortLink(%s) = %#v; want = %#v", tc.name, resp, want)
+		}
+
+		if tr.Method != http.MethodPost {
+			t.Errorf("Method = %q; want = %q", tr.Method, http.MethodPost)
+		}
+		if tr.URL.Path != "/shortLinks" {
+			t.Errorf("Path = %q; want = %q", tr.URL.Path, "/shortLinks")
+		}
+		if h := tr.Header.Get("Authorization"); h != "Bearer test-token" {
+			t.Errorf("Authorization = %q; want = %q", h, "Bearer test-token")
+		}
+		if h := tr.Header.Get("X-Firebase-Client"); h != "fire-admin-go/test-version" {
+			t.Errorf("X-Firebase-Client = %q; want = %q", h, "fire-admin-go/test-version")
+		}
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("CreateShortLink(%s) body = %v; want = %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCreateShortLinkInvalidRequest(t *testing.T) {
+	client, err := NewClient(context.Background(), testDynamicLinksConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		req  *ShortLinkRequest
+		want string
+	}{
+		{"nil", nil, "short link request must not be nil"},
+		{"empty", &ShortLinkRequest{}, "long dynamic link must not be empty"},
+		{
+			"invalid-suffix",
+			&ShortLinkRequest{LongDynamicLink: testLongLink, Suffix: "LONG"},
+			`invalid suffix option: "LONG"`,
+		},
+	}
+	for _, tc := range cases {
+		resp, err := client.CreateShortLink(context.Background(), tc.req)
+		if resp != nil || err == nil || err.Error() != tc.want {
+			t.Errorf("CreateShortLink(%s) = (%v, %v); want = (nil, %q)", tc.name, resp, err, tc.want)
+		}
+	}
+}
+
+func TestCreateShortLinkError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"status": "INVALID_ARGUMENT", "message": "Bad domain"}}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(context.Background(), testDynamicLinksConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.endpoint = ts.URL
+	client.httpClient.RetryConfig = nil
+
+	resp, err := client.CreateShortLink(context.Background(), &ShortLinkRequest{LongDynamicLink: testLongLink})
+	if resp != nil || !errorutils.IsInvalidArgument(err) || err.Error() != "Bad domain" {
+		t.Errorf("CreateShortLink() = (%v, %v); want = (nil, InvalidArgument)", resp, err)
+	}
+}
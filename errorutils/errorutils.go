@@ -129,6 +129,24 @@ func IsDeadlineExceeded(err error) bool {
 	return internal.HasPlatformErrorCode(err, internal.DeadlineExceeded)
 }
 
+// IsRetryable checks if the given error was caused by a condition that may be resolved by
+// retrying the operation later, such as a rate limit or a temporarily unavailable service.
+//
+// Errors caused by an HTTP error response are retryable if the response status is 429 or 5xx
+// (except 501). Other errors, such as network errors, are retryable if their error code indicates
+// a transient condition: RESOURCE_EXHAUSTED, UNAVAILABLE, DEADLINE_EXCEEDED or ABORTED. Returns
+// false for errors not raised by the Admin SDK.
+func IsRetryable(err error) bool {
+	fe, ok := err.(*internal.FirebaseError)
+	return ok && fe.Retryable()
+}
+
+// IsResponseTooLarge checks if the given error was due to a backend response body exceeding the
+// maximum size accepted by the SDK, as set by firebase.Config.MaxResponseSize.
+func IsResponseTooLarge(err error) bool {
+	return internal.IsResponseTooLarge(err)
+}
+
 // HTTPResponse returns the http.Response instance that caused the given error.
 //
 // If the error was not caused by an HTTP error response, returns nil.
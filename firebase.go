@@ -21,18 +21,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/appcheck"
 	"firebase.google.com/go/v4/auth"
 	"firebase.google.com/go/v4/db"
+	"firebase.google.com/go/v4/dynamiclinks"
 	"firebase.google.com/go/v4/iid"
 	"firebase.google.com/go/v4/internal"
 	"firebase.google.com/go/v4/messaging"
 	"firebase.google.com/go/v4/storage"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
 	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
 	"google.golang.org/api/transport"
 )
 
@@ -44,14 +54,27 @@ const Version = "4.13.0"
 // firebaseEnvName is the name of the environment variable with the Config.
 const firebaseEnvName = "FIREBASE_CONFIG"
 
+// tokenExpiryDelta is how long before their expiry OAuth2 tokens are no longer handed out. It
+// matches the default of the oauth2 package.
+const tokenExpiryDelta = 10 * time.Second
+
 // An App holds configuration and state common to all Firebase services that are exposed from the SDK.
 type App struct {
-	authOverride     map[string]interface{}
-	dbURL            string
-	projectID        string
-	serviceAccountID string
-	storageBucket    string
-	opts             []option.ClientOption
+	authOverride           map[string]interface{}
+	dbURL                  string
+	dbPrettyPrint          bool
+	clientInfo             string
+	jwksFile               string
+	customTokenBackdate    time.Duration
+	authEmulatorStrict     bool
+	appCheckTokenCacheSize int
+	maxResponseSize        int64
+	projectID              string
+	serviceAccountID       string
+	storageBucket          string
+	identityToolkitBaseURL string
+	opts                   []option.ClientOption
+	tracker                *internal.ResourceTracker
 }
 
 // Config represents the configuration used to initialize an App.
@@ -61,15 +84,269 @@ type Config struct {
 	ProjectID        string                  `json:"projectId"`
 	ServiceAccountID string                  `json:"serviceAccountId"`
 	StorageBucket    string                  `json:"storageBucket"`
+
+	// DatabasePrettyPrint makes the database client request human-readable (indented) JSON from
+	// the Realtime Database when reading data. This is useful when capturing traffic for
+	// debugging, but increases the size of the responses.
+	DatabasePrettyPrint bool `json:"databasePrettyPrint"`
+
+	// IdentityToolkitBaseURL overrides the base URL of the Identity Toolkit API used by the auth
+	// client (e.g. a regional or Private Service Connect endpoint). It must not include the API
+	// version suffix. Defaults to https://identitytoolkit.googleapis.com.
+	IdentityToolkitBaseURL string `json:"identityToolkitBaseUrl"`
+
+	// DisableProjectIDDetection prevents the project ID from being inferred from the environment
+	// or the credentials when ProjectID is not set. In that case the App has no project ID, and
+	// services that require one fail to initialize.
+	DisableProjectIDDetection bool `json:"disableProjectIdDetection"`
+
+	// ProjectIDDetectionTimeout limits the time NewApp spends detecting the project ID when
+	// ProjectID is not set, which involves resolving the credentials of the App and may query the
+	// metadata server of the environment. When set, NewApp returns an error if the project ID
+	// cannot be determined within the timeout. Must not be negative. Defaults to 0, in which case
+	// detection is not time-limited, and the App is initialized without a project ID if none is
+	// found. The timeout also limits the eager credential lookup made for OnTokenRefresh and
+	// EarlyTokenRefresh.
+	ProjectIDDetectionTimeout time.Duration `json:"-"`
+
+	// OnTokenRefresh, if set, is called whenever the credentials of the App mint a new OAuth2
+	// access token, or fail to do so. It receives the new token, or the error that occurred.
+	//
+	// The callback is invoked synchronously by the API call that triggered the refresh, and must
+	// therefore return quickly. It must not modify the token. Setting it makes NewApp resolve the
+	// credentials of the App eagerly, within ProjectIDDetectionTimeout if set, and return an error
+	// if that fails. It has no effect when the App is initialized with option.WithHTTPClient, in
+	// which case the credentials are not resolved.
+	OnTokenRefresh func(token *oauth2.Token, err error) `json:"-"`
+
+	// EarlyTokenRefresh makes the App refresh its OAuth2 access token in the background when the
+	// token is due to expire within the given duration. Until the token expires, API calls keep
+	// using it without waiting for the refresh, which avoids the latency of a synchronous refresh
+	// on the request path. The refresh is triggered by the first API call made within the window,
+	// and applies to all the services of the App. It should be shorter than the lifetime of the
+	// tokens, which is one hour for Google OAuth2 access tokens. Must not be negative. Defaults to
+	// 0, in which case the token is refreshed synchronously shortly before it expires. Like
+	// OnTokenRefresh, it makes NewApp resolve the credentials of the App eagerly, and has no effect
+	// when the App is initialized with option.WithHTTPClient.
+	EarlyTokenRefresh time.Duration `json:"-"`
+
+	// CustomTokenBackdate backdates the issued-at time (iat) of the custom tokens minted by the auth
+	// client by the given duration. This prevents the tokens from being rejected as issued in the
+	// future when the clock of the server is ahead of the Firebase Auth backend. The expiry of the
+	// tokens is backdated as well, so that their lifetime remains the same. Must not be negative
+	// or longer than 5 minutes. Defaults to 0, in which case tokens are issued at the current time.
+	CustomTokenBackdate time.Duration `json:"-"`
+
+	// AuthEmulatorStrictProjectID makes the auth client check the project ID of ID tokens and
+	// session cookies when it is connected to the Firebase Auth Emulator. By default the emulator
+	// tokens are verified leniently: their signature is not checked, and neither is whether their
+	// issuer (iss) and audience (aud) claims match the project ID, since emulator tokens do not
+	// provide any security. Their claims are still parsed, and their expiry is still checked. Set
+	// this in tests that assert tokens issued for other projects are rejected. It has no effect
+	// when the emulator is not used, in which case the project ID is always checked.
+	AuthEmulatorStrictProjectID bool `json:"-"`
+
+	// AppCheckTokenCacheSize enables caching the results of App Check token verifications, and sets
+	// the maximum number of tokens kept in the cache. Verifying a cached token again does not
+	// repeat the signature and claims checks, until the token expires. The least recently verified
+	// tokens are evicted when the cache is full. Must not be negative. Defaults to 0, in which
+	// case verification results are not cached.
+	AppCheckTokenCacheSize int `json:"-"`
+
+	// MaxResponseSize limits the size of the response bodies read by the service clients of the
+	// App, in bytes. Larger responses are rejected with an error for which
+	// errorutils.IsResponseTooLarge returns true. This bounds the memory used when a server
+	// misbehaves, or when the auth client fetches the OpenID configuration and keys of a
+	// third-party OIDC provider. It applies to the auth, database, dynamic links, instance ID and
+	// messaging clients. Must not be negative. Defaults to 0, in which case responses are limited
+	// to 256 MiB.
+	MaxResponseSize int64 `json:"-"`
+}
+
+// ServiceAccount represents the fields of a Google service account key.
+//
+// It can be used with WithServiceAccount to supply credentials that have been assembled in memory
+// (e.g. from a secret manager), without having to write them to a file or encode them as JSON.
+type ServiceAccount struct {
+	ClientEmail  string
+	PrivateKey   string
+	PrivateKeyID string
+	ProjectID    string
+}
+
+// WithServiceAccount returns a ClientOption that authenticates the App using the given service
+// account.
+//
+// The service account is used both to obtain OAuth2 access tokens, and to sign custom tokens
+// locally. Its ProjectID is used as the project ID of the App, unless one is set explicitly via
+// Config.ProjectID. A malformed private key is reported when the credentials are first used.
+func WithServiceAccount(sa ServiceAccount) option.ClientOption {
+	conf := &jwt.Config{
+		Email:        sa.ClientEmail,
+		PrivateKey:   []byte(sa.PrivateKey),
+		PrivateKeyID: sa.PrivateKeyID,
+		Scopes:       internal.FirebaseScopes,
+		TokenURL:     google.JWTTokenURL,
+	}
+
+	// Credentials derived from an option are expected to carry the JSON representation of the
+	// key, which is how downstream services (e.g. the custom token signer) discover it.
+	b, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     sa.ProjectID,
+		"private_key_id": sa.PrivateKeyID,
+		"private_key":    sa.PrivateKey,
+		"client_email":   sa.ClientEmail,
+		"token_uri":      google.JWTTokenURL,
+	})
+	return option.WithCredentials(&google.Credentials{
+		ProjectID:   sa.ProjectID,
+		TokenSource: conf.TokenSource(context.Background()),
+		JSON:        b,
+	})
+}
+
+// WithQuotaProject returns a ClientOption that attributes the quota and billing of the API calls
+// made by the App to the given project, instead of the project of the credentials.
+//
+// The project is sent in the X-Goog-User-Project header of every request to Google APIs, and the
+// caller must have the serviceusage.services.use permission on it. The option has no effect when
+// the App is initialized with option.WithHTTPClient, since the SDK does not control the transport
+// in that case.
+func WithQuotaProject(projectID string) option.ClientOption {
+	return option.WithQuotaProject(projectID)
+}
+
+// WithImpersonatedServiceAccount returns a ClientOption that makes the App act as the given target
+// service account, using short-lived credentials obtained by impersonating it.
+//
+// The credentials of the App (as specified by the other options, or Application Default
+// Credentials) are used to impersonate the target, either directly or through the given chain of
+// delegate service accounts. Each account in the chain must have the Service Account Token
+// Creator role on the next one. Custom tokens are signed by the target service account via the
+// IAM signBlob API, unless Config.ServiceAccountID specifies another account. This requires the
+// target service account to hold the Service Account Token Creator role on itself.
+func WithImpersonatedServiceAccount(targetSA string, delegates []string) option.ClientOption {
+	// The impersonation is carried out by the transport layer, which makes it apply to all the
+	// clients created from the App. Only the target needs to be known to the App itself.
+	return &impersonatedServiceAccount{
+		ClientOption: option.ImpersonateCredentials(targetSA, delegates...),
+		target:       targetSA,
+	}
+}
+
+type impersonatedServiceAccount struct {
+	option.ClientOption
+	target string
+}
+
+// WithJWKSFile returns a ClientOption that makes the Auth clients of the App verify ID tokens
+// with the public keys in the given JSON Web Key Set (JWKS) file, instead of fetching them from
+// Google servers.
+//
+// This allows verifying ID tokens without network access, provided that the file is kept in sync
+// with the Firebase Auth signing keys. The file is reloaded whenever its modification time
+// changes. Only RSA keys are used, and tokens are matched to keys by their kid header. Session
+// cookies are still verified with keys fetched from Google servers.
+//
+// This option is only interpreted by NewApp, and must not be passed to other client libraries.
+func WithJWKSFile(path string) option.ClientOption {
+	return &jwksFile{path: path}
+}
+
+// jwksFile is consumed by NewApp, and never passed on to the transport layer. Hence the embedded
+// ClientOption is never used.
+type jwksFile struct {
+	option.ClientOption
+	path string
+}
+
+// WithScopes returns a ClientOption that requests the given OAuth2 scopes in addition to the
+// default scopes of the SDK, when the App obtains access tokens from its credentials.
+//
+// This is useful when the App makes calls that require scopes the SDK does not request by
+// default, such as when running with Application Default Credentials on Compute Engine. Unlike
+// option.WithScopes, which replaces the default scopes, this option augments them. It has no
+// effect on credentials that do not take scopes into account, such as an oauth2.TokenSource or
+// the credentials created by WithServiceAccount.
+//
+// This option is only interpreted by NewApp, and must not be passed to other client libraries.
+func WithScopes(scopes []string) option.ClientOption {
+	return &additionalScopes{scopes: scopes}
+}
+
+// additionalScopes is consumed by NewApp, and never passed on to the transport layer. Hence the
+// embedded ClientOption is never used.
+type additionalScopes struct {
+	option.ClientOption
+	scopes []string
+}
+
+// appendScopes appends the scopes in extra that are not already in scopes.
+func appendScopes(scopes, extra []string) []string {
+	for _, s := range extra {
+		found := false
+		for _, existing := range scopes {
+			if s == existing {
+				found = true
+				break
+			}
+		}
+		if !found && s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// WithClientInfo returns a ClientOption that appends the given application name and version to
+// the User-Agent header of the requests made by the App, as "<appName>/<appVersion>".
+//
+// This helps attribute the traffic of an application in server logs and support cases. Both
+// values must be non-empty, and must not contain whitespace. The option replaces any User-Agent
+// specified via option.WithUserAgent.
+func WithClientInfo(appName, appVersion string) option.ClientOption {
+	ci := &clientInfo{
+		appName:    appName,
+		appVersion: appVersion,
+	}
+	ci.ClientOption = option.WithUserAgent(fmt.Sprintf("fire-admin-go/%s %s", Version, ci))
+	return ci
+}
+
+type clientInfo struct {
+	option.ClientOption
+	appName    string
+	appVersion string
+}
+
+func (ci *clientInfo) validate() error {
+	if ci.appName == "" || ci.appVersion == "" {
+		return errors.New("client info app name and version must be non-empty")
+	}
+	if strings.ContainsAny(ci.appName+ci.appVersion, " \t\r\n") {
+		return errors.New("client info app name and version must not contain whitespace")
+	}
+	return nil
+}
+
+func (ci *clientInfo) String() string {
+	return fmt.Sprintf("%s/%s", ci.appName, ci.appVersion)
 }
 
 // Auth returns an instance of auth.Client.
 func (a *App) Auth(ctx context.Context) (*auth.Client, error) {
 	conf := &internal.AuthConfig{
-		ProjectID:        a.projectID,
-		Opts:             a.opts,
-		ServiceAccountID: a.serviceAccountID,
-		Version:          Version,
+		ProjectID:               a.projectID,
+		Opts:                    a.opts,
+		ServiceAccountID:        a.serviceAccountID,
+		IdentityToolkitBaseURL:  a.identityToolkitBaseURL,
+		Version:                 Version,
+		Tracker:                 a.tracker,
+		JWKSFile:                a.jwksFile,
+		CustomTokenBackdate:     a.customTokenBackdate,
+		EmulatorStrictProjectID: a.authEmulatorStrict,
+		MaxResponseSize:         a.maxResponseSize,
 	}
 	return auth.NewClient(ctx, conf)
 }
@@ -84,10 +361,14 @@ func (a *App) Database(ctx context.Context) (*db.Client, error) {
 // identified by the given URL.
 func (a *App) DatabaseWithURL(ctx context.Context, url string) (*db.Client, error) {
 	conf := &internal.DatabaseConfig{
-		AuthOverride: a.authOverride,
-		URL:          url,
-		Opts:         a.opts,
-		Version:      Version,
+		AuthOverride:    a.authOverride,
+		URL:             url,
+		PrettyPrint:     a.dbPrettyPrint,
+		ClientInfo:      a.clientInfo,
+		Opts:            a.opts,
+		Version:         Version,
+		Tracker:         a.tracker,
+		MaxResponseSize: a.maxResponseSize,
 	}
 	return db.NewClient(ctx, conf)
 }
@@ -110,11 +391,25 @@ func (a *App) Firestore(ctx context.Context) (*firestore.Client, error) {
 	return firestore.NewClient(ctx, a.projectID, a.opts...)
 }
 
+// DynamicLinks returns an instance of dynamiclinks.Client.
+func (a *App) DynamicLinks(ctx context.Context) (*dynamiclinks.Client, error) {
+	conf := &internal.DynamicLinksConfig{
+		Opts:            a.opts,
+		Version:         Version,
+		Tracker:         a.tracker,
+		MaxResponseSize: a.maxResponseSize,
+	}
+	return dynamiclinks.NewClient(ctx, conf)
+}
+
 // InstanceID returns an instance of iid.Client.
 func (a *App) InstanceID(ctx context.Context) (*iid.Client, error) {
 	conf := &internal.InstanceIDConfig{
-		ProjectID: a.projectID,
-		Opts:      a.opts,
+		ProjectID:       a.projectID,
+		Opts:            a.opts,
+		Version:         Version,
+		Tracker:         a.tracker,
+		MaxResponseSize: a.maxResponseSize,
 	}
 	return iid.NewClient(ctx, conf)
 }
@@ -122,9 +417,11 @@ func (a *App) InstanceID(ctx context.Context) (*iid.Client, error) {
 // Messaging returns an instance of messaging.Client.
 func (a *App) Messaging(ctx context.Context) (*messaging.Client, error) {
 	conf := &internal.MessagingConfig{
-		ProjectID: a.projectID,
-		Opts:      a.opts,
-		Version:   Version,
+		ProjectID:       a.projectID,
+		Opts:            a.opts,
+		Version:         Version,
+		Tracker:         a.tracker,
+		MaxResponseSize: a.maxResponseSize,
 	}
 	return messaging.NewClient(ctx, conf)
 }
@@ -132,11 +429,25 @@ func (a *App) Messaging(ctx context.Context) (*messaging.Client, error) {
 // AppCheck returns an instance of appcheck.Client.
 func (a *App) AppCheck(ctx context.Context) (*appcheck.Client, error) {
 	conf := &internal.AppCheckConfig{
-		ProjectID: a.projectID,
+		ProjectID:      a.projectID,
+		Version:        Version,
+		Tracker:        a.tracker,
+		TokenCacheSize: a.appCheckTokenCacheSize,
 	}
 	return appcheck.NewClient(ctx, conf)
 }
 
+// Close releases the resources held by the service clients created from the App, such as idle
+// HTTP connections and the goroutine that refreshes the App Check public keys.
+//
+// Clients created before Close remain usable, but re-establish connections as needed, and the App
+// Check client stops refreshing its keys. Clients returned by Storage() and Firestore() are not
+// affected, and must be closed separately.
+func (a *App) Close() error {
+	a.tracker.Release()
+	return nil
+}
+
 // NewApp creates a new App from the provided config and client options.
 //
 // If the client options contain a valid credential (a service account file, a refresh token
@@ -145,9 +456,31 @@ func (a *App) AppCheck(ctx context.Context) (*appcheck.Client, error) {
 // If `config` is nil, the SDK will attempt to load the config options from the
 // `FIREBASE_CONFIG` environment variable. If the value in it starts with a `{` it is parsed as a
 // JSON object, otherwise it is assumed to be the name of the JSON file containing the options.
+//
+// The project ID of the App is the first non-empty value among the following:
+//
+//  1. Config.ProjectID
+//  2. The GOOGLE_CLOUD_PROJECT environment variable
+//  3. The GCLOUD_PROJECT environment variable
+//  4. The project ID of the credentials (for application default credentials on Google Cloud,
+//     this is obtained from the metadata server)
+//
+// Only the first source is consulted when Config.DisableProjectIDDetection is set.
 func NewApp(ctx context.Context, config *Config, opts ...option.ClientOption) (*App, error) {
-	o := []option.ClientOption{option.WithScopes(internal.FirebaseScopes...)}
-	o = append(o, opts...)
+	scopes := append([]string{}, internal.FirebaseScopes...)
+	var jwksPath string
+	var rest []option.ClientOption
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case *jwksFile:
+			jwksPath = v.path
+		case *additionalScopes:
+			scopes = appendScopes(scopes, v.scopes)
+		default:
+			rest = append(rest, opt)
+		}
+	}
+	o := append([]option.ClientOption{option.WithScopes(scopes...)}, rest...)
 	if config == nil {
 		var err error
 		if config, err = getConfigDefaults(); err != nil {
@@ -155,22 +488,249 @@ func NewApp(ctx context.Context, config *Config, opts ...option.ClientOption) (*
 		}
 	}
 
-	pid := getProjectID(ctx, config, o...)
+	serviceAccountID := config.ServiceAccountID
+	if serviceAccountID == "" {
+		for _, opt := range opts {
+			if isa, ok := opt.(*impersonatedServiceAccount); ok {
+				serviceAccountID = isa.target
+			}
+		}
+	}
+
+	var info string
+	for _, opt := range opts {
+		if ci, ok := opt.(*clientInfo); ok {
+			if err := ci.validate(); err != nil {
+				return nil, err
+			}
+			info = ci.String()
+		}
+	}
+
+	if config.EarlyTokenRefresh < 0 {
+		return nil, errors.New("early token refresh must not be negative")
+	}
+	if config.MaxResponseSize < 0 {
+		return nil, errors.New("max response size must not be negative")
+	}
+	if (config.OnTokenRefresh != nil || config.EarlyTokenRefresh > 0) && !hasHTTPClient(o) {
+		var err error
+		timeout := config.ProjectIDDetectionTimeout
+		o, err = wrapTokenSource(ctx, timeout, o, func(ts oauth2.TokenSource) oauth2.TokenSource {
+			if config.EarlyTokenRefresh > 0 {
+				ts = newEarlyRefresher(ts, config.EarlyTokenRefresh)
+			}
+			if config.OnTokenRefresh != nil {
+				ts = &refreshObserver{src: ts, onRefresh: config.OnTokenRefresh}
+			}
+			return ts
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pid, err := getProjectID(ctx, config, o...)
+	if err != nil {
+		return nil, err
+	}
 	ao := defaultAuthOverrides
 	if config.AuthOverride != nil {
 		ao = *config.AuthOverride
 	}
 
 	return &App{
-		authOverride:     ao,
-		dbURL:            config.DatabaseURL,
-		projectID:        pid,
-		serviceAccountID: config.ServiceAccountID,
-		storageBucket:    config.StorageBucket,
-		opts:             o,
+		authOverride:           ao,
+		dbURL:                  config.DatabaseURL,
+		dbPrettyPrint:          config.DatabasePrettyPrint,
+		clientInfo:             info,
+		jwksFile:               jwksPath,
+		customTokenBackdate:    config.CustomTokenBackdate,
+		authEmulatorStrict:     config.AuthEmulatorStrictProjectID,
+		appCheckTokenCacheSize: config.AppCheckTokenCacheSize,
+		maxResponseSize:        config.MaxResponseSize,
+		projectID:              pid,
+		serviceAccountID:       serviceAccountID,
+		storageBucket:          config.StorageBucket,
+		identityToolkitBaseURL: config.IdentityToolkitBaseURL,
+		opts:                   o,
+		tracker:                &internal.ResourceTracker{},
 	}, nil
 }
 
+var (
+	tokenSourceOptionType = reflect.TypeOf(option.WithTokenSource(nil))
+	httpClientOptionType  = reflect.TypeOf(option.WithHTTPClient(nil))
+)
+
+// hasHTTPClient checks if the given options specify an HTTP client via option.WithHTTPClient. Such
+// a client is used as is, and the credentials specified by the other options are ignored.
+func hasHTTPClient(opts []option.ClientOption) bool {
+	for _, opt := range opts {
+		if reflect.TypeOf(opt) == httpClientOptionType {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapTokenSource resolves the credentials specified by the given options, and returns options
+// that use the same credentials with their TokenSource wrapped by the given function.
+//
+// The wrapped credentials are passed via internaloption.WithCredentials, which takes precedence
+// over the credential options already present without conflicting with them. The exceptions are
+// option.WithTokenSource, which the HTTP transport uses directly when set, and impersonation,
+// which has already been applied while resolving the credentials. Those options are dropped from
+// the result. The credentials must be resolved within the given timeout, unless it is 0.
+func wrapTokenSource(
+	ctx context.Context, timeout time.Duration, opts []option.ClientOption,
+	wrap func(oauth2.TokenSource) oauth2.TokenSource) ([]option.ClientOption, error) {
+	creds, err := findCredentialsWithTimeout(ctx, timeout, opts...)
+	if err == errCredentialsTimeout {
+		return nil, fmt.Errorf("failed to resolve credentials within %v", timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve credentials: %v", err)
+	}
+
+	var result []option.ClientOption
+	for _, opt := range opts {
+		if _, ok := opt.(*impersonatedServiceAccount); ok {
+			continue
+		}
+		if reflect.TypeOf(opt) == tokenSourceOptionType {
+			continue
+		}
+		result = append(result, opt)
+	}
+	return append(result, internaloption.WithCredentials(&google.Credentials{
+		ProjectID:   creds.ProjectID,
+		TokenSource: wrap(creds.TokenSource),
+		JSON:        creds.JSON,
+	})), nil
+}
+
+// refreshObserver is an oauth2.TokenSource that reports new tokens and errors obtained from an
+// underlying TokenSource. A token is considered new when its access token differs from the one
+// last reported. This way cached tokens handed out by a reusing TokenSource are not reported.
+type refreshObserver struct {
+	src       oauth2.TokenSource
+	onRefresh func(*oauth2.Token, error)
+
+	mu   sync.Mutex
+	last string
+}
+
+func (r *refreshObserver) Token() (*oauth2.Token, error) {
+	token, err := r.src.Token()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.onRefresh(nil, err)
+		return nil, err
+	}
+	if token.AccessToken != r.last {
+		r.last = token.AccessToken
+		r.onRefresh(token, nil)
+	}
+	return token, nil
+}
+
+// earlyRefresher is an oauth2.TokenSource that refreshes the token obtained from an underlying
+// TokenSource in the background, once the token is due to expire within the refresh window. The
+// current token is handed out while the refresh is in progress. The token is only refreshed
+// synchronously when no valid token is cached.
+type earlyRefresher struct {
+	src    oauth2.TokenSource
+	window time.Duration
+
+	mu         sync.Mutex
+	token      *oauth2.Token
+	refreshing bool
+	// exhausted is the cached token when a background refresh did not yield a token that expires
+	// later. No further background refreshes are made until a different token is cached.
+	exhausted *oauth2.Token
+}
+
+// newEarlyRefresher returns an earlyRefresher that obtains tokens from the given TokenSource.
+//
+// Credentials typically hand out tokens through an oauth2.ReuseTokenSource, which returns the
+// cached token until it is about to expire. Refreshing through such a source would only yield the
+// same token again. Therefore the TokenSource is rebuilt with oauth2.ReuseTokenSourceWithExpiry,
+// which fetches tokens from the same underlying TokenSource, but considers them expired once they
+// enter the refresh window. Passing a non-nil initial token makes it replace the reuse cache
+// instead of adjusting the original one, which is shared with the given credentials.
+func newEarlyRefresher(src oauth2.TokenSource, window time.Duration) *earlyRefresher {
+	return &earlyRefresher{
+		src:    oauth2.ReuseTokenSourceWithExpiry(&oauth2.Token{}, src, window),
+		window: window,
+	}
+}
+
+func (r *earlyRefresher) Token() (*oauth2.Token, error) {
+	r.mu.Lock()
+	if token := r.token; usable(token) {
+		if r.dueForRefresh(token) {
+			r.refreshing = true
+			go r.refresh(token)
+		}
+		r.mu.Unlock()
+		return token, nil
+	}
+	r.mu.Unlock()
+
+	token, err := r.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store(token)
+	return token, nil
+}
+
+// dueForRefresh checks if a background refresh should be started for the given cached token.
+// Must be called with the mutex held.
+func (r *earlyRefresher) dueForRefresh(token *oauth2.Token) bool {
+	return !token.Expiry.IsZero() && time.Until(token.Expiry) < r.window &&
+		!r.refreshing && token != r.exhausted
+}
+
+// refresh obtains a new token from the underlying TokenSource. Errors are ignored, since the
+// current token remains usable, and is refreshed synchronously once it expires.
+func (r *earlyRefresher) refresh(current *oauth2.Token) {
+	token, err := r.src.Token()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshing = false
+	if err != nil {
+		return
+	}
+	if !token.Expiry.After(current.Expiry) {
+		r.exhausted = current
+		return
+	}
+	r.store(token)
+}
+
+// store caches the given token, unless a token that expires later is already cached. Must be
+// called with the mutex held.
+func (r *earlyRefresher) store(token *oauth2.Token) {
+	if r.token == nil || token.Expiry.IsZero() || r.token.Expiry.IsZero() ||
+		!token.Expiry.Before(r.token.Expiry) {
+		r.token = token
+	}
+}
+
+// usable checks if the given token can still be handed out. Token.Valid() cannot be used for
+// this, since tokens obtained from the rebuilt reuse cache are considered expired as soon as they
+// enter the refresh window.
+func usable(token *oauth2.Token) bool {
+	return token != nil && token.AccessToken != "" &&
+		(token.Expiry.IsZero() || time.Until(token.Expiry) > tokenExpiryDelta)
+}
+
 // getConfigDefaults reads the default config file, defined by the FIREBASE_CONFIG
 // env variable, used only when options are nil.
 func getConfigDefaults() (*Config, error) {
@@ -205,19 +765,85 @@ func getConfigDefaults() (*Config, error) {
 	return fbc, nil
 }
 
-func getProjectID(ctx context.Context, config *Config, opts ...option.ClientOption) string {
-	if config.ProjectID != "" {
-		return config.ProjectID
-	}
+// findCredentials resolves the credentials used to detect the project ID. It is a variable so
+// that tests can simulate slow credential lookups.
+var findCredentials = transport.Creds
 
-	creds, _ := transport.Creds(ctx, opts...)
-	if creds != nil && creds.ProjectID != "" {
-		return creds.ProjectID
+func getProjectID(ctx context.Context, config *Config, opts ...option.ClientOption) (string, error) {
+	if config.ProjectID != "" || config.DisableProjectIDDetection {
+		return config.ProjectID, nil
+	}
+	if config.ProjectIDDetectionTimeout < 0 {
+		return "", errors.New("project ID detection timeout must not be negative")
 	}
 
 	if pid := os.Getenv("GOOGLE_CLOUD_PROJECT"); pid != "" {
-		return pid
+		return pid, nil
+	}
+
+	if pid := os.Getenv("GCLOUD_PROJECT"); pid != "" {
+		return pid, nil
+	}
+
+	if config.ProjectIDDetectionTimeout == 0 {
+		creds, _ := findCredentials(ctx, opts...)
+		if creds != nil {
+			return creds.ProjectID, nil
+		}
+		return "", nil
 	}
+	return detectProjectIDWithTimeout(ctx, config.ProjectIDDetectionTimeout, opts...)
+}
+
+// detectProjectIDWithTimeout detects the project ID from the credentials, and fails if that does
+// not succeed within the given timeout.
+func detectProjectIDWithTimeout(
+	ctx context.Context, timeout time.Duration, opts ...option.ClientOption) (string, error) {
+	creds, err := findCredentialsWithTimeout(ctx, timeout, opts...)
 
-	return os.Getenv("GCLOUD_PROJECT")
+	const hint = "specify Config.ProjectID or set the GOOGLE_CLOUD_PROJECT environment variable"
+	if err == errCredentialsTimeout {
+		return "", fmt.Errorf("failed to determine the project ID within %v; %s", timeout, hint)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the project ID: %v; %s", err, hint)
+	}
+	if creds.ProjectID == "" {
+		return "", fmt.Errorf("failed to determine the project ID from the credentials; %s", hint)
+	}
+	return creds.ProjectID, nil
+}
+
+var errCredentialsTimeout = errors.New("timed out resolving credentials")
+
+// findCredentialsWithTimeout resolves the credentials specified by the given options, and returns
+// errCredentialsTimeout if that does not succeed within the given timeout. The lookup is not
+// time-limited if the timeout is not positive. The credential lookup does not honor the deadline
+// of its context in all environments, hence it runs in a separate goroutine that is abandoned on
+// timeout.
+func findCredentialsWithTimeout(
+	ctx context.Context, timeout time.Duration, opts ...option.ClientOption) (*google.Credentials, error) {
+	if timeout <= 0 {
+		return findCredentials(ctx, opts...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		creds *google.Credentials
+		err   error
+	}
+	find := findCredentials
+	done := make(chan result, 1)
+	go func() {
+		creds, err := find(ctx, opts...)
+		done <- result{creds: creds, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.creds, r.err
+	case <-ctx.Done():
+		return nil, errCredentialsTimeout
+	}
 }
@@ -16,20 +16,26 @@ package firebase
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"firebase.google.com/go/v4/internal"
 	"firebase.google.com/go/v4/messaging"
+	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
@@ -150,6 +156,126 @@ func TestRefreshTokenWithEnvVar(t *testing.T) {
 	}
 }
 
+func TestProjectIDPrecedence(t *testing.T) {
+	for _, varName := range []string{"GCLOUD_PROJECT", "GOOGLE_CLOUD_PROJECT"} {
+		t.Run(varName, func(t *testing.T) {
+			t.Setenv("GCLOUD_PROJECT", "")
+			t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+			t.Setenv(varName, "env-project-id")
+
+			ctx := context.Background()
+			opt := option.WithCredentialsFile("testdata/service_account.json")
+			app, err := NewApp(ctx, &Config{}, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if app.projectID != "env-project-id" {
+				t.Errorf("Project ID: %q; want: env-project-id", app.projectID)
+			}
+
+			app, err = NewApp(ctx, &Config{ProjectID: "explicit-project-id"}, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if app.projectID != "explicit-project-id" {
+				t.Errorf("Project ID: %q; want: explicit-project-id", app.projectID)
+			}
+		})
+	}
+}
+
+func TestDisableProjectIDDetection(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "env-project-id")
+
+	ctx := context.Background()
+	opt := option.WithCredentialsFile("testdata/service_account.json")
+	app, err := NewApp(ctx, &Config{DisableProjectIDDetection: true}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.projectID != "" {
+		t.Errorf("Project ID: %q; want: empty", app.projectID)
+	}
+
+	config := &Config{ProjectID: "explicit-project-id", DisableProjectIDDetection: true}
+	app, err = NewApp(ctx, config, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.projectID != "explicit-project-id" {
+		t.Errorf("Project ID: %q; want: explicit-project-id", app.projectID)
+	}
+}
+
+func TestProjectIDDetectionTimeout(t *testing.T) {
+	for _, varName := range []string{"GCLOUD_PROJECT", "GOOGLE_CLOUD_PROJECT"} {
+		current := os.Getenv(varName)
+		if err := os.Setenv(varName, ""); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Setenv(varName, current)
+	}
+
+	ctx := context.Background()
+	conf := &Config{ProjectIDDetectionTimeout: time.Second}
+	app, err := NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.projectID != "mock-project-id" {
+		t.Errorf("Project ID: %q; want: mock-project-id", app.projectID)
+	}
+
+	app, err = NewApp(ctx, conf, option.WithCredentialsFile("testdata/refresh_token.json"))
+	want := "failed to determine the project ID from the credentials; " +
+		"specify Config.ProjectID or set the GOOGLE_CLOUD_PROJECT environment variable"
+	if app != nil || err == nil || err.Error() != want {
+		t.Errorf("NewApp() = (%v, %v); want = (nil, %q)", app, err, want)
+	}
+
+	conf = &Config{ProjectIDDetectionTimeout: -time.Second}
+	app, err = NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	want = "project ID detection timeout must not be negative"
+	if app != nil || err == nil || err.Error() != want {
+		t.Errorf("NewApp() = (%v, %v); want = (nil, %q)", app, err, want)
+	}
+}
+
+func TestProjectIDDetectionTimeoutExceeded(t *testing.T) {
+	for _, varName := range []string{"GCLOUD_PROJECT", "GOOGLE_CLOUD_PROJECT"} {
+		current := os.Getenv(varName)
+		if err := os.Setenv(varName, ""); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Setenv(varName, current)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	original := findCredentials
+	findCredentials = func(ctx context.Context, opts ...option.ClientOption) (*google.Credentials, error) {
+		<-release
+		return original(ctx, opts...)
+	}
+	defer func() {
+		findCredentials = original
+	}()
+
+	conf := &Config{ProjectIDDetectionTimeout: 10 * time.Millisecond}
+	app, err := NewApp(context.Background(), conf, option.WithCredentialsFile("testdata/service_account.json"))
+	want := "failed to determine the project ID within 10ms; " +
+		"specify Config.ProjectID or set the GOOGLE_CLOUD_PROJECT environment variable"
+	if app != nil || err == nil || err.Error() != want {
+		t.Errorf("NewApp() = (%v, %v); want = (nil, %q)", app, err, want)
+	}
+
+	// The timeout does not apply when the project ID is specified explicitly.
+	conf.ProjectID = "explicit-project-id"
+	if app, err := NewApp(context.Background(), conf); app == nil || err != nil {
+		t.Errorf("NewApp() = (%v, %v); want = (app, nil)", app, err)
+	}
+}
+
 func TestAppDefault(t *testing.T) {
 	current := os.Getenv(credEnvVar)
 
@@ -217,6 +343,215 @@ func TestAuth(t *testing.T) {
 	}
 }
 
+func TestAuthCustomTokenBackdate(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{CustomTokenBackdate: 30 * time.Second}
+	app, err := NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.customTokenBackdate != 30*time.Second {
+		t.Errorf("customTokenBackdate = %v; want = %v", app.customTokenBackdate, 30*time.Second)
+	}
+	if c, err := app.Auth(ctx); c == nil || err != nil {
+		t.Errorf("Auth() = (%v, %v); want (auth, nil)", c, err)
+	}
+
+	conf = &Config{CustomTokenBackdate: time.Hour}
+	app, err = NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := app.Auth(ctx); c != nil || err == nil {
+		t.Errorf("Auth() = (%v, %v); want (nil, error)", c, err)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{ProjectID: "mock-project-id", MaxResponseSize: 1024}
+	app, err := NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.maxResponseSize != 1024 {
+		t.Errorf("maxResponseSize = %d; want = 1024", app.maxResponseSize)
+	}
+	if c, err := app.Auth(ctx); c == nil || err != nil {
+		t.Errorf("Auth() = (%v, %v); want (auth, nil)", c, err)
+	}
+	if c, err := app.Messaging(ctx); c == nil || err != nil {
+		t.Errorf("Messaging() = (%v, %v); want (messaging, nil)", c, err)
+	}
+
+	conf = &Config{MaxResponseSize: -1}
+	app, err = NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	want := "max response size must not be negative"
+	if app != nil || err == nil || err.Error() != want {
+		t.Errorf("NewApp() = (%v, %v); want = (nil, %q)", app, err, want)
+	}
+}
+
+func TestAuthEmulatorStrictProjectID(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{AuthEmulatorStrictProjectID: true}
+	app, err := NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !app.authEmulatorStrict {
+		t.Errorf("authEmulatorStrict = false; want = true")
+	}
+	if c, err := app.Auth(ctx); c == nil || err != nil {
+		t.Errorf("Auth() = (%v, %v); want (auth, nil)", c, err)
+	}
+}
+
+func TestWithServiceAccount(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/service_account.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed map[string]string
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	sa := ServiceAccount{
+		ClientEmail:  parsed["client_email"],
+		PrivateKey:   parsed["private_key"],
+		PrivateKeyID: parsed["private_key_id"],
+		ProjectID:    parsed["project_id"],
+	}
+
+	ctx := context.Background()
+	app, err := NewApp(ctx, &Config{}, WithServiceAccount(sa))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.projectID != "mock-project-id" {
+		t.Errorf("app.projectID = %q; want = %q", app.projectID, "mock-project-id")
+	}
+
+	creds, err := transport.Creds(ctx, app.opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.ProjectID != sa.ProjectID {
+		t.Errorf("Creds().ProjectID = %q; want = %q", creds.ProjectID, sa.ProjectID)
+	}
+
+	// Custom tokens are signed locally with the in-memory key, without any RPC calls.
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := client.CustomToken(ctx, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		t.Fatalf("CustomToken() = %q; want a JWT", token)
+	}
+	var payload map[string]interface{}
+	decoded, _ := base64.RawURLEncoding.DecodeString(segments[1])
+	if err := json.Unmarshal(decoded, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["iss"] != sa.ClientEmail {
+		t.Errorf("CustomToken() iss = %v; want = %q", payload["iss"], sa.ClientEmail)
+	}
+}
+
+func TestWithQuotaProject(t *testing.T) {
+	var header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Goog-User-Project")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("null"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	app, err := NewApp(ctx, nil, option.WithTokenSource(&testTokenSource{AccessToken: "mock-token"}), WithQuotaProject("quota-project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ts.Listener.Addr().(*net.TCPAddr).Port
+	client, err := app.DatabaseWithURL(ctx, fmt.Sprintf("localhost:%d?ns=test-db", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got interface{}
+	if err := client.NewRef("foo").Get(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if header != "quota-project" {
+		t.Errorf("X-Goog-User-Project = %q; want = %q", header, "quota-project")
+	}
+}
+
+func TestWithImpersonatedServiceAccount(t *testing.T) {
+	target := "target@mock-project-id.iam.gserviceaccount.com"
+	ctx := context.Background()
+	app, err := NewApp(ctx, nil,
+		option.WithCredentialsFile("testdata/service_account.json"),
+		WithImpersonatedServiceAccount(target, []string{"delegate@mock-project-id.iam.gserviceaccount.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.serviceAccountID != target {
+		t.Errorf("serviceAccountID = %q; want = %q", app.serviceAccountID, target)
+	}
+	if app.projectID != "mock-project-id" {
+		t.Errorf("projectID = %q; want = %q", app.projectID, "mock-project-id")
+	}
+
+	// The impersonated credentials must not expose the key of the base service account, so that
+	// custom tokens are signed by the target via IAM.
+	creds, err := transport.Creds(ctx, app.opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creds.JSON) != 0 || creds.TokenSource == nil {
+		t.Errorf("Creds() = %#v; want = impersonated token source without JSON", creds)
+	}
+	if c, err := app.Auth(ctx); c == nil || err != nil {
+		t.Errorf("Auth() = (%v, %v); want = (auth, nil)", c, err)
+	}
+}
+
+func TestWithImpersonatedServiceAccountExplicitID(t *testing.T) {
+	app, err := NewApp(context.Background(), &Config{ServiceAccountID: "signer@example.com"},
+		option.WithCredentialsFile("testdata/service_account.json"),
+		WithImpersonatedServiceAccount("target@example.com", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.serviceAccountID != "signer@example.com" {
+		t.Errorf("serviceAccountID = %q; want = %q", app.serviceAccountID, "signer@example.com")
+	}
+}
+
+func TestWithServiceAccountExplicitProjectID(t *testing.T) {
+	sa := ServiceAccount{
+		ClientEmail: "test@example.iam.gserviceaccount.com",
+		ProjectID:   "sa-project-id",
+	}
+	app, err := NewApp(context.Background(), &Config{ProjectID: "explicit-project-id"}, WithServiceAccount(sa))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.projectID != "explicit-project-id" {
+		t.Errorf("app.projectID = %q; want = %q", app.projectID, "explicit-project-id")
+	}
+}
+
 func TestDatabase(t *testing.T) {
 	ctx := context.Background()
 	conf := &Config{DatabaseURL: "https://mock-db.firebaseio.com"}
@@ -235,6 +570,26 @@ func TestDatabase(t *testing.T) {
 	if c, err := app.DatabaseWithURL(ctx, url); c == nil || err != nil {
 		t.Errorf("Database() = (%v, %v); want (db, nil)", c, err)
 	}
+	url = "https://other-mock-db.example.com"
+	if c, err := app.DatabaseWithURL(ctx, url); c != nil || err == nil {
+		t.Errorf("DatabaseWithURL(%q) = (%v, %v); want (nil, error)", url, c, err)
+	}
+}
+
+func TestDatabasePrettyPrint(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{DatabaseURL: "https://mock-db.firebaseio.com", DatabasePrettyPrint: true}
+	app, err := NewApp(ctx, conf, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !app.dbPrettyPrint {
+		t.Errorf("dbPrettyPrint = false; want = true")
+	}
+	if c, err := app.Database(ctx); c == nil || err != nil {
+		t.Errorf("Database() = (%v, %v); want (db, nil)", c, err)
+	}
 }
 
 func TestDatabaseAuthOverrides(t *testing.T) {
@@ -350,6 +705,18 @@ func TestInstanceID(t *testing.T) {
 	}
 }
 
+func TestDynamicLinks(t *testing.T) {
+	ctx := context.Background()
+	app, err := NewApp(ctx, nil, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := app.DynamicLinks(ctx); c == nil || err != nil {
+		t.Errorf("DynamicLinks() = (%v, %v); want (dynamiclinks, nil)", c, err)
+	}
+}
+
 func TestMessaging(t *testing.T) {
 	ctx := context.Background()
 	app, err := NewApp(ctx, nil, option.WithCredentialsFile("testdata/service_account.json"))
@@ -398,6 +765,292 @@ func TestMessagingSendWithCustomEndpoint(t *testing.T) {
 	}
 }
 
+func TestOnTokenRefresh(t *testing.T) {
+	var authHeaders []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "message-id"}`))
+	}))
+	defer ts.Close()
+
+	var refreshed []string
+	ctx := context.Background()
+	tokenSource := &testTokenSource{AccessToken: "token1"}
+	app, err := NewApp(
+		ctx,
+		&Config{
+			ProjectID: "test-project-id",
+			OnTokenRefresh: func(token *oauth2.Token, err error) {
+				if err != nil {
+					t.Errorf("OnTokenRefresh() err = %v; want = nil", err)
+					return
+				}
+				refreshed = append(refreshed, token.AccessToken)
+			},
+		},
+		option.WithTokenSource(tokenSource),
+		option.WithEndpoint(ts.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := app.Messaging(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &messaging.Message{Token: "token"}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Send(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tokenSource.AccessToken = "token2"
+	if _, err := c.Send(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"token1", "token2"}
+	if !reflect.DeepEqual(refreshed, want) {
+		t.Errorf("OnTokenRefresh() tokens = %v; want = %v", refreshed, want)
+	}
+	wantHeaders := []string{"Bearer token1", "Bearer token1", "Bearer token2"}
+	if !reflect.DeepEqual(authHeaders, wantHeaders) {
+		t.Errorf("Authorization = %v; want = %v", authHeaders, wantHeaders)
+	}
+}
+
+func TestOnTokenRefreshError(t *testing.T) {
+	wantErr := errors.New("refresh failed")
+	var got error
+	r := &refreshObserver{
+		src: &errorTokenSource{err: wantErr},
+		onRefresh: func(token *oauth2.Token, err error) {
+			got = err
+		},
+	}
+	if token, err := r.Token(); token != nil || err != wantErr {
+		t.Errorf("Token() = (%v, %v); want = (nil, %v)", token, err, wantErr)
+	}
+	if got != wantErr {
+		t.Errorf("OnTokenRefresh() err = %v; want = %v", got, wantErr)
+	}
+}
+
+func TestOnTokenRefreshWithHTTPClient(t *testing.T) {
+	original := findCredentials
+	defer func() {
+		findCredentials = original
+	}()
+	findCredentials = func(ctx context.Context, opts ...option.ClientOption) (*google.Credentials, error) {
+		t.Errorf("findCredentials() called; want no credential lookup")
+		return original(ctx, opts...)
+	}
+
+	conf := &Config{
+		ProjectID:         "test-project-id",
+		OnTokenRefresh:    func(token *oauth2.Token, err error) {},
+		EarlyTokenRefresh: time.Minute,
+	}
+	app, err := NewApp(context.Background(), conf, option.WithHTTPClient(http.DefaultClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasHTTPClient(app.opts) {
+		t.Errorf("NewApp() dropped the HTTP client option")
+	}
+}
+
+func TestOnTokenRefreshTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	original := findCredentials
+	findCredentials = func(ctx context.Context, opts ...option.ClientOption) (*google.Credentials, error) {
+		<-release
+		return original(ctx, opts...)
+	}
+	defer func() {
+		findCredentials = original
+	}()
+
+	conf := &Config{
+		ProjectID:                 "test-project-id",
+		ProjectIDDetectionTimeout: 10 * time.Millisecond,
+		OnTokenRefresh:            func(token *oauth2.Token, err error) {},
+	}
+	app, err := NewApp(context.Background(), conf, option.WithCredentialsFile("testdata/service_account.json"))
+	want := "failed to resolve credentials within 10ms"
+	if app != nil || err == nil || err.Error() != want {
+		t.Errorf("NewApp() = (%v, %v); want = (nil, %q)", app, err, want)
+	}
+}
+
+func TestEarlyTokenRefresh(t *testing.T) {
+	src := &testTokenSource{AccessToken: "token1", Expiry: time.Now().Add(time.Minute)}
+	r := newEarlyRefresher(src, 5*time.Minute)
+	if token, err := r.Token(); err != nil || token.AccessToken != "token1" {
+		t.Fatalf("Token() = (%v, %v); want = (token1, nil)", token, err)
+	}
+
+	// The cached token is due to expire within the window, and is refreshed in the background.
+	src.AccessToken = "token2"
+	src.Expiry = time.Now().Add(time.Hour)
+	if token, err := r.Token(); err != nil || token.AccessToken != "token1" {
+		t.Fatalf("Token() = (%v, %v); want = (token1, nil)", token, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		token, err := r.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken == "token2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Token() = %q; want = %q", token.AccessToken, "token2")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEarlyTokenRefreshExpired(t *testing.T) {
+	src := &testTokenSource{AccessToken: "token1", Expiry: time.Now().Add(-time.Minute)}
+	r := newEarlyRefresher(src, 5*time.Minute)
+	if token, err := r.Token(); err != nil || token.AccessToken != "token1" {
+		t.Fatalf("Token() = (%v, %v); want = (token1, nil)", token, err)
+	}
+
+	// Expired tokens are refreshed synchronously.
+	src.AccessToken = "token2"
+	src.Expiry = time.Now().Add(time.Hour)
+	if token, err := r.Token(); err != nil || token.AccessToken != "token2" {
+		t.Errorf("Token() = (%v, %v); want = (token2, nil)", token, err)
+	}
+
+	wantErr := errors.New("refresh failed")
+	r = newEarlyRefresher(&errorTokenSource{err: wantErr}, time.Minute)
+	if token, err := r.Token(); token != nil || err != wantErr {
+		t.Errorf("Token() = (%v, %v); want = (nil, %v)", token, err, wantErr)
+	}
+}
+
+func TestEarlyTokenRefreshReuseTokenSource(t *testing.T) {
+	// Credentials hand out tokens through an oauth2.ReuseTokenSource, which keeps returning the
+	// cached token until it expires.
+	src := &mintingTokenSource{lifetimes: []time.Duration{time.Minute, time.Hour}}
+	r := newEarlyRefresher(oauth2.ReuseTokenSource(nil, src), 5*time.Minute)
+	if token, err := r.Token(); err != nil || token.AccessToken != "token1" {
+		t.Fatalf("Token() = (%v, %v); want = (token1, nil)", token, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		token, err := r.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken == "token2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Token() = %q; want = %q", token.AccessToken, "token2")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The refreshed token is outside the window, and is reused without further fetches.
+	for i := 0; i < 3; i++ {
+		if token, err := r.Token(); err != nil || token.AccessToken != "token2" {
+			t.Fatalf("Token() = (%v, %v); want = (token2, nil)", token, err)
+		}
+	}
+	if got := src.count(); got != 2 {
+		t.Errorf("fetched tokens = %d; want = 2", got)
+	}
+}
+
+func TestEarlyTokenRefreshNoLaterToken(t *testing.T) {
+	// A TokenSource that keeps handing out the same token is not asked again in the background.
+	src := &mintingTokenSource{expiry: time.Now().Add(time.Minute)}
+	r := newEarlyRefresher(src, 5*time.Minute)
+	for i := 0; i < 10; i++ {
+		if _, err := r.Token(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := src.count(); got > 2 {
+		t.Errorf("fetched tokens = %d; want <= 2", got)
+	}
+}
+
+func TestEarlyTokenRefreshNegative(t *testing.T) {
+	conf := &Config{ProjectID: "test-project-id", EarlyTokenRefresh: -time.Second}
+	app, err := NewApp(context.Background(), conf, option.WithTokenSource(&testTokenSource{AccessToken: "token"}))
+	want := "early token refresh must not be negative"
+	if app != nil || err == nil || err.Error() != want {
+		t.Errorf("NewApp() = (%v, %v); want = (nil, %q)", app, err, want)
+	}
+}
+
+func TestWithClientInfo(t *testing.T) {
+	var userAgent, clientVersion string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		clientVersion = r.Header.Get("X-Client-Version")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "message-id"}`))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	app, err := NewApp(
+		ctx,
+		&Config{ProjectID: "test-project-id"},
+		option.WithTokenSource(&testTokenSource{AccessToken: "mock-token"}),
+		option.WithEndpoint(ts.URL),
+		WithClientInfo("my-app", "4.5.6"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := app.Messaging(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Send(ctx, &messaging.Message{Token: "token"}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantUA := fmt.Sprintf("fire-admin-go/%s my-app/4.5.6", Version)
+	if userAgent != wantUA {
+		t.Errorf("User-Agent = %q; want = %q", userAgent, wantUA)
+	}
+	wantVersion := "Go/Admin/" + Version
+	if clientVersion != wantVersion {
+		t.Errorf("X-Client-Version = %q; want = %q", clientVersion, wantVersion)
+	}
+}
+
+func TestWithClientInfoInvalid(t *testing.T) {
+	cases := [][]string{
+		{"", "1.0"},
+		{"my-app", ""},
+		{"my app", "1.0"},
+		{"my-app", "1.0\n"},
+	}
+	for _, tc := range cases {
+		app, err := NewApp(context.Background(), &Config{ProjectID: "test-project-id"},
+			option.WithTokenSource(&testTokenSource{}), WithClientInfo(tc[0], tc[1]))
+		if app != nil || err == nil {
+			t.Errorf("NewApp(WithClientInfo(%q, %q)) = (%v, %v); want = (nil, error)", tc[0], tc[1], app, err)
+		}
+	}
+}
+
 func TestCustomTokenSource(t *testing.T) {
 	ctx := context.Background()
 	ts := &testTokenSource{AccessToken: "mock-token-from-custom"}
@@ -431,6 +1084,30 @@ func TestCustomTokenSource(t *testing.T) {
 	}
 }
 
+func TestClose(t *testing.T) {
+	ctx := context.Background()
+	config := &Config{DatabaseURL: "https://mock-db.firebaseio.com"}
+	app, err := NewApp(ctx, config, option.WithCredentialsFile("testdata/service_account.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := app.Auth(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Database(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Messaging(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := app.Close(); err != nil {
+			t.Errorf("Close() = %v; want = nil", err)
+		}
+	}
+}
+
 func TestVersion(t *testing.T) {
 	segments := strings.Split(Version, ".")
 	if len(segments) != 3 {
@@ -482,6 +1159,18 @@ func TestAutoInit(t *testing.T) {
 				StorageBucket: "auto-init.storage.bucket",
 			},
 		},
+		{
+			"<env=string_identity_toolkit_url,opts=nil>",
+			`{
+				"projectId": "auto-init-project-id",
+				"identityToolkitBaseUrl": "https://us-identitytoolkit.p.example.com"
+			  }`,
+			nil,
+			&Config{
+				ProjectID:              "auto-init-project-id",
+				IdentityToolkitBaseURL: "https://us-identitytoolkit.p.example.com",
+			},
+		},
 		{
 			"<env=file_missing_fields,opts=nil>",
 			"testdata/firebase_config_partial.json",
@@ -638,6 +1327,48 @@ func (t *testTokenSource) Token() (*oauth2.Token, error) {
 	}, nil
 }
 
+// mintingTokenSource hands out a new token on every call, with the lifetime at the same position
+// in lifetimes, or the last one once they are used up. A non-zero expiry overrides the lifetimes.
+type mintingTokenSource struct {
+	lifetimes []time.Duration
+	expiry    time.Time
+
+	mu     sync.Mutex
+	minted int
+}
+
+func (m *mintingTokenSource) Token() (*oauth2.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiry := m.expiry
+	if expiry.IsZero() {
+		lifetime := m.lifetimes[len(m.lifetimes)-1]
+		if m.minted < len(m.lifetimes) {
+			lifetime = m.lifetimes[m.minted]
+		}
+		expiry = time.Now().Add(lifetime)
+	}
+	m.minted++
+	return &oauth2.Token{
+		AccessToken: fmt.Sprintf("token%d", m.minted),
+		Expiry:      expiry,
+	}, nil
+}
+
+func (m *mintingTokenSource) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.minted
+}
+
+type errorTokenSource struct {
+	err error
+}
+
+func (e *errorTokenSource) Token() (*oauth2.Token, error) {
+	return nil, e.err
+}
+
 func compareConfig(got *App, want *Config, t *testing.T) {
 	if got.dbURL != want.DatabaseURL {
 		t.Errorf("app.dbURL = %q; want = %q", got.dbURL, want.DatabaseURL)
@@ -655,6 +1386,9 @@ func compareConfig(got *App, want *Config, t *testing.T) {
 	if got.storageBucket != want.StorageBucket {
 		t.Errorf("app.storageBucket = %q; want = %q", got.storageBucket, want.StorageBucket)
 	}
+	if got.identityToolkitBaseURL != want.IdentityToolkitBaseURL {
+		t.Errorf("app.identityToolkitBaseURL = %q; want = %q", got.identityToolkitBaseURL, want.IdentityToolkitBaseURL)
+	}
 }
 
 // mockServiceAcct generates a service account configuration with the provided URL as the
@@ -708,3 +1442,96 @@ func reinstateEnv(varName, oldVal string) {
 		os.Unsetenv(varName)
 	}
 }
+
+func TestWithJWKSFile(t *testing.T) {
+	ctx := context.Background()
+	app, err := NewApp(ctx, &Config{ProjectID: "mock-project-id"},
+		option.WithCredentialsFile("testdata/service_account.json"), WithJWKSFile("testdata/jwks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.jwksFile != "testdata/jwks.json" {
+		t.Errorf("jwksFile = %q; want = %q", app.jwksFile, "testdata/jwks.json")
+	}
+	for _, opt := range app.opts {
+		if _, ok := opt.(*jwksFile); ok {
+			t.Errorf("NewApp() passed the JWKS file option to the transport")
+		}
+	}
+
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The JWKS file holds the public key of the service account, which signs the token.
+	idToken := signIDToken(t, "mock-key-id-1", "mock-project-id")
+	token, err := client.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.UID != "test-uid" {
+		t.Errorf("VerifyIDToken() UID = %q; want = %q", token.UID, "test-uid")
+	}
+
+	if _, err := client.VerifyIDToken(ctx, signIDToken(t, "unknown-key-id", "mock-project-id")); err == nil {
+		t.Errorf("VerifyIDToken(unknown key) = nil; want = error")
+	}
+}
+
+// signIDToken returns an ID token for the given project, signed by the private key of the test
+// service account under the given key ID.
+func signIDToken(t *testing.T, kid, projectID string) string {
+	b, err := ioutil.ReadFile("testdata/service_account.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sa struct {
+		PrivateKey string `json:"private_key"`
+	}
+	if err := json.Unmarshal(b, &sa); err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"aud":       projectID,
+		"iss":       "https://securetoken.google.com/" + projectID,
+		"sub":       "test-uid",
+		"iat":       now - 100,
+		"exp":       now + 3600,
+		"auth_time": now - 100,
+		"firebase": map[string]interface{}{
+			"identities":       map[string]interface{}{},
+			"sign_in_provider": "custom",
+		},
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestWithScopes(t *testing.T) {
+	extra := []string{"https://www.googleapis.com/auth/iam", internal.FirebaseScopes[0], ""}
+	app, err := NewApp(context.Background(), &Config{ProjectID: "test-project-id"},
+		option.WithTokenSource(&testTokenSource{}), WithScopes(extra))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(append([]string{}, internal.FirebaseScopes...), "https://www.googleapis.com/auth/iam")
+	if !reflect.DeepEqual(app.opts[0], option.WithScopes(want...)) {
+		t.Errorf("NewApp() scopes = %v; want = %v", app.opts[0], want)
+	}
+	for _, opt := range app.opts {
+		if _, ok := opt.(*additionalScopes); ok {
+			t.Errorf("NewApp() passed the scopes option to the transport")
+		}
+	}
+}
@@ -119,8 +119,13 @@ func NewClient(ctx context.Context, c *internal.InstanceIDConfig) (*Client, erro
 	if err != nil {
 		return nil, err
 	}
+	c.Tracker.TrackHTTPClient(hc.Client)
 
 	hc.CreateErrFn = createError
+	hc.Opts = []internal.HTTPOption{
+		internal.WithClientVersion(c.Version),
+	}
+	hc.MaxResponseSize = c.MaxResponseSize
 	return &Client{
 		endpoint: iidEndpoint,
 		client:   hc,
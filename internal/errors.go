@@ -95,6 +95,28 @@ func (fe *FirebaseError) Error() string {
 	return fe.String
 }
 
+// Retryable reports whether the operation that caused the error may succeed if retried later.
+//
+// Errors caused by an HTTP error response are classified by the response status: 429 and 5xx
+// (except 501) responses are retryable, while all other responses are terminal. Errors without an
+// HTTP response, such as network errors, are classified by their error code.
+func (fe *FirebaseError) Retryable() bool {
+	if fe.Response != nil {
+		status := fe.Response.StatusCode
+		if status == http.StatusTooManyRequests {
+			return true
+		}
+		return status >= http.StatusInternalServerError && status != http.StatusNotImplemented
+	}
+
+	switch fe.ErrorCode {
+	case ResourceExhausted, Unavailable, DeadlineExceeded, Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
 // HasPlatformErrorCode checks if the given error contains a specific error code.
 func HasPlatformErrorCode(err error, code ErrorCode) bool {
 	fe, ok := err.(*FirebaseError)
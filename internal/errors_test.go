@@ -335,3 +335,44 @@ func TestErrorHTTPResponse(t *testing.T) {
 		t.Errorf("Unmarshal(Response.Body) = %v; want = {key: value}", m)
 	}
 }
+
+func TestErrorRetryable(t *testing.T) {
+	statuses := map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusForbidden:           false,
+		http.StatusNotFound:            false,
+		http.StatusConflict:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusNotImplemented:      false,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+	}
+	for status, want := range statuses {
+		fe := &FirebaseError{
+			ErrorCode: Unknown,
+			Response:  &http.Response{StatusCode: status},
+		}
+		if got := fe.Retryable(); got != want {
+			t.Errorf("Retryable(%d) = %v; want = %v", status, got, want)
+		}
+	}
+
+	codes := map[ErrorCode]bool{
+		InvalidArgument:   false,
+		NotFound:          false,
+		Unknown:           false,
+		Aborted:           true,
+		ResourceExhausted: true,
+		Unavailable:       true,
+		DeadlineExceeded:  true,
+	}
+	for code, want := range codes {
+		fe := &FirebaseError{ErrorCode: code}
+		if got := fe.Retryable(); got != want {
+			t.Errorf("Retryable(%q) = %v; want = %v", code, got, want)
+		}
+	}
+}
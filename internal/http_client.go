@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -26,6 +27,7 @@ import (
 	"strconv"
 	"time"
 
+	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/transport"
 )
@@ -44,6 +46,71 @@ type HTTPClient struct {
 	CreateErrFn CreateErrFn
 	SuccessFn   SuccessFn
 	Opts        []HTTPOption
+
+	// MaxResponseSize is the maximum number of bytes read from a response body. Larger responses
+	// are rejected with an error for which IsResponseTooLarge returns true. If zero,
+	// DefaultMaxResponseSize is used.
+	MaxResponseSize int64
+
+	// AccessTokenFromContext makes the client authorize requests with the access token carried by
+	// their context, as set by WithAccessToken. Otherwise that token is ignored.
+	AccessTokenFromContext bool
+}
+
+// DefaultMaxResponseSize is the maximum response body size accepted by an HTTPClient that does not
+// specify MaxResponseSize. It is large enough for the biggest responses the Firebase services
+// send, such as Realtime Database reads, while bounding the memory used by a misbehaving server.
+const DefaultMaxResponseSize int64 = 256 << 20
+
+const responseTooLarge = "RESPONSE_TOO_LARGE"
+
+// IsResponseTooLarge checks if the given error was due to a response body exceeding the
+// MaxResponseSize of the HTTPClient.
+func IsResponseTooLarge(err error) bool {
+	fe, ok := err.(*FirebaseError)
+	return ok && fe.Ext["internalErrorCode"] == responseTooLarge
+}
+
+type responseTooLargeError struct {
+	limit int64
+}
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the maximum size of %d bytes", e.limit)
+}
+
+func (e *responseTooLargeError) firebaseError() *FirebaseError {
+	return &FirebaseError{
+		ErrorCode: OutOfRange,
+		String:    e.Error(),
+		Ext:       map[string]interface{}{"internalErrorCode": responseTooLarge},
+	}
+}
+
+// ReadResponseBody reads a response body that is not read by an HTTPClient, up to the given
+// maximum number of bytes. Larger bodies are rejected with the same error as in HTTPClient, for
+// which IsResponseTooLarge returns true. If limit is zero, DefaultMaxResponseSize is used.
+func ReadResponseBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	b, err := readLimited(r, limit)
+	if tooLarge, ok := err.(*responseTooLargeError); ok {
+		return nil, tooLarge.firebaseError()
+	}
+	return b, err
+}
+
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	// Read one byte past the limit to tell a body of exactly limit bytes from a larger one.
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, &responseTooLargeError{limit: limit}
+	}
+	return b, nil
 }
 
 // SuccessFn is a function that checks if a Response indicates success.
@@ -132,15 +199,19 @@ func (r *Response) LowLevelResponse() *http.Response {
 // CreateErrFn on the client or on the request. If neither is set, CreatePlatformError is
 // used as the default error function.
 func (c *HTTPClient) Do(ctx context.Context, req *Request) (*Response, error) {
-	var result *attemptResult
+	hc, err := c.httpClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	var result *attemptResult
 	for retries := 0; ; retries++ {
 		hr, err := req.buildHTTPRequest(c.Opts)
 		if err != nil {
 			return nil, err
 		}
 
-		result = c.attempt(ctx, hr, retries)
+		result = c.attempt(ctx, hc, hr, retries)
 		if !result.Retry {
 			break
 		}
@@ -174,19 +245,66 @@ func (c *HTTPClient) DoAndUnmarshal(ctx context.Context, req *Request, v interfa
 	return resp, nil
 }
 
-func (c *HTTPClient) attempt(ctx context.Context, hr *http.Request, retries int) *attemptResult {
-	resp, err := c.Client.Do(hr.WithContext(ctx))
+type accessTokenKey struct{}
+
+// WithAccessToken returns a copy of ctx that carries the given OAuth2 access token. An HTTPClient
+// with AccessTokenFromContext set authorizes the requests made with the returned context using
+// this token, instead of the credentials of its underlying http.Client.
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, token)
+}
+
+// httpClient returns the http.Client used to make requests with the given context. When the
+// client honors the access token carried by the context, the OAuth2 transport of the client is
+// replaced by one that authorizes requests with that token, keeping the underlying transport.
+//
+// The replacement fails closed. If the client has a transport that is not an OAuth2 transport,
+// such as a custom transport or one wrapping the OAuth2 transport, it may set credentials of its
+// own, which would override the access token. An error is returned in that case, instead of
+// making the request with credentials other than the ones requested.
+func (c *HTTPClient) httpClient(ctx context.Context) (*http.Client, error) {
+	token, ok := ctx.Value(accessTokenKey{}).(string)
+	if !ok || !c.AccessTokenFromContext {
+		return c.Client, nil
+	}
+
+	var base http.RoundTripper
+	switch t := c.Client.Transport.(type) {
+	case nil:
+	case *oauth2.Transport:
+		base = t.Base
+	default:
+		return nil, errors.New(
+			"cannot authorize the request with the access token of the context: the HTTP " +
+				"client has a custom transport, which may set credentials of its own")
+	}
+	hc := *c.Client
+	hc.Transport = &oauth2.Transport{
+		Base:   base,
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token, TokenType: "Bearer"}),
+	}
+	return &hc, nil
+}
+
+func (c *HTTPClient) attempt(
+	ctx context.Context, hc *http.Client, hr *http.Request, retries int) *attemptResult {
+	resp, err := hc.Do(hr.WithContext(ctx))
 	result := &attemptResult{}
 	if err != nil {
 		result.Err = err
 	} else {
 		// Read the response body here forcing any I/O errors to occur so that retry logic will
 		// cover them as well.
-		ir, err := newResponse(resp)
+		ir, err := newResponse(resp, c.maxResponseSize())
 		result.Resp = ir
 		result.Err = err
 	}
 
+	// Oversized responses are not transient, and are never retried.
+	if _, ok := result.Err.(*responseTooLargeError); ok {
+		return result
+	}
+
 	// If a RetryConfig is available, always consult it to determine if the request should be retried
 	// or not. Even if there was a network error, we may not want to retry the request based on the
 	// RetryConfig that is in effect.
@@ -199,7 +317,17 @@ func (c *HTTPClient) attempt(ctx context.Context, hr *http.Request, retries int)
 	return result
 }
 
+func (c *HTTPClient) maxResponseSize() int64 {
+	if c.MaxResponseSize > 0 {
+		return c.MaxResponseSize
+	}
+	return DefaultMaxResponseSize
+}
+
 func (c *HTTPClient) handleResult(req *Request, result *attemptResult) (*Response, error) {
+	if tooLarge, ok := result.Err.(*responseTooLargeError); ok {
+		return nil, tooLarge.firebaseError()
+	}
 	if result.Err != nil {
 		return nil, newFirebaseErrorTransport(result.Err)
 	}
@@ -301,9 +429,9 @@ func (e *jsonEntity) Mime() string {
 	return "application/json"
 }
 
-func newResponse(resp *http.Response) (*Response, error) {
+func newResponse(resp *http.Response, limit int64) (*Response, error) {
 	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := readLimited(resp.Body, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -326,6 +454,12 @@ func WithHeader(key, value string) HTTPOption {
 	}
 }
 
+// WithClientVersion creates an HTTPOption that will set the X-Client-Version header on the
+// request to identify the given version of the Admin SDK.
+func WithClientVersion(version string) HTTPOption {
+	return WithHeader("X-Client-Version", fmt.Sprintf("Go/Admin/%s", version))
+}
+
 // WithQueryParam creates an HTTPOption that will set a query parameter on the request.
 func WithQueryParam(key, value string) HTTPOption {
 	return func(r *http.Request) {
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -365,6 +366,121 @@ func TestContext(t *testing.T) {
 	}
 }
 
+func TestWithAccessToken(t *testing.T) {
+	var auth []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Write([]byte("{}"))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, _, err := NewHTTPClient(context.Background(), tokenSourceOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.AccessTokenFromContext = true
+	req := &Request{
+		Method: http.MethodGet,
+		URL:    server.URL,
+	}
+
+	ctx := WithAccessToken(context.Background(), "user-token")
+	if _, err := client.Do(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Bearer user-token", "Bearer test"}
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("Authorization = %v; want = %v", auth, want)
+	}
+}
+
+func TestWithAccessTokenCustomClient(t *testing.T) {
+	var auth string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte("{}"))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &HTTPClient{Client: http.DefaultClient, AccessTokenFromContext: true}
+	ctx := WithAccessToken(context.Background(), "user-token")
+	if _, err := client.Do(ctx, &Request{Method: http.MethodGet, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer user-token" {
+		t.Errorf("Authorization = %q; want = %q", auth, "Bearer user-token")
+	}
+}
+
+func TestWithAccessTokenCustomTransport(t *testing.T) {
+	var requests int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("{}"))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// A transport that sets credentials of its own would override the access token.
+	hc, _, err := NewHTTPClient(context.Background(), tokenSourceOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &HTTPClient{
+		Client:                 &http.Client{Transport: &headerTransport{base: hc.Client.Transport}},
+		RetryConfig:            hc.RetryConfig,
+		AccessTokenFromContext: true,
+	}
+	ctx := WithAccessToken(context.Background(), "user-token")
+	resp, err := client.Do(ctx, &Request{Method: http.MethodGet, URL: server.URL})
+	want := "cannot authorize the request with the access token of the context: the HTTP " +
+		"client has a custom transport, which may set credentials of its own"
+	if resp != nil || err == nil || err.Error() != want {
+		t.Errorf("Do() = (%v, %v); want = (nil, %q)", resp, err, want)
+	}
+	if requests != 0 {
+		t.Errorf("requests = %d; want = 0", requests)
+	}
+}
+
+func TestWithAccessTokenIgnored(t *testing.T) {
+	var auth string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte("{}"))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, _, err := NewHTTPClient(context.Background(), tokenSourceOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithAccessToken(context.Background(), "user-token")
+	if _, err := client.Do(ctx, &Request{Method: http.MethodGet, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer test" {
+		t.Errorf("Authorization = %q; want = %q", auth, "Bearer test")
+	}
+}
+
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("X-Custom", "custom")
+	return t.base.RoundTrip(r)
+}
+
 func TestInvalidURL(t *testing.T) {
 	req := &Request{
 		Method: http.MethodGet,
@@ -430,6 +546,73 @@ func TestRetryDisabled(t *testing.T) {
 	}
 }
 
+func TestMaxResponseSize(t *testing.T) {
+	requests := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"foo": "bar"}`))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &HTTPClient{
+		Client:          http.DefaultClient,
+		RetryConfig:     &testRetryConfig,
+		MaxResponseSize: 14,
+	}
+	req := &Request{Method: http.MethodGet, URL: server.URL}
+	resp, err := client.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do() with body at limit = %v; want = nil", err)
+	}
+	if string(resp.Body) != `{"foo": "bar"}` {
+		t.Errorf("Body = %q; want = %q", string(resp.Body), `{"foo": "bar"}`)
+	}
+
+	requests = 0
+	client.MaxResponseSize = 13
+	resp, err = client.Do(context.Background(), req)
+	want := "response body exceeds the maximum size of 13 bytes"
+	if resp != nil || err == nil || err.Error() != want {
+		t.Fatalf("Do() = (%v, %v); want = (nil, %q)", resp, err, want)
+	}
+	if !IsResponseTooLarge(err) || !HasPlatformErrorCode(err, OutOfRange) {
+		t.Errorf("Do() = %v; want = ResponseTooLarge and OutOfRange", err)
+	}
+	if requests != 1 {
+		t.Errorf("Total requests = %d; want = 1", requests)
+	}
+}
+
+func TestDefaultMaxResponseSize(t *testing.T) {
+	client := &HTTPClient{}
+	if got := client.maxResponseSize(); got != DefaultMaxResponseSize {
+		t.Errorf("maxResponseSize() = %d; want = %d", got, DefaultMaxResponseSize)
+	}
+}
+
+func TestReadResponseBody(t *testing.T) {
+	b, err := ReadResponseBody(strings.NewReader(`{"foo": "bar"}`), 14)
+	if string(b) != `{"foo": "bar"}` || err != nil {
+		t.Fatalf("ReadResponseBody() = (%q, %v); want = (%q, nil)", string(b), err, `{"foo": "bar"}`)
+	}
+
+	b, err = ReadResponseBody(strings.NewReader(`{"foo": "bar"}`), 13)
+	want := "response body exceeds the maximum size of 13 bytes"
+	if b != nil || err == nil || err.Error() != want {
+		t.Fatalf("ReadResponseBody() = (%q, %v); want = (nil, %q)", string(b), err, want)
+	}
+	if !IsResponseTooLarge(err) || !HasPlatformErrorCode(err, OutOfRange) {
+		t.Errorf("ReadResponseBody() = %v; want = ResponseTooLarge and OutOfRange", err)
+	}
+
+	b, err = ReadResponseBody(strings.NewReader(`{"foo": "bar"}`), 0)
+	if string(b) != `{"foo": "bar"}` || err != nil {
+		t.Errorf("ReadResponseBody(0) = (%q, %v); want = (%q, nil)", string(b), err, `{"foo": "bar"}`)
+	}
+}
+
 func TestNetworkErrorMaxRetries(t *testing.T) {
 	err := errors.New("network error")
 	maxRetries := testRetryConfig.MaxRetries
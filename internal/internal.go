@@ -16,6 +16,8 @@
 package internal
 
 import (
+	"net/http"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -37,10 +39,17 @@ var SystemClock = &systemClock{}
 
 // AuthConfig represents the configuration of Firebase Auth service.
 type AuthConfig struct {
-	Opts             []option.ClientOption
-	ProjectID        string
-	ServiceAccountID string
-	Version          string
+	Opts                   []option.ClientOption
+	ProjectID              string
+	ServiceAccountID       string
+	IdentityToolkitBaseURL string
+	Version                string
+	Tracker                *ResourceTracker
+	JWKSFile               string
+	CustomTokenBackdate    time.Duration
+	MaxResponseSize        int64
+
+	EmulatorStrictProjectID bool
 }
 
 // HashConfig represents a hash algorithm configuration used to generate password hashes.
@@ -48,16 +57,31 @@ type HashConfig map[string]interface{}
 
 // InstanceIDConfig represents the configuration of Firebase Instance ID service.
 type InstanceIDConfig struct {
-	Opts      []option.ClientOption
-	ProjectID string
+	Opts            []option.ClientOption
+	ProjectID       string
+	Version         string
+	Tracker         *ResourceTracker
+	MaxResponseSize int64
+}
+
+// DynamicLinksConfig represents the configuration of Firebase Dynamic Links service.
+type DynamicLinksConfig struct {
+	Opts            []option.ClientOption
+	Version         string
+	Tracker         *ResourceTracker
+	MaxResponseSize int64
 }
 
 // DatabaseConfig represents the configuration of Firebase Database service.
 type DatabaseConfig struct {
-	Opts         []option.ClientOption
-	URL          string
-	Version      string
-	AuthOverride map[string]interface{}
+	Opts            []option.ClientOption
+	URL             string
+	Version         string
+	AuthOverride    map[string]interface{}
+	PrettyPrint     bool
+	ClientInfo      string
+	Tracker         *ResourceTracker
+	MaxResponseSize int64
 }
 
 // StorageConfig represents the configuration of Google Cloud Storage service.
@@ -68,14 +92,59 @@ type StorageConfig struct {
 
 // MessagingConfig represents the configuration of Firebase Cloud Messaging service.
 type MessagingConfig struct {
-	Opts      []option.ClientOption
-	ProjectID string
-	Version   string
+	Opts            []option.ClientOption
+	ProjectID       string
+	Version         string
+	Tracker         *ResourceTracker
+	MaxResponseSize int64
 }
 
 // AppCheckConfig represents the configuration of App Check service.
 type AppCheckConfig struct {
-	ProjectID string
+	ProjectID      string
+	Version        string
+	Tracker        *ResourceTracker
+	TokenCacheSize int
+}
+
+// ResourceTracker collects the resources held by the service clients of an App, such as pooled
+// HTTP connections and background goroutines, so that they can be released together.
+//
+// All methods are safe to call on a nil ResourceTracker, in which case they do nothing.
+type ResourceTracker struct {
+	mu       sync.Mutex
+	releases []func()
+}
+
+// TrackHTTPClient registers the idle connections of the given HTTP client for release.
+func (t *ResourceTracker) TrackHTTPClient(hc *http.Client) {
+	if hc != nil {
+		t.TrackFunc(hc.CloseIdleConnections)
+	}
+}
+
+// TrackFunc registers a function to be called when the resources are released.
+func (t *ResourceTracker) TrackFunc(fn func()) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.releases = append(t.releases, fn)
+}
+
+// Release calls all the registered functions, and forgets them.
+func (t *ResourceTracker) Release() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	releases := t.releases
+	t.releases = nil
+	t.mu.Unlock()
+	for _, fn := range releases {
+		fn()
+	}
 }
 
 // MockTokenSource is a TokenSource implementation that can be used for testing.
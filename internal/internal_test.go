@@ -0,0 +1,42 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import "testing"
+
+func TestResourceTracker(t *testing.T) {
+	tracker := &ResourceTracker{}
+	var calls int
+	tracker.TrackFunc(func() { calls++ })
+	tracker.TrackFunc(func() { calls++ })
+
+	tracker.Release()
+	if calls != 2 {
+		t.Errorf("Release() = %d calls; want = 2", calls)
+	}
+
+	tracker.Release()
+	if calls != 2 {
+		t.Errorf("Release() again = %d calls; want = 2", calls)
+	}
+}
+
+func TestNilResourceTracker(t *testing.T) {
+	var tracker *ResourceTracker
+	tracker.TrackFunc(func() {
+		t.Errorf("nil tracker called a release function")
+	})
+	tracker.Release()
+}
@@ -18,11 +18,11 @@ package messaging
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -43,6 +43,7 @@ const (
 	internalError       = "INTERNAL"
 	thirdPartyAuthError = "THIRD_PARTY_AUTH_ERROR"
 	invalidArgument     = "INVALID_ARGUMENT"
+	payloadTooLarge     = "PAYLOAD_TOO_LARGE"
 	quotaExceeded       = "QUOTA_EXCEEDED"
 	senderIDMismatch    = "SENDER_ID_MISMATCH"
 	unregistered        = "UNREGISTERED"
@@ -51,10 +52,6 @@ const (
 	rfc3339Zulu = "2006-01-02T15:04:05.000000000Z"
 )
 
-var (
-	topicNamePattern = regexp.MustCompile("^(/topics/)?(private/)?[a-zA-Z0-9-_.~%]+$")
-)
-
 // Message to be sent via Firebase Cloud Messaging.
 //
 // Message contains payload data, recipient information and platform-specific configuration
@@ -62,8 +59,19 @@ var (
 // that a Message may specify any combination of Data, Notification, Android, Webpush and APNS
 // fields. See https://firebase.google.com/docs/reference/fcm/rest/v1/projects.messages for more
 // details on how the backend FCM servers handle different message parameters.
+//
+// BinaryData holds data values that are not valid strings. Each value is encoded in standard
+// base64, and sent as a data entry under its key, alongside the entries of Data. The keys of
+// BinaryData and Data must not overlap. Since FCM limits the data payload of a message to 4096
+// bytes, the combined size of the keys and the encoded values is checked before the message is
+// sent when BinaryData is set.
+//
+// Metadata holds labels for the observability of the application, such as a campaign ID. It is
+// never sent to FCM. Instead, it is attached to the context of the HTTP request that sends the
+// message, where it can be read by an HTTP middleware via MetadataFromContext.
 type Message struct {
 	Data         map[string]string `json:"data,omitempty"`
+	BinaryData   map[string][]byte `json:"-"`
 	Notification *Notification     `json:"notification,omitempty"`
 	Android      *AndroidConfig    `json:"android,omitempty"`
 	Webpush      *WebpushConfig    `json:"webpush,omitempty"`
@@ -72,6 +80,7 @@ type Message struct {
 	Token        string            `json:"token,omitempty"`
 	Topic        string            `json:"-"`
 	Condition    string            `json:"condition,omitempty"`
+	Metadata     map[string]string `json:"-"`
 }
 
 // MarshalJSON marshals a Message into JSON (for internal use only).
@@ -80,15 +89,32 @@ func (m *Message) MarshalJSON() ([]byte, error) {
 	// to customize how a subset of the fields in a struct should be serialized.
 	type messageInternal Message
 	temp := &struct {
-		BareTopic string `json:"topic,omitempty"`
+		BareTopic string            `json:"topic,omitempty"`
+		Data      map[string]string `json:"data,omitempty"`
 		*messageInternal
 	}{
 		BareTopic:       strings.TrimPrefix(m.Topic, "/topics/"),
+		Data:            m.combinedData(),
 		messageInternal: (*messageInternal)(m),
 	}
 	return json.Marshal(temp)
 }
 
+// combinedData returns the entries of Data, along with the base64-encoded entries of BinaryData.
+func (m *Message) combinedData() map[string]string {
+	if len(m.BinaryData) == 0 {
+		return m.Data
+	}
+	data := make(map[string]string, len(m.Data)+len(m.BinaryData))
+	for k, v := range m.Data {
+		data[k] = v
+	}
+	for k, v := range m.BinaryData {
+		data[k] = base64.StdEncoding.EncodeToString(v)
+	}
+	return data
+}
+
 // UnmarshalJSON unmarshals a JSON string into a Message (for internal use only).
 func (m *Message) UnmarshalJSON(b []byte) error {
 	type messageInternal Message
@@ -121,6 +147,7 @@ type AndroidConfig struct {
 	Data                  map[string]string    `json:"data,omitempty"` // if specified, overrides the Data field on Message type
 	Notification          *AndroidNotification `json:"notification,omitempty"`
 	FCMOptions            *AndroidFCMOptions   `json:"fcm_options,omitempty"`
+	DirectBootOK          bool                 `json:"direct_boot_ok,omitempty"` // if true, the message may be delivered while the device is in direct boot mode
 }
 
 // MarshalJSON marshals an AndroidConfig into JSON (for internal use only).
@@ -190,6 +217,7 @@ type AndroidNotification struct {
 	DefaultLightSettings  bool                          `json:"default_light_settings,omitempty"`
 	Visibility            AndroidNotificationVisibility `json:"-"`
 	NotificationCount     *int                          `json:"notification_count,omitempty"`
+	Proxy                 AndroidNotificationProxy      `json:"-"`
 }
 
 // MarshalJSON marshals an AndroidNotification into JSON (for internal use only).
@@ -216,6 +244,16 @@ func (a *AndroidNotification) MarshalJSON() ([]byte, error) {
 		visibility, _ = visibilities[a.Visibility]
 	}
 
+	var proxy string
+	if a.Proxy != proxyUnspecified {
+		proxies := map[AndroidNotificationProxy]string{
+			ProxyAllow:             "ALLOW",
+			ProxyDeny:              "DENY",
+			ProxyIfPriorityLowered: "IF_PRIORITY_LOWERED",
+		}
+		proxy, _ = proxies[a.Proxy]
+	}
+
 	var timestamp string
 	if a.EventTimestamp != nil {
 		timestamp = a.EventTimestamp.UTC().Format(rfc3339Zulu)
@@ -231,12 +269,14 @@ func (a *AndroidNotification) MarshalJSON() ([]byte, error) {
 		EventTimestamp string   `json:"event_time,omitempty"`
 		Priority       string   `json:"notification_priority,omitempty"`
 		Visibility     string   `json:"visibility,omitempty"`
+		Proxy          string   `json:"proxy,omitempty"`
 		VibrateTimings []string `json:"vibrate_timings,omitempty"`
 		*androidInternal
 	}{
 		EventTimestamp:  timestamp,
 		Priority:        priority,
 		Visibility:      visibility,
+		Proxy:           proxy,
 		VibrateTimings:  vibTimings,
 		androidInternal: (*androidInternal)(a),
 	}
@@ -250,6 +290,7 @@ func (a *AndroidNotification) UnmarshalJSON(b []byte) error {
 		EventTimestamp string   `json:"event_time,omitempty"`
 		Priority       string   `json:"notification_priority,omitempty"`
 		Visibility     string   `json:"visibility,omitempty"`
+		Proxy          string   `json:"proxy,omitempty"`
 		VibrateTimings []string `json:"vibrate_timings,omitempty"`
 		*androidInternal
 	}{
@@ -287,6 +328,19 @@ func (a *AndroidNotification) UnmarshalJSON(b []byte) error {
 		}
 	}
 
+	if temp.Proxy != "" {
+		proxies := map[string]AndroidNotificationProxy{
+			"ALLOW":               ProxyAllow,
+			"DENY":                ProxyDeny,
+			"IF_PRIORITY_LOWERED": ProxyIfPriorityLowered,
+		}
+		if proxy, ok := proxies[temp.Proxy]; ok {
+			a.Proxy = proxy
+		} else {
+			return fmt.Errorf("unknown proxy value: %q", temp.Proxy)
+		}
+	}
+
 	if temp.EventTimestamp != "" {
 		ts, err := time.Parse(rfc3339Zulu, temp.EventTimestamp)
 		if err != nil {
@@ -355,6 +409,24 @@ const (
 	VisibilitySecret
 )
 
+// AndroidNotificationProxy controls when a notification may be proxied to the device via other
+// apps, such as Google Play services.
+type AndroidNotificationProxy int
+
+const (
+	proxyUnspecified AndroidNotificationProxy = iota
+
+	// ProxyAllow tries to proxy this notification.
+	ProxyAllow
+
+	// ProxyDeny does not proxy this notification.
+	ProxyDeny
+
+	// ProxyIfPriorityLowered only tries to proxy this notification if its AndroidConfig priority
+	// was lowered from high to normal on the device.
+	ProxyIfPriorityLowered
+)
+
 // LightSettings to control notification LED.
 type LightSettings struct {
 	Color                  string
@@ -673,18 +745,52 @@ func (p *APNSPayload) UnmarshalJSON(b []byte) error {
 // Aps represents the aps dictionary that may be included in an APNSPayload.
 //
 // Alert may be specified as a string (via the AlertString field), or as a struct (via the Alert
-// field).
+// field). InterruptionLevel and RelevanceScore are honored by iOS 15 and later. RelevanceScore
+// must be in the interval [0, 1].
 type Aps struct {
-	AlertString      string                 `json:"-"`
-	Alert            *ApsAlert              `json:"-"`
-	Badge            *int                   `json:"badge,omitempty"`
-	Sound            string                 `json:"-"`
-	CriticalSound    *CriticalSound         `json:"-"`
-	ContentAvailable bool                   `json:"-"`
-	MutableContent   bool                   `json:"-"`
-	Category         string                 `json:"category,omitempty"`
-	ThreadID         string                 `json:"thread-id,omitempty"`
-	CustomData       map[string]interface{} `json:"-"`
+	AlertString       string                 `json:"-"`
+	Alert             *ApsAlert              `json:"-"`
+	Badge             *int                   `json:"badge,omitempty"`
+	Sound             string                 `json:"-"`
+	CriticalSound     *CriticalSound         `json:"-"`
+	ContentAvailable  bool                   `json:"-"`
+	MutableContent    bool                   `json:"-"`
+	Category          string                 `json:"category,omitempty"`
+	ThreadID          string                 `json:"thread-id,omitempty"`
+	TargetContentID   string                 `json:"target-content-id,omitempty"`
+	InterruptionLevel ApsInterruptionLevel   `json:"-"`
+	RelevanceScore    *float64               `json:"relevance-score,omitempty"`
+	CustomData        map[string]interface{} `json:"-"`
+}
+
+// ApsInterruptionLevel represents the interruption levels of a notification on Apple devices.
+type ApsInterruptionLevel int
+
+const (
+	interruptionLevelUnspecified ApsInterruptionLevel = iota
+
+	// InterruptionLevelPassive adds the notification to the notification list without lighting
+	// up the screen or playing a sound.
+	InterruptionLevelPassive
+
+	// InterruptionLevelActive presents the notification immediately, lights up the screen, and
+	// can play a sound. This is the default behavior of the system.
+	InterruptionLevelActive
+
+	// InterruptionLevelTimeSensitive presents the notification immediately, and allows it to
+	// break through system controls such as Focus modes.
+	InterruptionLevelTimeSensitive
+
+	// InterruptionLevelCritical presents the notification immediately, and plays a sound even when
+	// the device is muted. Requires the critical alerts entitlement.
+	InterruptionLevelCritical
+)
+
+var interruptionLevels = map[ApsInterruptionLevel]string{
+	InterruptionLevelPassive:       "passive",
+	InterruptionLevelActive:        "active",
+	InterruptionLevelTimeSensitive: "time-sensitive",
+	InterruptionLevelCritical:      "critical",
 }
 
 // standardFields creates a map containing all the fields except the custom data.
@@ -715,6 +821,15 @@ func (a *Aps) standardFields() map[string]interface{} {
 	if a.ThreadID != "" {
 		m["thread-id"] = a.ThreadID
 	}
+	if a.TargetContentID != "" {
+		m["target-content-id"] = a.TargetContentID
+	}
+	if a.InterruptionLevel != interruptionLevelUnspecified {
+		m["interruption-level"] = interruptionLevels[a.InterruptionLevel]
+	}
+	if a.RelevanceScore != nil {
+		m["relevance-score"] = *a.RelevanceScore
+	}
 	return m
 }
 
@@ -735,6 +850,7 @@ func (a *Aps) UnmarshalJSON(b []byte) error {
 		SoundObject         *json.RawMessage `json:"sound,omitempty"`
 		ContentAvailableInt int              `json:"content-available,omitempty"`
 		MutableContentInt   int              `json:"mutable-content,omitempty"`
+		InterruptionLevel   string           `json:"interruption-level,omitempty"`
 		*apsInternal
 	}{
 		apsInternal: (*apsInternal)(a),
@@ -744,6 +860,17 @@ func (a *Aps) UnmarshalJSON(b []byte) error {
 	}
 	a.ContentAvailable = (temp.ContentAvailableInt == 1)
 	a.MutableContent = (temp.MutableContentInt == 1)
+	if temp.InterruptionLevel != "" {
+		a.InterruptionLevel = interruptionLevelUnspecified
+		for level, name := range interruptionLevels {
+			if name == temp.InterruptionLevel {
+				a.InterruptionLevel = level
+			}
+		}
+		if a.InterruptionLevel == interruptionLevelUnspecified {
+			return fmt.Errorf("unknown interruption level value: %q", temp.InterruptionLevel)
+		}
+	}
 	if temp.AlertObject != nil {
 		if err := json.Unmarshal(*temp.AlertObject, &a.Alert); err != nil {
 			a.Alert = nil
@@ -781,6 +908,30 @@ type CriticalSound struct {
 	Volume   float64 `json:"volume,omitempty"`
 }
 
+// NewCriticalAlert creates an Aps dictionary for a critical alert, which plays the named sound at
+// the given volume even when the device is muted or in a Focus mode.
+//
+// The sound is sent as a dictionary with the critical flag set, as required by APNs, and the
+// interruption level is set to critical. The volume must be in the interval [0, 1]. Sending
+// critical alerts requires the app to hold the critical alerts entitlement.
+func NewCriticalAlert(alert *ApsAlert, soundName string, volume float64) (*Aps, error) {
+	if soundName == "" {
+		return nil, errors.New("sound name must not be empty")
+	}
+	if volume < 0 || volume > 1 {
+		return nil, errors.New("critical sound volume must be in the interval [0, 1]")
+	}
+	return &Aps{
+		Alert: alert,
+		CriticalSound: &CriticalSound{
+			Critical: true,
+			Name:     soundName,
+			Volume:   volume,
+		},
+		InterruptionLevel: InterruptionLevelCritical,
+	}, nil
+}
+
 // MarshalJSON marshals a CriticalSound into JSON (for internal use only).
 func (cs *CriticalSound) MarshalJSON() ([]byte, error) {
 	type criticalSoundInternal CriticalSound
@@ -842,11 +993,47 @@ type FCMOptions struct {
 }
 
 // ErrorInfo is a topic management error.
+//
+// Index is the position of the failed registration token in the list passed to the topic
+// management operation. Reason is the error string reported by the backend, and Code classifies it
+// into one of the known TopicManagementErrorCode values.
 type ErrorInfo struct {
 	Index  int
+	Token  string
 	Reason string
+	Code   TopicManagementErrorCode
 }
 
+// TopicManagementErrorCode describes why a topic management operation failed for an individual
+// registration token.
+type TopicManagementErrorCode string
+
+const (
+	// TopicErrorInvalidArgument indicates that the registration token is malformed or invalid.
+	TopicErrorInvalidArgument TopicManagementErrorCode = "INVALID_ARGUMENT"
+
+	// TopicErrorNotFound indicates that the registration token is not registered.
+	TopicErrorNotFound TopicManagementErrorCode = "NOT_FOUND"
+
+	// TopicErrorTooManyTopics indicates that the app instance has reached the maximum number of
+	// topic subscriptions.
+	TopicErrorTooManyTopics TopicManagementErrorCode = "TOO_MANY_TOPICS"
+
+	// TopicErrorResourceExhausted indicates that the request was throttled.
+	TopicErrorResourceExhausted TopicManagementErrorCode = "RESOURCE_EXHAUSTED"
+
+	// TopicErrorPermissionDenied indicates that the caller is not authorized to manage the
+	// subscriptions of the registration token (e.g. it belongs to a different project).
+	TopicErrorPermissionDenied TopicManagementErrorCode = "PERMISSION_DENIED"
+
+	// TopicErrorInternal indicates an internal error of the backend service.
+	TopicErrorInternal TopicManagementErrorCode = "INTERNAL"
+
+	// TopicErrorUnknown indicates an error reason not known to this SDK. Refer to
+	// ErrorInfo.Reason for the original reason.
+	TopicErrorUnknown TopicManagementErrorCode = "UNKNOWN"
+)
+
 // Client is the interface for the Firebase Cloud Messaging (FCM) service.
 type Client struct {
 	*fcmClient
@@ -866,6 +1053,7 @@ func NewClient(ctx context.Context, c *internal.MessagingConfig) (*Client, error
 	if err != nil {
 		return nil, err
 	}
+	c.Tracker.TrackHTTPClient(hc)
 
 	batchEndpoint := messagingEndpoint
 
@@ -876,7 +1064,7 @@ func NewClient(ctx context.Context, c *internal.MessagingConfig) (*Client, error
 
 	return &Client{
 		fcmClient: newFCMClient(hc, c, messagingEndpoint, batchEndpoint),
-		iidClient: newIIDClient(hc),
+		iidClient: newIIDClient(hc, c),
 	}, nil
 }
 
@@ -896,7 +1084,9 @@ func newFCMClient(hc *http.Client, conf *internal.MessagingConfig, messagingEndp
 	client.Opts = []internal.HTTPOption{
 		internal.WithHeader(apiFormatVersionHeader, apiFormatVersion),
 		internal.WithHeader(firebaseClientHeader, version),
+		internal.WithClientVersion(conf.Version),
 	}
+	client.MaxResponseSize = conf.MaxResponseSize
 
 	return &fcmClient{
 		fcmEndpoint:   messagingEndpoint,
@@ -935,6 +1125,9 @@ func (c *fcmClient) makeSendRequest(ctx context.Context, req *fcmRequest) (strin
 	if err := validateMessage(req.Message); err != nil {
 		return "", err
 	}
+	if len(req.Message.Metadata) > 0 {
+		ctx = context.WithValue(ctx, metadataKey{}, req.Message.Metadata)
+	}
 
 	request := &internal.Request{
 		Method: http.MethodPost,
@@ -947,6 +1140,21 @@ func (c *fcmClient) makeSendRequest(ctx context.Context, req *fcmRequest) (strin
 	return result.Name, err
 }
 
+type metadataKey struct{}
+
+// MetadataFromContext returns the Metadata of the Message sent by the HTTP request with the given
+// context, and whether the Message specifies any Metadata.
+//
+// This allows an HTTP middleware, such as a custom http.RoundTripper of the HTTP client passed to
+// the SDK, to log the Metadata of each message sent by Send, SendDryRun and the SendEach functions.
+// The Metadata of the messages sent by the deprecated SendAll and SendMulticast functions is not
+// available, since they send multiple messages per HTTP request. The returned map must not be
+// modified.
+func MetadataFromContext(ctx context.Context) (map[string]string, bool) {
+	metadata, ok := ctx.Value(metadataKey{}).(map[string]string)
+	return metadata, ok
+}
+
 // IsInternal checks if the given error was due to an internal server error.
 func IsInternal(err error) bool {
 	return hasMessagingErrorCode(err, internalError)
@@ -971,6 +1179,12 @@ func IsInvalidArgument(err error) bool {
 	return hasMessagingErrorCode(err, invalidArgument)
 }
 
+// IsPayloadTooLarge checks if the given error was due to a message payload exceeding the size
+// limit of the target platform.
+func IsPayloadTooLarge(err error) bool {
+	return hasMessagingErrorCode(err, payloadTooLarge)
+}
+
 // IsMessageRateExceeded checks if the given error was due to the client exceeding a quota.
 //
 // Deprecated: Use IsQuotaExceeded().
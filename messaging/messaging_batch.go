@@ -22,12 +22,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math/rand"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"sync"
+	"time"
 
 	"firebase.google.com/go/v4/internal"
 )
@@ -35,6 +36,14 @@ import (
 const maxMessages = 500
 const multipartBoundary = "__END_OF_PART__"
 
+const (
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// backoffJitter returns a pseudo-random number in [0.0, 1.0). It may be replaced in tests.
+var backoffJitter = rand.Float64
+
 // MulticastMessage represents a message that can be sent to multiple devices via Firebase Cloud
 // Messaging (FCM).
 //
@@ -97,7 +106,42 @@ type BatchResponse struct {
 // none of the messages in the list could be sent. Partial failures or no failures are only
 // indicated by a BatchResponse return value.
 func (c *fcmClient) SendEach(ctx context.Context, messages []*Message) (*BatchResponse, error) {
-	return c.sendEachInBatch(ctx, messages, false)
+	return c.sendEachInBatch(ctx, messages, false, nil)
+}
+
+// SendEachOptions controls how SendEachWithOptions() retries the individual sends of a fan-out.
+//
+// The options replace the retry policy that the client applies to its other requests, under which
+// SendEach() resends a message up to 4 times after an UNAVAILABLE error. Each message is therefore
+// sent at most MaxRetries + 1 times.
+type SendEachOptions struct {
+	// MaxRetries is the maximum number of times each message is resent after a transient error,
+	// such as an UNAVAILABLE or INTERNAL error. Errors that are not transient, such as UNREGISTERED
+	// and INVALID_ARGUMENT, are never retried. If zero, each message is sent exactly once, without
+	// any retries.
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry of a message. The delay doubles with each
+	// subsequent retry, and is reduced by a random jitter of up to 50% so that the retries of
+	// different messages are spread out. Defaults to 1 second if not positive.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between the retries of a message. Defaults to 30 seconds if not
+	// positive.
+	MaxBackoff time.Duration
+}
+
+// SendEachWithOptions sends the messages in the given array like SendEach(), but retries each
+// individual send that fails with a transient error as specified in opts, instead of the default
+// retry policy of the client. If opts is nil, this behaves the same as SendEach().
+//
+// Each message is retried independently of the others, so that a message that fails
+// intermittently is not reported as a failure. Retries are not attempted if the deadline of the
+// context would expire before the backoff delay elapses. In that case, and when the retries are
+// exhausted, the last error is reported in the response of the message.
+func (c *fcmClient) SendEachWithOptions(
+	ctx context.Context, messages []*Message, opts *SendEachOptions) (*BatchResponse, error) {
+	return c.sendEachInBatch(ctx, messages, false, opts)
 }
 
 // SendEachDryRun sends the messages in the given array via Firebase Cloud Messaging in the
@@ -113,7 +157,7 @@ func (c *fcmClient) SendEach(ctx context.Context, messages []*Message) (*BatchRe
 // that none of the messages in the list could be sent. Partial failures or no failures are only
 // indicated by a BatchResponse return value.
 func (c *fcmClient) SendEachDryRun(ctx context.Context, messages []*Message) (*BatchResponse, error) {
-	return c.sendEachInBatch(ctx, messages, true)
+	return c.sendEachInBatch(ctx, messages, true, nil)
 }
 
 // SendEachForMulticast sends the given multicast message to all the FCM registration tokens specified.
@@ -133,6 +177,32 @@ func (c *fcmClient) SendEachForMulticast(ctx context.Context, message *Multicast
 	return c.SendEach(ctx, messages)
 }
 
+// SendEachForMulticastWithCleanup sends the given multicast message like SendEachForMulticast,
+// and calls onInvalid with each registration token that FCM rejected as unregistered or invalid.
+//
+// This makes it possible to prune stale tokens from an application's storage as part of the send.
+// onInvalid is called synchronously, in the order of the input tokens, once all the messages have
+// been sent. Note that FCM also reports an invalid argument error when the message itself is
+// malformed. The message is validated before it is sent, and an error is returned without calling
+// onInvalid if it is malformed, but not all such errors can be detected locally.
+func (c *fcmClient) SendEachForMulticastWithCleanup(
+	ctx context.Context, message *MulticastMessage, onInvalid func(token string)) (*BatchResponse, error) {
+	br, err := c.SendEachForMulticast(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if onInvalid == nil {
+		return br, nil
+	}
+
+	for idx, resp := range br.Responses {
+		if !resp.Success && (IsUnregistered(resp.Error) || IsInvalidArgument(resp.Error)) {
+			onInvalid(message.Tokens[idx])
+		}
+	}
+	return br, nil
+}
+
 // SendEachForMulticastDryRun sends the given multicast message to all the specified FCM registration
 // tokens in the dry run (validation only) mode.
 //
@@ -154,7 +224,8 @@ func (c *fcmClient) SendEachForMulticastDryRun(ctx context.Context, message *Mul
 	return c.SendEachDryRun(ctx, messages)
 }
 
-func (c *fcmClient) sendEachInBatch(ctx context.Context, messages []*Message, dryRun bool) (*BatchResponse, error) {
+func (c *fcmClient) sendEachInBatch(
+	ctx context.Context, messages []*Message, dryRun bool, opts *SendEachOptions) (*BatchResponse, error) {
 	if len(messages) == 0 {
 		return nil, errors.New("messages must not be nil or empty")
 	}
@@ -166,6 +237,13 @@ func (c *fcmClient) sendEachInBatch(ctx context.Context, messages []*Message, dr
 	var responses []*SendResponse = make([]*SendResponse, len(messages))
 	var wg sync.WaitGroup
 
+	// The retries specified in opts replace the ones of the HTTP client, instead of being made on
+	// top of them.
+	sender := c
+	if opts != nil {
+		sender = c.withoutRetries()
+	}
+
 	for idx, m := range messages {
 		if err := validateMessage(m); err != nil {
 			return nil, fmt.Errorf("invalid message at index %d: %v", idx, err)
@@ -173,13 +251,7 @@ func (c *fcmClient) sendEachInBatch(ctx context.Context, messages []*Message, dr
 		wg.Add(1)
 		go func(idx int, m *Message, dryRun bool, responses []*SendResponse) {
 			defer wg.Done()
-			var resp string
-			var err error
-			if dryRun {
-				resp, err = c.SendDryRun(ctx, m)
-			} else {
-				resp, err = c.Send(ctx, m)
-			}
+			resp, err := sender.sendWithRetries(ctx, m, dryRun, opts)
 			if err == nil {
 				responses[idx] = &SendResponse{
 					Success:   true,
@@ -210,6 +282,67 @@ func (c *fcmClient) sendEachInBatch(ctx context.Context, messages []*Message, dr
 	}, nil
 }
 
+func (c *fcmClient) sendWithRetries(
+	ctx context.Context, m *Message, dryRun bool, opts *SendEachOptions) (string, error) {
+	send := c.Send
+	if dryRun {
+		send = c.SendDryRun
+	}
+	if opts == nil {
+		return send(ctx, m)
+	}
+
+	backoff := opts.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialBackoff
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	for retries := 0; ; retries++ {
+		resp, err := send(ctx, m)
+		if err == nil || retries >= opts.MaxRetries || !isTransientSendError(err) {
+			return resp, err
+		}
+
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		delay := backoff - time.Duration(backoffJitter()*float64(backoff)/2)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// withoutRetries returns a copy of the fcmClient whose HTTP client does not retry failed requests.
+func (c *fcmClient) withoutRetries() *fcmClient {
+	hc := *c.httpClient
+	hc.RetryConfig = nil
+	cp := *c
+	cp.httpClient = &hc
+	return &cp
+}
+
+func isTransientSendError(err error) bool {
+	if IsUnregistered(err) || IsInvalidArgument(err) {
+		return false
+	}
+	fe, ok := err.(*internal.FirebaseError)
+	return ok && fe.Retryable()
+}
+
 // SendAll sends the messages in the given array via Firebase Cloud Messaging.
 //
 // The messages array may contain up to 500 messages. SendAll employs batching to send the entire
@@ -315,7 +448,7 @@ func (c *fcmClient) sendBatch(
 		return nil, handleFCMError(resp)
 	}
 
-	return newBatchResponse(resp)
+	return newBatchResponse(resp, c.httpClient.MaxResponseSize)
 }
 
 // part represents a HTTP request that can be sent embedded in a multipart batch request.
@@ -369,7 +502,7 @@ func (c *fcmClient) newBatchRequest(messages []*Message, dryRun bool) (*internal
 	}, nil
 }
 
-func newBatchResponse(resp *internal.Response) (*BatchResponse, error) {
+func newBatchResponse(resp *internal.Response, maxResponseSize int64) (*BatchResponse, error) {
 	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
 	if err != nil {
 		return nil, fmt.Errorf("error parsing content-type header: %v", err)
@@ -386,7 +519,7 @@ func newBatchResponse(resp *internal.Response) (*BatchResponse, error) {
 			return nil, err
 		}
 
-		sr, err := newSendResponse(part)
+		sr, err := newSendResponse(part, maxResponseSize)
 		if err != nil {
 			return nil, err
 		}
@@ -404,13 +537,13 @@ func newBatchResponse(resp *internal.Response) (*BatchResponse, error) {
 	}, nil
 }
 
-func newSendResponse(part *multipart.Part) (*SendResponse, error) {
+func newSendResponse(part *multipart.Part, maxResponseSize int64) (*SendResponse, error) {
 	hr, err := http.ReadResponse(bufio.NewReader(part), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing multipart body: %v", err)
 	}
 
-	b, err := ioutil.ReadAll(hr.Body)
+	b, err := internal.ReadResponseBody(hr.Body, maxResponseSize)
 	if err != nil {
 		return nil, err
 	}
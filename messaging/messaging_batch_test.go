@@ -26,9 +26,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/textproto"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/internal"
 	"google.golang.org/api/option"
 )
 
@@ -351,6 +356,131 @@ func TestSendEachTotalFailure(t *testing.T) {
 	}
 }
 
+func TestSendEachWithOptionsRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := ioutil.ReadAll(r.Body)
+		var parsed fcmRequest
+		json.Unmarshal(req, &parsed)
+		topic := parsed.Message.Topic
+
+		mu.Lock()
+		attempts[topic]++
+		count := attempts[topic]
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case topic == "topic1" && count <= 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("{\"error\": {\"status\": \"UNAVAILABLE\", \"message\": \"test error\"}}"))
+		case topic == "topic2":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"status": "NOT_FOUND", "message": "test error", "details": [{
+				"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+				"errorCode": "UNREGISTERED"}]}}`))
+		default:
+			w.Write([]byte("{ \"name\":\"" + testSuccessResponse[0].Name + "\" }"))
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = ts.URL
+
+	opts := &SendEachOptions{MaxRetries: 3, InitialBackoff: time.Millisecond}
+	br, err := client.SendEachWithOptions(ctx, testMessages, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if br.SuccessCount != 1 || br.FailureCount != 1 {
+		t.Errorf("SendEachWithOptions() = (%d, %d); want = (1, 1)", br.SuccessCount, br.FailureCount)
+	}
+	if !br.Responses[0].Success {
+		t.Errorf("Responses[0] = %v; want = success", br.Responses[0].Error)
+	}
+	if !IsUnregistered(br.Responses[1].Error) {
+		t.Errorf("Responses[1] = %v; want = unregistered error", br.Responses[1].Error)
+	}
+	want := map[string]int{"topic1": 3, "topic2": 1}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("SendEachWithOptions() attempts = %v; want = %v", attempts, want)
+	}
+	if client.fcmClient.httpClient.RetryConfig == nil {
+		t.Errorf("RetryConfig = nil; want = default retry config")
+	}
+}
+
+func TestSendEachWithOptionsRetriesExhausted(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("{\"error\": {\"status\": \"UNAVAILABLE\", \"message\": \"test error\"}}"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = ts.URL
+
+	cases := []struct {
+		name  string
+		ctx   func() (context.Context, context.CancelFunc)
+		opts  *SendEachOptions
+		wantN int
+	}{
+		{
+			name:  "NoRetries",
+			ctx:   func() (context.Context, context.CancelFunc) { return ctx, func() {} },
+			opts:  &SendEachOptions{},
+			wantN: 2,
+		},
+		{
+			name:  "MaxRetries",
+			ctx:   func() (context.Context, context.CancelFunc) { return ctx, func() {} },
+			opts:  &SendEachOptions{MaxRetries: 2, InitialBackoff: time.Millisecond},
+			wantN: 6,
+		},
+		{
+			name: "Deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(ctx, time.Minute)
+			},
+			opts:  &SendEachOptions{MaxRetries: 2, InitialBackoff: time.Hour, MaxBackoff: time.Hour},
+			wantN: 2,
+		},
+	}
+	for _, tc := range cases {
+		attempts = 0
+		ctx, cancel := tc.ctx()
+		br, err := client.SendEachWithOptions(ctx, testMessages, tc.opts)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if br.FailureCount != 2 || !errorutils.IsUnavailable(br.Responses[0].Error) {
+			t.Errorf("SendEachWithOptions(%s) = %v; want = 2 unavailable errors", tc.name, br.Responses[0].Error)
+		}
+		if attempts != tc.wantN {
+			t.Errorf("SendEachWithOptions(%s) attempts = %d; want = %d", tc.name, attempts, tc.wantN)
+		}
+	}
+}
+
 func TestSendEachForMulticastNil(t *testing.T) {
 	ctx := context.Background()
 	client, err := NewClient(ctx, testMessagingConfig)
@@ -571,6 +701,108 @@ func TestSendEachForMulticastPartialFailure(t *testing.T) {
 	}
 }
 
+func testFCMErrorResponse(status, code string) string {
+	return `{"error": {"status": "` + status + `", "message": "test error", "details": [` +
+		`{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "` + code + `"}]}}`
+}
+
+func TestSendEachForMulticastWithCleanup(t *testing.T) {
+	responses := map[string]string{
+		"token2": testFCMErrorResponse("NOT_FOUND", "UNREGISTERED"),
+		"token3": testFCMErrorResponse("INVALID_ARGUMENT", "INVALID_ARGUMENT"),
+		"token4": testFCMErrorResponse("UNAVAILABLE", "UNAVAILABLE"),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fcmRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		if resp, ok := responses[req.Message.Token]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(resp))
+			return
+		}
+		w.Write([]byte(`{"name": "projects/test-project/messages/1"}`))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = ts.URL
+	client.fcmClient.httpClient.RetryConfig = nil
+
+	var invalid []string
+	mm := &MulticastMessage{Tokens: []string{"token1", "token2", "token3", "token4"}}
+	br, err := client.SendEachForMulticastWithCleanup(ctx, mm, func(token string) {
+		invalid = append(invalid, token)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.SuccessCount != 1 || br.FailureCount != 3 {
+		t.Errorf("SendEachForMulticastWithCleanup() = %#v; want = {SuccessCount: 1, FailureCount: 3}", br)
+	}
+	if want := []string{"token2", "token3"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("onInvalid() tokens = %v; want = %v", invalid, want)
+	}
+}
+
+func TestSendEachForMulticastWithCleanupSingleInvalidToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(testFCMErrorResponse("INVALID_ARGUMENT", "INVALID_ARGUMENT")))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = ts.URL
+
+	var invalid []string
+	mm := &MulticastMessage{Tokens: []string{"garbage"}}
+	br, err := client.SendEachForMulticastWithCleanup(ctx, mm, func(token string) {
+		invalid = append(invalid, token)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.FailureCount != 1 {
+		t.Errorf("FailureCount = %d; want = 1", br.FailureCount)
+	}
+	if want := []string{"garbage"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("onInvalid() tokens = %v; want = %v", invalid, want)
+	}
+}
+
+func TestSendEachForMulticastWithCleanupInvalidMessage(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid []string
+	mm := &MulticastMessage{
+		Tokens:  []string{"token1", "token2"},
+		Android: &AndroidConfig{Priority: "invalid"},
+	}
+	br, err := client.SendEachForMulticastWithCleanup(ctx, mm, func(token string) {
+		invalid = append(invalid, token)
+	})
+	if br != nil || err == nil {
+		t.Errorf("SendEachForMulticastWithCleanup() = (%v, %v); want = (nil, error)", br, err)
+	}
+	if len(invalid) != 0 {
+		t.Errorf("onInvalid() tokens = %v; want = none", invalid)
+	}
+}
+
 func TestSendAllEmptyArray(t *testing.T) {
 	ctx := context.Background()
 	client, err := NewClient(ctx, testMessagingConfig)
@@ -685,6 +917,26 @@ func TestSendAllDryRun(t *testing.T) {
 	}
 }
 
+func TestBatchResponsePartTooLarge(t *testing.T) {
+	body, err := createMultipartResponse(testSuccessResponse, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &internal.Response{
+		Status: http.StatusOK,
+		Header: http.Header{"Content-Type": []string{wantMime}},
+		Body:   body,
+	}
+
+	if br, err := newBatchResponse(resp, 0); br == nil || err != nil {
+		t.Fatalf("newBatchResponse() = (%v, %v); want = (batch, nil)", br, err)
+	}
+	br, err := newBatchResponse(resp, 10)
+	if br != nil || !errorutils.IsResponseTooLarge(err) {
+		t.Errorf("newBatchResponse() = (%v, %v); want = (nil, ResponseTooLarge)", br, err)
+	}
+}
+
 func TestSendAllPartialFailure(t *testing.T) {
 	success := []fcmResponse{
 		{
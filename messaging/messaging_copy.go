@@ -0,0 +1,367 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messaging
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+var expiryClock internal.Clock = internal.SystemClock
+
+// WithAndroid returns a copy of the Message with the Android field set to a copy of the given
+// config.
+//
+// Neither the receiver nor the config are modified, and the returned Message does not share any
+// mutable state with them. This makes it safe to derive platform-specific variants from a shared
+// base Message.
+func (m *Message) WithAndroid(config *AndroidConfig) *Message {
+	cp := m.copy()
+	cp.Android = config.copy()
+	return cp
+}
+
+// WithAPNS returns a copy of the Message with the APNS field set to a copy of the given config.
+//
+// See WithAndroid for details on the copy semantics.
+func (m *Message) WithAPNS(config *APNSConfig) *Message {
+	cp := m.copy()
+	cp.APNS = config.copy()
+	return cp
+}
+
+// WithWebpush returns a copy of the Message with the Webpush field set to a copy of the given
+// config.
+//
+// See WithAndroid for details on the copy semantics.
+func (m *Message) WithWebpush(config *WebpushConfig) *Message {
+	cp := m.copy()
+	cp.Webpush = config.copy()
+	return cp
+}
+
+// WithExpiry returns a copy of the Message that expires at the given deadline on both Android and
+// Apple devices.
+//
+// The deadline is converted into a relative Android TTL, and into the absolute apns-expiration
+// header expected by APNs, so that both platforms stop delivery attempts at the same time. The
+// Android and APNS configs are copied before they are modified, and are created if not set. An
+// error is returned if the deadline is not in the future.
+func (m *Message) WithExpiry(deadline time.Time) (*Message, error) {
+	ttl := deadline.Sub(expiryClock.Now())
+	if ttl <= 0 {
+		return nil, errors.New("expiry deadline must be in the future")
+	}
+
+	cp := m.copy()
+	if cp.Android == nil {
+		cp.Android = &AndroidConfig{}
+	}
+	cp.Android.TTL = &ttl
+
+	if cp.APNS == nil {
+		cp.APNS = &APNSConfig{}
+	}
+	if cp.APNS.Headers == nil {
+		cp.APNS.Headers = make(map[string]string)
+	}
+	cp.APNS.Headers["apns-expiration"] = strconv.FormatInt(deadline.Unix(), 10)
+	return cp, nil
+}
+
+// LocalizedNotification specifies the title and body of a notification as keys into the string
+// resources of the client app, so that the notification is localized on the device.
+//
+// The args replace the format specifiers in the localized strings. Each key is required when the
+// corresponding args are specified.
+type LocalizedNotification struct {
+	TitleLocKey  string
+	TitleLocArgs []string
+	BodyLocKey   string
+	BodyLocArgs  []string
+}
+
+// WithLocalizedNotification returns a copy of the Message that displays the given localized
+// notification on both Android and Apple devices.
+//
+// The keys and args are set as the title_loc_key, title_loc_args, body_loc_key and body_loc_args
+// fields of the Android notification, and as the title-loc-key, title-loc-args, loc-key and
+// loc-args fields of the APNs alert. Other fields of those configs are preserved. The Android and
+// APNS configs are copied before they are modified, and are created if not set. An error is
+// returned if the notification is invalid, or if the APNs payload specifies the alert as a string.
+func (m *Message) WithLocalizedNotification(n *LocalizedNotification) (*Message, error) {
+	if n == nil {
+		return nil, errors.New("localized notification must not be nil")
+	}
+	if n.TitleLocKey == "" && n.BodyLocKey == "" {
+		return nil, errors.New("at least one of titleLocKey and bodyLocKey must be specified")
+	}
+	if len(n.TitleLocArgs) > 0 && n.TitleLocKey == "" {
+		return nil, errors.New("titleLocKey is required when specifying titleLocArgs")
+	}
+	if len(n.BodyLocArgs) > 0 && n.BodyLocKey == "" {
+		return nil, errors.New("bodyLocKey is required when specifying bodyLocArgs")
+	}
+
+	cp := m.copy()
+	if cp.APNS == nil {
+		cp.APNS = &APNSConfig{}
+	}
+	if cp.APNS.Payload == nil {
+		cp.APNS.Payload = &APNSPayload{}
+	}
+	if cp.APNS.Payload.Aps == nil {
+		cp.APNS.Payload.Aps = &Aps{}
+	}
+	aps := cp.APNS.Payload.Aps
+	if aps.AlertString != "" {
+		return nil, errors.New("cannot localize an APNs alert specified as a string")
+	}
+	if aps.Alert == nil {
+		aps.Alert = &ApsAlert{}
+	}
+	aps.Alert.TitleLocKey = n.TitleLocKey
+	aps.Alert.TitleLocArgs = copyStrings(n.TitleLocArgs)
+	aps.Alert.LocKey = n.BodyLocKey
+	aps.Alert.LocArgs = copyStrings(n.BodyLocArgs)
+
+	if cp.Android == nil {
+		cp.Android = &AndroidConfig{}
+	}
+	if cp.Android.Notification == nil {
+		cp.Android.Notification = &AndroidNotification{}
+	}
+	cp.Android.Notification.TitleLocKey = n.TitleLocKey
+	cp.Android.Notification.TitleLocArgs = copyStrings(n.TitleLocArgs)
+	cp.Android.Notification.BodyLocKey = n.BodyLocKey
+	cp.Android.Notification.BodyLocArgs = copyStrings(n.BodyLocArgs)
+	return cp, nil
+}
+
+func (m *Message) copy() *Message {
+	if m == nil {
+		return &Message{}
+	}
+	cp := *m
+	cp.Data = copyStringMap(m.Data)
+	cp.Metadata = copyStringMap(m.Metadata)
+	if m.BinaryData != nil {
+		cp.BinaryData = make(map[string][]byte, len(m.BinaryData))
+		for k, v := range m.BinaryData {
+			cp.BinaryData[k] = append([]byte(nil), v...)
+		}
+	}
+	if m.Notification != nil {
+		n := *m.Notification
+		cp.Notification = &n
+	}
+	cp.Android = m.Android.copy()
+	cp.Webpush = m.Webpush.copy()
+	cp.APNS = m.APNS.copy()
+	if m.FCMOptions != nil {
+		o := *m.FCMOptions
+		cp.FCMOptions = &o
+	}
+	return &cp
+}
+
+func (a *AndroidConfig) copy() *AndroidConfig {
+	if a == nil {
+		return nil
+	}
+	cp := *a
+	if a.TTL != nil {
+		ttl := *a.TTL
+		cp.TTL = &ttl
+	}
+	cp.Data = copyStringMap(a.Data)
+	cp.Notification = a.Notification.copy()
+	if a.FCMOptions != nil {
+		o := *a.FCMOptions
+		cp.FCMOptions = &o
+	}
+	return &cp
+}
+
+func (n *AndroidNotification) copy() *AndroidNotification {
+	if n == nil {
+		return nil
+	}
+	cp := *n
+	cp.BodyLocArgs = copyStrings(n.BodyLocArgs)
+	cp.TitleLocArgs = copyStrings(n.TitleLocArgs)
+	if n.EventTimestamp != nil {
+		ts := *n.EventTimestamp
+		cp.EventTimestamp = &ts
+	}
+	if n.VibrateTimingMillis != nil {
+		cp.VibrateTimingMillis = append([]int64{}, n.VibrateTimingMillis...)
+	}
+	if n.LightSettings != nil {
+		ls := *n.LightSettings
+		cp.LightSettings = &ls
+	}
+	if n.NotificationCount != nil {
+		count := *n.NotificationCount
+		cp.NotificationCount = &count
+	}
+	return &cp
+}
+
+func (w *WebpushConfig) copy() *WebpushConfig {
+	if w == nil {
+		return nil
+	}
+	cp := *w
+	cp.Headers = copyStringMap(w.Headers)
+	cp.Data = copyStringMap(w.Data)
+	cp.Notification = w.Notification.copy()
+	if w.FCMOptions != nil {
+		o := *w.FCMOptions
+		cp.FCMOptions = &o
+	}
+	return &cp
+}
+
+func (n *WebpushNotification) copy() *WebpushNotification {
+	if n == nil {
+		return nil
+	}
+	cp := *n
+	if n.Actions != nil {
+		cp.Actions = make([]*WebpushNotificationAction, len(n.Actions))
+		for i, action := range n.Actions {
+			if action != nil {
+				a := *action
+				cp.Actions[i] = &a
+			}
+		}
+	}
+	cp.Data = copyValue(n.Data)
+	if n.TimestampMillis != nil {
+		ts := *n.TimestampMillis
+		cp.TimestampMillis = &ts
+	}
+	if n.Vibrate != nil {
+		cp.Vibrate = append([]int{}, n.Vibrate...)
+	}
+	cp.CustomData = copyInterfaceMap(n.CustomData)
+	return &cp
+}
+
+func (a *APNSConfig) copy() *APNSConfig {
+	if a == nil {
+		return nil
+	}
+	cp := *a
+	cp.Headers = copyStringMap(a.Headers)
+	cp.Payload = a.Payload.copy()
+	if a.FCMOptions != nil {
+		o := *a.FCMOptions
+		cp.FCMOptions = &o
+	}
+	return &cp
+}
+
+func (p *APNSPayload) copy() *APNSPayload {
+	if p == nil {
+		return nil
+	}
+	cp := *p
+	cp.Aps = p.Aps.copy()
+	cp.CustomData = copyInterfaceMap(p.CustomData)
+	return &cp
+}
+
+func (a *Aps) copy() *Aps {
+	if a == nil {
+		return nil
+	}
+	cp := *a
+	if a.Alert != nil {
+		alert := *a.Alert
+		alert.LocArgs = copyStrings(a.Alert.LocArgs)
+		alert.TitleLocArgs = copyStrings(a.Alert.TitleLocArgs)
+		alert.SubTitleLocArgs = copyStrings(a.Alert.SubTitleLocArgs)
+		cp.Alert = &alert
+	}
+	if a.Badge != nil {
+		badge := *a.Badge
+		cp.Badge = &badge
+	}
+	if a.CriticalSound != nil {
+		cs := *a.CriticalSound
+		cp.CriticalSound = &cs
+	}
+	if a.RelevanceScore != nil {
+		score := *a.RelevanceScore
+		cp.RelevanceScore = &score
+	}
+	cp.CustomData = copyInterfaceMap(a.CustomData)
+	return &cp
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
+
+func copyInterfaceMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		cp[k] = copyValue(v)
+	}
+	return cp
+}
+
+// copyValue copies maps and slices of the kind produced by JSON decoding. Other values are
+// returned as is.
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return copyInterfaceMap(val)
+	case map[string]string:
+		return copyStringMap(val)
+	case []interface{}:
+		cp := make([]interface{}, len(val))
+		for i, item := range val {
+			cp[i] = copyValue(item)
+		}
+		return cp
+	case []string:
+		return copyStrings(val)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messaging
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholderPattern = regexp.MustCompile(`{{\s*([a-zA-Z0-9_.-]+)\s*}}`)
+
+// Template is a notification template, whose title, body and data values may contain placeholders
+// of the form {{name}}.
+//
+// Base, if set, specifies the other fields of the rendered messages, such as the target and the
+// platform-specific configs. It is copied when a message is rendered, and is never modified.
+type Template struct {
+	Title string
+	Body  string
+	Data  map[string]string
+	Base  *Message
+}
+
+// Render returns a Message with the placeholders of the template replaced by the given variables.
+//
+// The title and body are set on the Notification of the Message, and the data entries are added to
+// its Data, replacing any entries of Base with the same keys. An error listing the missing
+// variables is returned if any placeholder does not have a corresponding variable. Variables that
+// are not referenced by the template are ignored.
+func (t *Template) Render(vars map[string]string) (*Message, error) {
+	missing := make(map[string]bool)
+	render := func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			value, ok := vars[name]
+			if !ok {
+				missing[name] = true
+			}
+			return value
+		})
+	}
+
+	msg := t.Base.copy()
+	if t.Title != "" || t.Body != "" {
+		if msg.Notification == nil {
+			msg.Notification = &Notification{}
+		}
+		if t.Title != "" {
+			msg.Notification.Title = render(t.Title)
+		}
+		if t.Body != "" {
+			msg.Notification.Body = render(t.Body)
+		}
+	}
+	if len(t.Data) > 0 {
+		if msg.Data == nil {
+			msg.Data = make(map[string]string, len(t.Data))
+		}
+		for k, v := range t.Data {
+			msg.Data[k] = render(v)
+		}
+	}
+
+	if len(missing) > 0 {
+		var names []string
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("missing template variables: %s", strings.Join(names, ", "))
+	}
+	return msg, nil
+}
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -46,6 +47,8 @@ var (
 
 	badge           = 42
 	badgeZero       = 0
+	relevanceScore  = 0.5
+	invalidScore    = 1.5
 	timestampMillis = int64(12345)
 	timestamp       = time.Unix(0, 1546304523123*1000000).UTC()
 )
@@ -75,6 +78,25 @@ var validMessages = []struct {
 		req:  &Message{Condition: "test-condition"},
 		want: map[string]interface{}{"condition": "test-condition"},
 	},
+	{
+		name: "BinaryDataMessage",
+		req: &Message{
+			Data: map[string]string{
+				"k1": "v1",
+			},
+			BinaryData: map[string][]byte{
+				"k2": {0x00, 0xff, 0x10},
+			},
+			Topic: "test-topic",
+		},
+		want: map[string]interface{}{
+			"data": map[string]interface{}{
+				"k1": "v1",
+				"k2": "AP8Q",
+			},
+			"topic": "test-topic",
+		},
+	},
 	{
 		name: "DataMessage",
 		req: &Message{
@@ -126,8 +148,9 @@ var validMessages = []struct {
 					"k1": "v1",
 					"k2": "v2",
 				},
-				Priority: "normal",
-				TTL:      &ttl,
+				Priority:     "normal",
+				TTL:          &ttl,
+				DirectBootOK: true,
 			},
 			Topic: "test-topic",
 		},
@@ -138,8 +161,9 @@ var validMessages = []struct {
 					"k1": "v1",
 					"k2": "v2",
 				},
-				"priority": "normal",
-				"ttl":      "10s",
+				"priority":       "normal",
+				"ttl":            "10s",
+				"direct_boot_ok": true,
 			},
 			"topic": "test-topic",
 		},
@@ -175,6 +199,7 @@ var validMessages = []struct {
 					},
 					Visibility:           VisibilityPrivate,
 					DefaultLightSettings: true,
+					Proxy:                ProxyIfPriorityLowered,
 				},
 				TTL: &ttlWithNanos,
 				FCMOptions: &AndroidFCMOptions{
@@ -217,6 +242,7 @@ var validMessages = []struct {
 					},
 					"visibility":             "PRIVATE",
 					"default_light_settings": true,
+					"proxy":                  "IF_PRIORITY_LOWERED",
 				},
 				"ttl": "1.500000000s",
 				"fcm_options": map[string]interface{}{
@@ -378,13 +404,16 @@ var validMessages = []struct {
 				},
 				Payload: &APNSPayload{
 					Aps: &Aps{
-						AlertString:      "a",
-						Badge:            &badge,
-						Category:         "c",
-						Sound:            "s",
-						ThreadID:         "t",
-						ContentAvailable: true,
-						MutableContent:   true,
+						AlertString:       "a",
+						Badge:             &badge,
+						Category:          "c",
+						Sound:             "s",
+						ThreadID:          "t",
+						ContentAvailable:  true,
+						MutableContent:    true,
+						TargetContentID:   "tci",
+						InterruptionLevel: InterruptionLevelTimeSensitive,
+						RelevanceScore:    &relevanceScore,
 					},
 					CustomData: map[string]interface{}{
 						"k1": "v1",
@@ -403,13 +432,16 @@ var validMessages = []struct {
 				"headers": map[string]interface{}{"h1": "v1", "h2": "v2"},
 				"payload": map[string]interface{}{
 					"aps": map[string]interface{}{
-						"alert":             "a",
-						"badge":             float64(badge),
-						"category":          "c",
-						"sound":             "s",
-						"thread-id":         "t",
-						"content-available": float64(1),
-						"mutable-content":   float64(1),
+						"alert":              "a",
+						"badge":              float64(badge),
+						"category":           "c",
+						"sound":              "s",
+						"thread-id":          "t",
+						"content-available":  float64(1),
+						"mutable-content":    float64(1),
+						"target-content-id":  "tci",
+						"interruption-level": "time-sensitive",
+						"relevance-score":    relevanceScore,
 					},
 					"k1": "v1",
 					"k2": true,
@@ -671,6 +703,24 @@ var invalidMessages = []struct {
 		req:  &Message{},
 		want: "exactly one of token, topic or condition must be specified",
 	},
+	{
+		name: "DuplicateBinaryDataKey",
+		req: &Message{
+			Data:       map[string]string{"k": "v"},
+			BinaryData: map[string][]byte{"k": {0x01}},
+			Topic:      "topic",
+		},
+		want: `multiple specifications for the data key "k"`,
+	},
+	{
+		name: "BinaryDataTooLarge",
+		req: &Message{
+			Data:       map[string]string{"k1": "v1"},
+			BinaryData: map[string][]byte{"k2": make([]byte, 3072)},
+			Topic:      "topic",
+		},
+		want: "data payload size 4102 exceeds the limit of 4096 bytes",
+	},
 	{
 		name: "MultipleTargets",
 		req: &Message{
@@ -679,19 +729,33 @@ var invalidMessages = []struct {
 		},
 		want: "exactly one of token, topic or condition must be specified",
 	},
+	{
+		name: "InvalidToken",
+		req: &Message{
+			Token: "foo bar",
+		},
+		want: "malformed registration token",
+	},
 	{
 		name: "InvalidPrefixedTopicName",
 		req: &Message{
 			Topic: "/topics/",
 		},
-		want: "malformed topic name",
+		want: `malformed topic name: "/topics/"; must only contain letters, digits and the characters "-_.~%"`,
+	},
+	{
+		name: "MissingTopicPrefixSlash",
+		req: &Message{
+			Topic: "topics/foo",
+		},
+		want: `malformed topic name: "topics/foo"; must only contain letters, digits and the characters "-_.~%"`,
 	},
 	{
 		name: "InvalidTopicName",
 		req: &Message{
 			Topic: "foo*bar",
 		},
-		want: "malformed topic name",
+		want: `malformed topic name: "foo*bar"; must only contain letters, digits and the characters "-_.~%"`,
 	},
 	{
 		name: "InvalidNotificationImage",
@@ -991,6 +1055,34 @@ var invalidMessages = []struct {
 		},
 		want: "multiple sound specifications",
 	},
+	{
+		name: "InvalidInterruptionLevel",
+		req: &Message{
+			APNS: &APNSConfig{
+				Payload: &APNSPayload{
+					Aps: &Aps{
+						InterruptionLevel: ApsInterruptionLevel(10),
+					},
+				},
+			},
+			Topic: "topic",
+		},
+		want: "invalid interruption level: 10",
+	},
+	{
+		name: "InvalidRelevanceScore",
+		req: &Message{
+			APNS: &APNSConfig{
+				Payload: &APNSPayload{
+					Aps: &Aps{
+						RelevanceScore: &invalidScore,
+					},
+				},
+			},
+			Topic: "topic",
+		},
+		want: "relevance score must be in the interval [0, 1]",
+	},
 	{
 		name: "VolumeTooLow",
 		req: &Message{
@@ -1087,7 +1179,8 @@ func TestNoProjectID(t *testing.T) {
 
 func TestJSONUnmarshal(t *testing.T) {
 	for _, tc := range validMessages {
-		if tc.name == "PrefixedTopicOnly" {
+		// Binary data is sent as regular data, and cannot be told apart when unmarshaling.
+		if tc.name == "PrefixedTopicOnly" || tc.name == "BinaryDataMessage" {
 			continue
 		}
 		b, err := json.Marshal(tc.req)
@@ -1152,6 +1245,13 @@ func TestInvalidJSONUnmarshal(t *testing.T) {
 			},
 			target: &AndroidNotification{},
 		},
+		{
+			name: "InvalidInterruptionLevel",
+			req: map[string]interface{}{
+				"interruption-level": "invalid",
+			},
+			target: &Aps{},
+		},
 		{
 			name: "InvalidVisibility",
 			req: map[string]interface{}{
@@ -1159,6 +1259,13 @@ func TestInvalidJSONUnmarshal(t *testing.T) {
 			},
 			target: &AndroidNotification{},
 		},
+		{
+			name: "InvalidProxy",
+			req: map[string]interface{}{
+				"proxy": "invalid",
+			},
+			target: &AndroidNotification{},
+		},
 		{
 			name: "InvalidEventTimestamp",
 			req: map[string]interface{}{
@@ -1288,6 +1395,66 @@ func TestSendDryRun(t *testing.T) {
 	}
 }
 
+type metadataRecorder struct {
+	metadata []map[string]string
+}
+
+func (r *metadataRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	metadata, _ := MetadataFromContext(req.Context())
+	r.metadata = append(r.metadata, metadata)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSendWithMetadata(t *testing.T) {
+	var b []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{ \"name\":\"" + testMessageID + "\" }"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	recorder := &metadataRecorder{}
+	client, err := NewClient(ctx, &internal.MessagingConfig{
+		ProjectID: "test-project",
+		Opts: []option.ClientOption{
+			option.WithHTTPClient(&http.Client{Transport: recorder}),
+		},
+		Version: "test-version",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.fcmEndpoint = ts.URL
+
+	metadata := map[string]string{"campaign": "spring-sale", "cohort": "b"}
+	messages := []*Message{
+		{Topic: "topic", Metadata: metadata},
+		{Topic: "topic"},
+	}
+	for _, m := range messages {
+		if _, err := client.Send(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal(b, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{
+			"message": map[string]interface{}{"topic": "topic"},
+		}
+		if !reflect.DeepEqual(parsed, want) {
+			t.Errorf("Send() request = %v; want = %v", parsed, want)
+		}
+	}
+
+	want := []map[string]string{metadata, nil}
+	if !reflect.DeepEqual(recorder.metadata, want) {
+		t.Errorf("MetadataFromContext() = %v; want = %v", recorder.metadata, want)
+	}
+}
+
 func TestSendError(t *testing.T) {
 	var resp string
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1330,6 +1497,367 @@ func TestInvalidMessage(t *testing.T) {
 	}
 }
 
+func TestMessageWithPlatformConfigs(t *testing.T) {
+	ttl := 10 * time.Second
+	base := &Message{
+		Data:         map[string]string{"k": "v"},
+		Notification: &Notification{Title: "title"},
+		Topic:        "topic",
+		Metadata:     map[string]string{"campaign": "c1"},
+	}
+	android := &AndroidConfig{
+		TTL:          &ttl,
+		Data:         map[string]string{"a": "b"},
+		Notification: &AndroidNotification{BodyLocArgs: []string{"arg"}},
+	}
+	apns := &APNSConfig{
+		Headers: map[string]string{"apns-priority": "10"},
+		Payload: &APNSPayload{
+			Aps:        &Aps{Alert: &ApsAlert{Title: "apns"}},
+			CustomData: map[string]interface{}{"nested": map[string]interface{}{"k": "v"}},
+		},
+	}
+	webpush := &WebpushConfig{
+		Notification: &WebpushNotification{Actions: []*WebpushNotificationAction{{Action: "a"}}},
+	}
+
+	msg := base.WithAndroid(android).WithAPNS(apns).WithWebpush(webpush)
+	if base.Android != nil || base.APNS != nil || base.Webpush != nil {
+		t.Errorf("base message modified: %#v", base)
+	}
+	if !reflect.DeepEqual(msg.Android, android) || !reflect.DeepEqual(msg.APNS, apns) ||
+		!reflect.DeepEqual(msg.Webpush, webpush) {
+		t.Errorf("platform configs not set: %#v", msg)
+	}
+
+	// Mutating the derived message must not affect the base or the given configs.
+	msg.Data["k"] = "changed"
+	msg.Metadata["campaign"] = "changed"
+	msg.Notification.Title = "changed"
+	*msg.Android.TTL = time.Minute
+	msg.Android.Data["a"] = "changed"
+	msg.Android.Notification.BodyLocArgs[0] = "changed"
+	msg.APNS.Headers["apns-priority"] = "5"
+	msg.APNS.Payload.Aps.Alert.Title = "changed"
+	msg.APNS.Payload.CustomData["nested"].(map[string]interface{})["k"] = "changed"
+	msg.Webpush.Notification.Actions[0].Action = "changed"
+
+	if base.Data["k"] != "v" || base.Metadata["campaign"] != "c1" || base.Notification.Title != "title" {
+		t.Errorf("base message modified: %#v", base)
+	}
+	if ttl != 10*time.Second || android.Data["a"] != "b" || android.Notification.BodyLocArgs[0] != "arg" {
+		t.Errorf("AndroidConfig modified: %#v", android)
+	}
+	nested := apns.Payload.CustomData["nested"].(map[string]interface{})
+	if apns.Headers["apns-priority"] != "10" || apns.Payload.Aps.Alert.Title != "apns" || nested["k"] != "v" {
+		t.Errorf("APNSConfig modified: %#v", apns)
+	}
+	if webpush.Notification.Actions[0].Action != "a" {
+		t.Errorf("WebpushConfig modified: %#v", webpush)
+	}
+}
+
+func TestMessageWithNilConfig(t *testing.T) {
+	base := &Message{
+		Topic:   "topic",
+		Android: &AndroidConfig{Priority: "high"},
+	}
+	msg := base.WithAndroid(nil)
+	if msg.Android != nil || msg.Topic != "topic" {
+		t.Errorf("WithAndroid(nil) = %#v; want = {Topic: %q}", msg, "topic")
+	}
+	if base.Android == nil {
+		t.Errorf("base message modified: %#v", base)
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	base := &Message{
+		Topic:        "news",
+		Data:         map[string]string{"type": "base", "id": "1"},
+		Notification: &Notification{ImageURL: "https://example.com/image.png"},
+	}
+	tmpl := &Template{
+		Title: "Hello {{name}}",
+		Body:  "{{ name }}, your order {{order.id}} has shipped",
+		Data:  map[string]string{"type": "shipping", "order": "{{order.id}}"},
+		Base:  base,
+	}
+
+	msg, err := tmpl.Render(map[string]string{"name": "Alice", "order.id": "42", "unused": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Message{
+		Topic: "news",
+		Data:  map[string]string{"type": "shipping", "id": "1", "order": "42"},
+		Notification: &Notification{
+			Title:    "Hello Alice",
+			Body:     "Alice, your order 42 has shipped",
+			ImageURL: "https://example.com/image.png",
+		},
+	}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("Render() = %#v; want = %#v", msg, want)
+	}
+	if base.Data["type"] != "base" || base.Notification.Title != "" {
+		t.Errorf("base message modified: %#v", base)
+	}
+}
+
+func TestTemplateRenderNoBase(t *testing.T) {
+	tmpl := &Template{Body: "Plain body"}
+	msg, err := tmpl.Render(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Message{Notification: &Notification{Body: "Plain body"}}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("Render() = %#v; want = %#v", msg, want)
+	}
+}
+
+func TestTemplateRenderMissingVariables(t *testing.T) {
+	tmpl := &Template{
+		Title: "Hello {{name}}",
+		Body:  "Your code is {{code}}",
+		Data:  map[string]string{"link": "{{url}}", "other": "{{name}}"},
+	}
+
+	msg, err := tmpl.Render(map[string]string{"code": "1234"})
+	want := "missing template variables: name, url"
+	if msg != nil || err == nil || err.Error() != want {
+		t.Errorf("Render() = (%v, %v); want = (nil, %q)", msg, err, want)
+	}
+}
+
+func TestMessageWithExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expiryClock = &internal.MockClock{Timestamp: now}
+	defer func() {
+		expiryClock = internal.SystemClock
+	}()
+
+	base := &Message{
+		Topic: "topic",
+		APNS: &APNSConfig{
+			Headers: map[string]string{"apns-priority": "10"},
+		},
+	}
+	msg, err := base.WithExpiry(now.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Android == nil || msg.Android.TTL == nil || *msg.Android.TTL != 90*time.Minute {
+		t.Errorf("WithExpiry() Android = %#v; want = {TTL: 90m}", msg.Android)
+	}
+	wantHeaders := map[string]string{
+		"apns-priority":   "10",
+		"apns-expiration": "1700005400",
+	}
+	if !reflect.DeepEqual(msg.APNS.Headers, wantHeaders) {
+		t.Errorf("WithExpiry() APNS.Headers = %v; want = %v", msg.APNS.Headers, wantHeaders)
+	}
+	if base.Android != nil || len(base.APNS.Headers) != 1 {
+		t.Errorf("base message modified: %#v", base)
+	}
+
+	for _, deadline := range []time.Time{now, now.Add(-time.Second)} {
+		msg, err := base.WithExpiry(deadline)
+		if msg != nil || err == nil {
+			t.Errorf("WithExpiry(%v) = (%v, %v); want = (nil, error)", deadline, msg, err)
+		}
+	}
+}
+
+func TestMessageWithLocalizedNotification(t *testing.T) {
+	base := &Message{
+		Topic: "topic",
+		Android: &AndroidConfig{
+			Notification: &AndroidNotification{Icon: "icon"},
+		},
+		APNS: &APNSConfig{
+			Payload: &APNSPayload{
+				Aps: &Aps{Alert: &ApsAlert{LaunchImage: "image"}},
+			},
+		},
+	}
+	args := []string{"Alice"}
+	msg, err := base.WithLocalizedNotification(&LocalizedNotification{
+		TitleLocKey: "title_key",
+		BodyLocKey:  "body_key",
+		BodyLocArgs: args,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantAndroid := &AndroidNotification{
+		Icon:        "icon",
+		TitleLocKey: "title_key",
+		BodyLocKey:  "body_key",
+		BodyLocArgs: []string{"Alice"},
+	}
+	if !reflect.DeepEqual(msg.Android.Notification, wantAndroid) {
+		t.Errorf("WithLocalizedNotification() Android = %#v; want = %#v", msg.Android.Notification, wantAndroid)
+	}
+	wantAlert := &ApsAlert{
+		LaunchImage: "image",
+		TitleLocKey: "title_key",
+		LocKey:      "body_key",
+		LocArgs:     []string{"Alice"},
+	}
+	if !reflect.DeepEqual(msg.APNS.Payload.Aps.Alert, wantAlert) {
+		t.Errorf("WithLocalizedNotification() APNS alert = %#v; want = %#v", msg.APNS.Payload.Aps.Alert, wantAlert)
+	}
+	if base.Android.Notification.BodyLocKey != "" || base.APNS.Payload.Aps.Alert.LocKey != "" {
+		t.Errorf("base message modified: %#v", base)
+	}
+	args[0] = "changed"
+	if msg.Android.Notification.BodyLocArgs[0] != "Alice" || msg.APNS.Payload.Aps.Alert.LocArgs[0] != "Alice" {
+		t.Errorf("WithLocalizedNotification() shares args with the caller")
+	}
+
+	// Messages without platform configs get new ones.
+	msg, err = (&Message{Topic: "topic"}).WithLocalizedNotification(&LocalizedNotification{TitleLocKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Android.Notification.TitleLocKey != "key" || msg.APNS.Payload.Aps.Alert.TitleLocKey != "key" {
+		t.Errorf("WithLocalizedNotification() = %#v; want title keys set", msg)
+	}
+}
+
+func TestMessageWithLocalizedNotificationError(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  *Message
+		n    *LocalizedNotification
+	}{
+		{"NilNotification", &Message{}, nil},
+		{"NoKeys", &Message{}, &LocalizedNotification{}},
+		{"TitleArgsWithoutKey", &Message{}, &LocalizedNotification{BodyLocKey: "k", TitleLocArgs: []string{"a"}}},
+		{"BodyArgsWithoutKey", &Message{}, &LocalizedNotification{TitleLocKey: "k", BodyLocArgs: []string{"a"}}},
+		{
+			"AlertString",
+			&Message{APNS: &APNSConfig{Payload: &APNSPayload{Aps: &Aps{AlertString: "alert"}}}},
+			&LocalizedNotification{TitleLocKey: "k"},
+		},
+	}
+	for _, tc := range cases {
+		if msg, err := tc.msg.WithLocalizedNotification(tc.n); msg != nil || err == nil {
+			t.Errorf("WithLocalizedNotification(%s) = (%v, %v); want = (nil, error)", tc.name, msg, err)
+		}
+	}
+}
+
+func TestNewCriticalAlert(t *testing.T) {
+	aps, err := NewCriticalAlert(&ApsAlert{Title: "t"}, "alarm.caf", 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(aps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"alert": map[string]interface{}{"title": "t"},
+		"sound": map[string]interface{}{
+			"critical": float64(1),
+			"name":     "alarm.caf",
+			"volume":   0.8,
+		},
+		"interruption-level": "critical",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewCriticalAlert() = %v; want = %v", got, want)
+	}
+	if err := validateAps(aps); err != nil {
+		t.Errorf("validateAps(NewCriticalAlert()) = %v; want = nil", err)
+	}
+}
+
+func TestNewCriticalAlertError(t *testing.T) {
+	cases := []struct {
+		name   string
+		sound  string
+		volume float64
+	}{
+		{"NoSound", "", 0.5},
+		{"VolumeTooLow", "alarm.caf", -0.1},
+		{"VolumeTooHigh", "alarm.caf", 1.1},
+	}
+	for _, tc := range cases {
+		if aps, err := NewCriticalAlert(nil, tc.sound, tc.volume); aps != nil || err == nil {
+			t.Errorf("NewCriticalAlert(%s) = (%v, %v); want = (nil, error)", tc.name, aps, err)
+		}
+	}
+}
+
+func TestAPNSPayloadSize(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := func(size int) *APNSPayload {
+		return &APNSPayload{
+			Aps:        &Aps{},
+			CustomData: map[string]interface{}{"k": strings.Repeat("a", size)},
+		}
+	}
+	cases := []struct {
+		name    string
+		headers map[string]string
+		size    int
+		want    string
+	}{
+		{"Regular", nil, 5000, "payload-too-large: apns payload is 5017 bytes; must not exceed 4096 bytes"},
+		{"VoIP", map[string]string{"apns-push-type": "voip"}, 6000, "payload-too-large: apns payload is 6017 bytes; must not exceed 5120 bytes"},
+	}
+	for _, tc := range cases {
+		msg := &Message{
+			Topic: "topic",
+			APNS: &APNSConfig{
+				Headers: tc.headers,
+				Payload: payload(tc.size),
+			},
+		}
+		name, err := client.Send(ctx, msg)
+		if err == nil || err.Error() != tc.want || !IsPayloadTooLarge(err) || !errorutils.IsInvalidArgument(err) {
+			t.Errorf("Send(%s) = (%q, %v); want = (%q, %q)", tc.name, name, err, "", tc.want)
+		}
+	}
+}
+
+func TestIsValidToken(t *testing.T) {
+	cases := []struct {
+		token string
+		want  bool
+	}{
+		{"test-token", true},
+		{"cXyZ_12-3:APA91bHun4MxP5egoKMwt2KZFBaFUH-1RYqx", true},
+		{"", false},
+		{" ", false},
+		{"foo bar", false},
+		{"token\n", false},
+		{"foo/bar", false},
+		{"foo*bar", false},
+	}
+	for _, tc := range cases {
+		if got := IsValidToken(tc.token); got != tc.want {
+			t.Errorf("IsValidToken(%q) = %v; want = %v", tc.token, got, tc.want)
+		}
+	}
+}
+
 func checkFCMRequest(t *testing.T, b []byte, tr *http.Request, want map[string]interface{}, dryRun bool) {
 	var parsed map[string]interface{}
 	if err := json.Unmarshal(b, &parsed); err != nil {
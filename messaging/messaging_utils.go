@@ -15,19 +15,42 @@
 package messaging
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
+
+	"firebase.google.com/go/v4/internal"
+)
+
+const (
+	// Maximum size in bytes of the APNs payload for regular notifications.
+	maxAPNSPayloadSize = 4096
+
+	// Maximum size in bytes of the APNs payload for VoIP notifications.
+	maxAPNSVoIPPayloadSize = 5120
+
+	// Maximum size in bytes of the data payload of a message.
+	maxDataPayloadSize = 4096
 )
 
 var (
 	bareTopicNamePattern  = regexp.MustCompile("^[a-zA-Z0-9-_.~%]+$")
+	tokenPattern          = regexp.MustCompile("^[a-zA-Z0-9-_:]+$")
 	colorPattern          = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
 	colorWithAlphaPattern = regexp.MustCompile("^#[0-9a-fA-F]{6}([0-9a-fA-F]{2})?$")
 )
 
+// IsValidToken checks whether the given string has the basic shape of an FCM registration token.
+//
+// Only the format of the token is checked; no request is made to FCM. A token that passes this
+// check may still be rejected by FCM as invalid or unregistered.
+func IsValidToken(token string) bool {
+	return tokenPattern.MatchString(token)
+}
+
 func validateMessage(message *Message) error {
 	if message == nil {
 		return fmt.Errorf("message must not be nil")
@@ -38,14 +61,22 @@ func validateMessage(message *Message) error {
 		return fmt.Errorf("exactly one of token, topic or condition must be specified")
 	}
 
+	// validate token
+	if message.Token != "" && !IsValidToken(message.Token) {
+		return fmt.Errorf("malformed registration token")
+	}
+
 	// validate topic
 	if message.Topic != "" {
-		bt := strings.TrimPrefix(message.Topic, "/topics/")
-		if !bareTopicNamePattern.MatchString(bt) {
-			return fmt.Errorf("malformed topic name")
+		if _, err := normalizeTopic(message.Topic); err != nil {
+			return err
 		}
 	}
 
+	if err := validateBinaryData(message); err != nil {
+		return err
+	}
+
 	// validate Notification
 	if err := validateNotification(message.Notification); err != nil {
 		return err
@@ -65,6 +96,51 @@ func validateMessage(message *Message) error {
 	return validateAPNSConfig(message.APNS)
 }
 
+func validateBinaryData(message *Message) error {
+	if len(message.BinaryData) == 0 {
+		return nil
+	}
+	for k := range message.BinaryData {
+		if _, ok := message.Data[k]; ok {
+			return fmt.Errorf("multiple specifications for the data key %q", k)
+		}
+	}
+
+	var size int
+	for k, v := range message.combinedData() {
+		size += len(k) + len(v)
+	}
+	if size > maxDataPayloadSize {
+		return fmt.Errorf("data payload size %d exceeds the limit of %d bytes", size, maxDataPayloadSize)
+	}
+	return nil
+}
+
+// normalizeTopic strips the optional "/topics/" prefix from the given topic name, and checks that
+// the remainder is a well-formed topic name.
+func normalizeTopic(topic string) (string, error) {
+	bt := strings.TrimPrefix(topic, "/topics/")
+	if !bareTopicNamePattern.MatchString(bt) {
+		return "", malformedTopicError(topic)
+	}
+	return bt, nil
+}
+
+// normalizeManagedTopic is like normalizeTopic, but also accepts the optional "private/" segment
+// of the topic names used for topic management.
+func normalizeManagedTopic(topic string) (string, error) {
+	bt := strings.TrimPrefix(topic, "/topics/")
+	if !bareTopicNamePattern.MatchString(strings.TrimPrefix(bt, "private/")) {
+		return "", malformedTopicError(topic)
+	}
+	return bt, nil
+}
+
+func malformedTopicError(topic string) error {
+	return fmt.Errorf(
+		"malformed topic name: %q; must only contain letters, digits and the characters \"-_.~%%\"", topic)
+}
+
 func validateNotification(notification *Notification) error {
 	if notification == nil {
 		return nil
@@ -150,7 +226,39 @@ func validateAPNSConfig(config *APNSConfig) error {
 				}
 			}
 		}
-		return validateAPNSPayload(config.Payload)
+		if err := validateAPNSPayload(config.Payload); err != nil {
+			return err
+		}
+		return validateAPNSPayloadSize(config)
+	}
+	return nil
+}
+
+// validateAPNSPayloadSize checks the serialized APNs payload against the size limit enforced by
+// APNs. Only the payload set on the APNSConfig is taken into account; fields that FCM derives from
+// the rest of the message are not.
+func validateAPNSPayloadSize(config *APNSConfig) error {
+	if config.Payload == nil {
+		return nil
+	}
+	b, err := json.Marshal(config.Payload)
+	if err != nil {
+		return err
+	}
+
+	limit := maxAPNSPayloadSize
+	if strings.EqualFold(config.Headers["apns-push-type"], "voip") {
+		limit = maxAPNSVoIPPayloadSize
+	}
+	if len(b) > limit {
+		return &internal.FirebaseError{
+			ErrorCode: internal.InvalidArgument,
+			String: fmt.Sprintf(
+				"payload-too-large: apns payload is %d bytes; must not exceed %d bytes", len(b), limit),
+			Ext: map[string]interface{}{
+				"messagingErrorCode": payloadTooLarge,
+			},
+		}
 	}
 	return nil
 }
@@ -181,6 +289,14 @@ func validateAps(aps *Aps) error {
 				return fmt.Errorf("critical sound volume must be in the interval [0, 1]")
 			}
 		}
+		if aps.InterruptionLevel != interruptionLevelUnspecified {
+			if _, ok := interruptionLevels[aps.InterruptionLevel]; !ok {
+				return fmt.Errorf("invalid interruption level: %d", aps.InterruptionLevel)
+			}
+		}
+		if aps.RelevanceScore != nil && (*aps.RelevanceScore < 0 || *aps.RelevanceScore > 1) {
+			return fmt.Errorf("relevance score must be in the interval [0, 1]")
+		}
 		m := aps.standardFields()
 		for k := range aps.CustomData {
 			if _, contains := m[k]; contains {
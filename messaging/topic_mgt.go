@@ -19,15 +19,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 
 	"firebase.google.com/go/v4/internal"
 )
 
 const (
-	iidEndpoint    = "https://iid.googleapis.com/iid/v1"
-	iidSubscribe   = "batchAdd"
-	iidUnsubscribe = "batchRemove"
+	iidEndpoint     = "https://iid.googleapis.com/iid/v1"
+	iidInfoEndpoint = "https://iid.googleapis.com/iid/info"
+	iidSubscribe    = "batchAdd"
+	iidUnsubscribe  = "batchRemove"
 )
 
 // TopicManagementResponse is the result produced by topic management operations.
@@ -41,17 +44,36 @@ type TopicManagementResponse struct {
 	Errors       []*ErrorInfo
 }
 
-func newTopicManagementResponse(resp *iidResponse) *TopicManagementResponse {
+var topicManagementErrorCodes = map[string]TopicManagementErrorCode{
+	string(TopicErrorInvalidArgument):   TopicErrorInvalidArgument,
+	string(TopicErrorNotFound):          TopicErrorNotFound,
+	string(TopicErrorTooManyTopics):     TopicErrorTooManyTopics,
+	string(TopicErrorResourceExhausted): TopicErrorResourceExhausted,
+	string(TopicErrorPermissionDenied):  TopicErrorPermissionDenied,
+	string(TopicErrorInternal):          TopicErrorInternal,
+}
+
+func newTopicManagementResponse(resp *iidResponse, tokens []string) *TopicManagementResponse {
 	tmr := &TopicManagementResponse{}
 	for idx, res := range resp.Results {
 		if len(res) == 0 {
 			tmr.SuccessCount++
 		} else {
 			tmr.FailureCount++
-			reason := res["error"].(string)
+			reason, _ := res["error"].(string)
+			code, ok := topicManagementErrorCodes[strings.ToUpper(reason)]
+			if !ok {
+				code = TopicErrorUnknown
+			}
+			var token string
+			if idx < len(tokens) {
+				token = tokens[idx]
+			}
 			tmr.Errors = append(tmr.Errors, &ErrorInfo{
 				Index:  idx,
+				Token:  token,
 				Reason: reason,
+				Code:   code,
 			})
 		}
 	}
@@ -59,17 +81,23 @@ func newTopicManagementResponse(resp *iidResponse) *TopicManagementResponse {
 }
 
 type iidClient struct {
-	iidEndpoint string
-	httpClient  *internal.HTTPClient
+	iidEndpoint     string
+	iidInfoEndpoint string
+	httpClient      *internal.HTTPClient
 }
 
-func newIIDClient(hc *http.Client) *iidClient {
+func newIIDClient(hc *http.Client, conf *internal.MessagingConfig) *iidClient {
 	client := internal.WithDefaultRetryConfig(hc)
 	client.CreateErrFn = handleIIDError
-	client.Opts = []internal.HTTPOption{internal.WithHeader("access_token_auth", "true")}
+	client.Opts = []internal.HTTPOption{
+		internal.WithHeader("access_token_auth", "true"),
+		internal.WithClientVersion(conf.Version),
+	}
+	client.MaxResponseSize = conf.MaxResponseSize
 	return &iidClient{
-		iidEndpoint: iidEndpoint,
-		httpClient:  client,
+		iidEndpoint:     iidEndpoint,
+		iidInfoEndpoint: iidInfoEndpoint,
+		httpClient:      client,
 	}
 }
 
@@ -97,6 +125,40 @@ func (c *iidClient) UnsubscribeFromTopic(ctx context.Context, tokens []string, t
 	return c.makeTopicManagementRequest(ctx, req)
 }
 
+// TopicSubscriptions returns the names of the topics the given registration token is subscribed
+// to, in sorted order and without the "/topics/" prefix.
+//
+// This makes an RPC call to the Instance ID service, which can be used to skip redundant
+// SubscribeToTopic and UnsubscribeFromTopic calls.
+func (c *iidClient) TopicSubscriptions(ctx context.Context, token string) ([]string, error) {
+	if token == "" {
+		return nil, fmt.Errorf("token must not be empty")
+	}
+
+	request := &internal.Request{
+		Method: http.MethodGet,
+		URL:    fmt.Sprintf("%s/%s", c.iidInfoEndpoint, url.PathEscape(token)),
+		Opts: []internal.HTTPOption{
+			internal.WithQueryParam("details", "true"),
+		},
+	}
+	var result struct {
+		Rel struct {
+			Topics map[string]interface{} `json:"topics"`
+		} `json:"rel"`
+	}
+	if _, err := c.httpClient.DoAndUnmarshal(ctx, request, &result); err != nil {
+		return nil, err
+	}
+
+	topics := make([]string, 0, len(result.Rel.Topics))
+	for topic := range result.Rel.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
 type iidRequest struct {
 	Topic  string   `json:"to"`
 	Tokens []string `json:"registration_tokens"`
@@ -127,13 +189,11 @@ func (c *iidClient) makeTopicManagementRequest(ctx context.Context, req *iidRequ
 	if req.Topic == "" {
 		return nil, fmt.Errorf("topic name not specified")
 	}
-	if !topicNamePattern.MatchString(req.Topic) {
-		return nil, fmt.Errorf("invalid topic name: %q", req.Topic)
-	}
-
-	if !strings.HasPrefix(req.Topic, "/topics/") {
-		req.Topic = "/topics/" + req.Topic
+	topic, err := normalizeManagedTopic(req.Topic)
+	if err != nil {
+		return nil, err
 	}
+	req.Topic = "/topics/" + topic
 
 	request := &internal.Request{
 		Method: http.MethodPost,
@@ -145,7 +205,7 @@ func (c *iidClient) makeTopicManagementRequest(ctx context.Context, req *iidRequ
 		return nil, err
 	}
 
-	return newTopicManagementResponse(&result), nil
+	return newTopicManagementResponse(&result, req.Tokens), nil
 }
 
 func handleIIDError(resp *internal.Response) error {
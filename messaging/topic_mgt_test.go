@@ -53,6 +53,72 @@ func TestSubscribe(t *testing.T) {
 	checkTopicMgtResponse(t, resp)
 }
 
+func TestSubscribeWithTopicPrefix(t *testing.T) {
+	var tr *http.Request
+	var b []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr = r
+		b, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"results\": [{}, {\"error\": \"error_reason\"}]}"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.iidEndpoint = ts.URL + "/v1"
+
+	resp, err := client.SubscribeToTopic(ctx, []string{"id1", "id2"}, "/topics/test-topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIIDRequest(t, b, tr, iidSubscribe)
+	checkTopicMgtResponse(t, resp)
+}
+
+func TestTopicMgtPrivateTopic(t *testing.T) {
+	var topics []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			To string `json:"to"`
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &req)
+		topics = append(topics, req.To)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"results\": [{}]}"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.iidEndpoint = ts.URL + "/v1"
+
+	for _, topic := range []string{"private/test-topic", "/topics/private/test-topic"} {
+		if _, err := client.SubscribeToTopic(ctx, []string{"id1"}, topic); err != nil {
+			t.Errorf("SubscribeToTopic(%q) = %v; want = nil", topic, err)
+		}
+		if _, err := client.UnsubscribeFromTopic(ctx, []string{"id1"}, topic); err != nil {
+			t.Errorf("UnsubscribeFromTopic(%q) = %v; want = nil", topic, err)
+		}
+	}
+	want := []string{
+		"/topics/private/test-topic",
+		"/topics/private/test-topic",
+		"/topics/private/test-topic",
+		"/topics/private/test-topic",
+	}
+	if !reflect.DeepEqual(topics, want) {
+		t.Errorf("Topics = %v; want = %v", topics, want)
+	}
+}
+
 func TestInvalidSubscribe(t *testing.T) {
 	ctx := context.Background()
 	client, err := NewClient(ctx, testMessagingConfig)
@@ -113,6 +179,73 @@ func TestInvalidUnsubscribe(t *testing.T) {
 	}
 }
 
+func TestTopicSubscriptions(t *testing.T) {
+	var tr *http.Request
+	resp := `{
+		"application": "com.example.app",
+		"rel": {
+			"topics": {
+				"news": {"addDate": "2023-01-02"},
+				"alerts": {"addDate": "2023-01-01"}
+			}
+		}
+	}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.iidInfoEndpoint = ts.URL + "/info"
+
+	topics, err := client.TopicSubscriptions(ctx, "id1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"alerts", "news"}
+	if !reflect.DeepEqual(topics, want) {
+		t.Errorf("TopicSubscriptions() = %v; want = %v", topics, want)
+	}
+
+	if tr.Method != http.MethodGet {
+		t.Errorf("TopicSubscriptions() Method = %q; want = %q", tr.Method, http.MethodGet)
+	}
+	if tr.URL.Path != "/info/id1" || tr.URL.Query().Get("details") != "true" {
+		t.Errorf("TopicSubscriptions() URL = %q; want = %q", tr.URL, "/info/id1?details=true")
+	}
+	if h := tr.Header.Get("access_token_auth"); h != "true" {
+		t.Errorf("access_token_auth = %q; want = %q", h, "true")
+	}
+	if h := tr.Header.Get("X-Client-Version"); h != "Go/Admin/test-version" {
+		t.Errorf("X-Client-Version = %q; want = %q", h, "Go/Admin/test-version")
+	}
+
+	resp = `{"application": "com.example.app"}`
+	topics, err = client.TopicSubscriptions(ctx, "id1")
+	if err != nil || len(topics) != 0 {
+		t.Errorf("TopicSubscriptions() = (%v, %v); want = ([], nil)", topics, err)
+	}
+}
+
+func TestTopicSubscriptionsEmptyToken(t *testing.T) {
+	client, err := NewClient(context.Background(), testMessagingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topics, err := client.TopicSubscriptions(context.Background(), "")
+	want := "token must not be empty"
+	if topics != nil || err == nil || err.Error() != want {
+		t.Errorf("TopicSubscriptions() = (%v, %v); want = (nil, %q)", topics, err, want)
+	}
+}
+
 func TestTopicManagementError(t *testing.T) {
 	var resp string
 	var status int
@@ -129,6 +262,7 @@ func TestTopicManagementError(t *testing.T) {
 		t.Fatal(err)
 	}
 	client.iidEndpoint = ts.URL + "/v1"
+	client.iidInfoEndpoint = ts.URL + "/info"
 	client.iidClient.httpClient.RetryConfig = nil
 
 	cases := []struct {
@@ -172,6 +306,11 @@ func TestTopicManagementError(t *testing.T) {
 		if err == nil || err.Error() != tc.want || !tc.check(err) {
 			t.Errorf("UnsubscribeFromTopic(%s) = (%#v, %v); want = (nil, %q)", tc.name, tmr, err, tc.want)
 		}
+
+		topics, err := client.TopicSubscriptions(ctx, "id1")
+		if err == nil || err.Error() != tc.want || !tc.check(err) {
+			t.Errorf("TopicSubscriptions(%s) = (%#v, %v); want = (nil, %q)", tc.name, topics, err, tc.want)
+		}
 	}
 }
 
@@ -198,6 +337,9 @@ func checkIIDRequest(t *testing.T, b []byte, tr *http.Request, op string) {
 	if h := tr.Header.Get("Authorization"); h != "Bearer test-token" {
 		t.Errorf("Authorization = %q; want = %q", h, "Bearer test-token")
 	}
+	if h := tr.Header.Get("X-Client-Version"); h != "Go/Admin/test-version" {
+		t.Errorf("X-Client-Version = %q; want = %q", h, "Go/Admin/test-version")
+	}
 }
 
 func checkTopicMgtResponse(t *testing.T, resp *TopicManagementResponse) {
@@ -214,9 +356,51 @@ func checkTopicMgtResponse(t *testing.T, resp *TopicManagementResponse) {
 	if e.Index != 1 {
 		t.Errorf("ErrorInfo.Index = %d; want = %d", e.Index, 1)
 	}
+	if e.Token != "id2" {
+		t.Errorf("ErrorInfo.Token = %q; want = %q", e.Token, "id2")
+	}
 	if e.Reason != "error_reason" {
 		t.Errorf("ErrorInfo.Reason = %s; want = %s", e.Reason, "error_reason")
 	}
+	if e.Code != TopicErrorUnknown {
+		t.Errorf("ErrorInfo.Code = %s; want = %s", e.Code, TopicErrorUnknown)
+	}
+}
+
+func TestTopicManagementErrorCodes(t *testing.T) {
+	resp := &iidResponse{
+		Results: []map[string]interface{}{
+			{},
+			{"error": "INVALID_ARGUMENT"},
+			{"error": "NOT_FOUND"},
+			{"error": "TOO_MANY_TOPICS"},
+			{"error": "RESOURCE_EXHAUSTED"},
+			{"error": "PERMISSION_DENIED"},
+			{"error": "INTERNAL"},
+			{"error": "something_else"},
+		},
+	}
+	tokens := []string{"t0", "t1", "t2", "t3", "t4", "t5", "t6", "t7"}
+	want := []TopicManagementErrorCode{
+		TopicErrorInvalidArgument,
+		TopicErrorNotFound,
+		TopicErrorTooManyTopics,
+		TopicErrorResourceExhausted,
+		TopicErrorPermissionDenied,
+		TopicErrorInternal,
+		TopicErrorUnknown,
+	}
+
+	tmr := newTopicManagementResponse(resp, tokens)
+	if tmr.SuccessCount != 1 || tmr.FailureCount != len(want) || len(tmr.Errors) != len(want) {
+		t.Fatalf("TopicManagementResponse = %#v; want = {SuccessCount: 1, FailureCount: %d}", tmr, len(want))
+	}
+	for i, e := range tmr.Errors {
+		idx := i + 1
+		if e.Index != idx || e.Token != tokens[idx] || e.Code != want[i] {
+			t.Errorf("Errors[%d] = %#v; want = {Index: %d, Token: %q, Code: %q}", i, e, idx, tokens[idx], want[i])
+		}
+	}
 }
 
 var invalidTopicMgtArgs = []struct {
@@ -238,7 +422,19 @@ var invalidTopicMgtArgs = []struct {
 		name:   "InvalidTopicName",
 		tokens: []string{"token1"},
 		topic:  "foo*bar",
-		want:   "invalid topic name: \"foo*bar\"",
+		want:   `malformed topic name: "foo*bar"; must only contain letters, digits and the characters "-_.~%"`,
+	},
+	{
+		name:   "MalformedPrivateTopicName",
+		tokens: []string{"token1"},
+		topic:  "private/foo*bar",
+		want:   `malformed topic name: "private/foo*bar"; must only contain letters, digits and the characters "-_.~%"`,
+	},
+	{
+		name:   "MalformedTopicPrefix",
+		tokens: []string{"token1"},
+		topic:  "topics/foo",
+		want:   `malformed topic name: "topics/foo"; must only contain letters, digits and the characters "-_.~%"`,
 	},
 	{
 		name:   "TooManyTokens",